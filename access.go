@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// accessGrant gives a Forgejo user or team access to the mirrors matching pattern
+type accessGrant struct {
+	pattern    string
+	team       bool
+	name       string
+	permission string
+}
+
+// parseAccessMap turns access map rules of the form
+// "acme/* -> user: alice = write" or "acme/* -> team: platform" into grants
+func parseAccessMap(rules []mappingRule) ([]accessGrant, error) {
+	var grants []accessGrant
+	for _, rule := range rules {
+		kind, rest, found := strings.Cut(rule.value, ":")
+		kind = strings.TrimSpace(kind)
+		if !found || (kind != "user" && kind != "team") {
+			return nil, fmt.Errorf("%s: expected 'user: name = permission' or 'team: name'", rule.pattern)
+		}
+		name, permission, hasPermission := strings.Cut(rest, "=")
+		grant := accessGrant{
+			pattern:    rule.pattern,
+			team:       kind == "team",
+			name:       strings.TrimSpace(name),
+			permission: strings.TrimSpace(permission),
+		}
+		switch {
+		case grant.name == "":
+			return nil, fmt.Errorf("%s: missing %s name", rule.pattern, kind)
+		case grant.team && hasPermission:
+			return nil, fmt.Errorf("%s: team %s has its own permission in Forgejo, remove '= %s'", rule.pattern, grant.name, grant.permission)
+		case !grant.team && grant.permission != "read" && grant.permission != "write" && grant.permission != "admin":
+			return nil, fmt.Errorf("%s: invalid permission %q for user %s (must be 'read', 'write' or 'admin')", rule.pattern, grant.permission, grant.name)
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// accessFor returns every grant whose pattern matches a GitHub repository
+func (c *Client) accessFor(repo *GitHubRepo) []accessGrant {
+	var grants []accessGrant
+	for _, grant := range c.config.AccessMap {
+		if ok, _ := path.Match(strings.ToLower(grant.pattern), strings.ToLower(repo.FullName)); ok {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+// ApplyAccess adds the users and teams of the access map to the mirror of a
+// GitHub repository. Existing collaborators and teams are left in place.
+func (c *Client) ApplyAccess(ctx context.Context, repo *GitHubRepo) error {
+	owner, isOrg := c.resolveOwner(repo)
+	for _, grant := range c.accessFor(repo) {
+		if grant.team && !isOrg {
+			return fmt.Errorf("team %s can't be added to %s/%s, which isn't owned by an organization", grant.name, owner, repo.Name)
+		}
+		if c.config.DryRun {
+			if grant.team {
+				fmt.Printf("[DRY RUN] Would add team %s to %s\n", grant.name, repo.Name)
+			} else {
+				fmt.Printf("[DRY RUN] Would add %s to %s with %s access\n", grant.name, repo.Name, grant.permission)
+			}
+			continue
+		}
+
+		var status int
+		var body []byte
+		var err error
+		if grant.team {
+			status, body, err = c.forgejoRequest(ctx, "PUT", repoPath(owner, repo.Name, "teams", url.PathEscape(grant.name)), nil)
+		} else {
+			payload := map[string]string{"permission": grant.permission}
+			status, body, err = c.forgejoRequest(ctx, "PUT", repoPath(owner, repo.Name, "collaborators", url.PathEscape(grant.name)), payload)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to grant access to %s: %w", grant.name, err)
+		}
+		// Forgejo answers 422 when the team is already assigned
+		if status != http.StatusNoContent && !(grant.team && status == http.StatusUnprocessableEntity) {
+			return forgejoError("granting access to "+grant.name, status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("👥 Granted %s access to %s\n", grant.name, repo.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// actionsValue is the value of an Actions secret or variable for the
+// repositories matching pattern
+type actionsValue struct {
+	pattern string
+	name    string
+	value   string
+}
+
+// parseActionsValues turns rules of the form "acme/* -> NPM_TOKEN = value"
+// into values
+func parseActionsValues(rules []mappingRule) ([]actionsValue, error) {
+	var values []actionsValue
+	for _, rule := range rules {
+		name, value, ok := strings.Cut(rule.value, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s: expected 'NAME = value'", rule.pattern)
+		}
+		values = append(values, actionsValue{pattern: rule.pattern, name: name, value: strings.TrimSpace(value)})
+	}
+	return values, nil
+}
+
+// actionsValueFor returns the value the --actions-values file sets for a
+// secret or variable of a repository
+func (c *Client) actionsValueFor(repo *GitHubRepo, name string) (string, bool) {
+	for _, v := range c.config.ActionsValues {
+		if !strings.EqualFold(v.name, name) {
+			continue
+		}
+		if ok, _ := path.Match(strings.ToLower(v.pattern), strings.ToLower(repo.FullName)); ok {
+			return v.value, true
+		}
+	}
+	return "", false
+}
+
+// ScaffoldActions creates the GitHub Actions secrets and variables of a
+// repository as Forgejo Actions secrets and variables. GitHub doesn't reveal
+// secret values, so secrets are only created with a value from the
+// --actions-values file; the others are reported. Variables get their GitHub
+// value unless the file overrides it.
+func (c *Client) ScaffoldActions(ctx context.Context, repo *GitHubRepo) error {
+	gh := c.githubFor(repo)
+	var secrets []string
+	var variables []*github.ActionsVariable
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := gh.Actions.ListRepoSecrets(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return githubError("listing Actions secrets of "+repo.FullName, err)
+		}
+		for _, secret := range list.Secrets {
+			secrets = append(secrets, secret.Name)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	opts = &github.ListOptions{PerPage: 30}
+	for {
+		list, resp, err := gh.Actions.ListRepoVariables(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return githubError("listing Actions variables of "+repo.FullName, err)
+		}
+		variables = append(variables, list.Variables...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	owner := c.ownerFor(repo)
+	var missing []string
+	for _, name := range secrets {
+		value, ok := c.actionsValueFor(repo, name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would set Actions secret %s of %s\n", name, repo.Name)
+			continue
+		}
+		status, body, err := c.forgejoRequest(ctx, "PUT", repoPath(owner, repo.Name, "actions", "secrets", url.PathEscape(name)), map[string]string{"data": value})
+		if err != nil {
+			return fmt.Errorf("failed to set Actions secret %s: %w", name, err)
+		}
+		if status != http.StatusCreated && status != http.StatusNoContent {
+			return forgejoError("setting Actions secret "+name, status, body)
+		}
+	}
+
+	for _, variable := range variables {
+		value := variable.Value
+		if override, ok := c.actionsValueFor(repo, variable.Name); ok {
+			value = override
+		}
+		if err := c.setActionsVariable(ctx, owner, repo, variable.Name, value); err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf("⚠️  Actions secrets of %s without a value in --actions-values, not created: %s\n", repo.Name, strings.Join(missing, ", "))
+	}
+	if c.config.Verbose {
+		fmt.Printf("⚙️  Scaffolded Actions of %s: %d secrets, %d variables\n", repo.Name, len(secrets)-len(missing), len(variables))
+	}
+	return nil
+}
+
+// setActionsVariable creates a Forgejo Actions variable or updates its value
+func (c *Client) setActionsVariable(ctx context.Context, owner string, repo *GitHubRepo, name, value string) error {
+	variablePath := repoPath(owner, repo.Name, "actions", "variables", url.PathEscape(name))
+	status, body, err := c.forgejoRequest(ctx, "GET", variablePath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Actions variable %s: %w", name, err)
+	}
+	method := "POST"
+	switch status {
+	case http.StatusOK:
+		var current struct {
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(body, &current); err != nil {
+			return fmt.Errorf("failed to decode Actions variable %s: %w", name, err)
+		}
+		if current.Data == value {
+			return nil
+		}
+		method = "PUT"
+	case http.StatusNotFound:
+	default:
+		return forgejoError("fetching Actions variable "+name, status, body)
+	}
+
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would set Actions variable %s of %s\n", name, repo.Name)
+		return nil
+	}
+	status, body, err = c.forgejoRequest(ctx, method, variablePath, map[string]string{"value": value})
+	if err != nil {
+		return fmt.Errorf("failed to set Actions variable %s: %w", name, err)
+	}
+	if status != http.StatusCreated && status != http.StatusNoContent {
+		return forgejoError("setting Actions variable "+name, status, body)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errStale marks a planned action whose precondition no longer holds because
+// Forgejo or GitHub changed since the plan was made
+var errStale = errors.New("the plan is out of date")
+
+// runApply executes exactly the actions of a plan file written by the plan
+// command and returns the process exit code
+func runApply(ctx context.Context, config *Config, client *Client) int {
+	saved, err := readPlanFile(config.PlanFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("📋 Applying %s: %d actions planned at %s\n\n", config.PlanFile, len(saved.Actions), saved.CreatedAt.Format(time.RFC3339))
+
+	targets := make(map[string]*Client)
+	for _, target := range client.targets() {
+		targets[target.name] = target
+	}
+	needsGitHub := false
+	for _, action := range saved.Actions {
+		if targets[action.Target] == nil {
+			log.Fatalf("The plan has actions for %s, which isn't a configured target", action.Target)
+		}
+		needsGitHub = needsGitHub || action.Repo != ""
+	}
+
+	// The listing applies the same owner mapping and renaming as the plan did
+	repos := make(map[string]*GitHubRepo)
+	if needsGitHub {
+		githubRepos, err := client.GetGitHubRepos(ctx)
+		if err != nil {
+			log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+		}
+		for _, repo := range githubRepos {
+			repos[repo.FullName] = repo
+		}
+	}
+
+	var applied, stale, failed, notApplied int
+	for _, action := range saved.Actions {
+		if ctx.Err() != nil {
+			notApplied++
+			continue
+		}
+		err := targets[action.Target].applyAction(ctx, action, repos[action.Repo])
+		switch {
+		case errors.Is(err, errStale):
+			stale++
+			fmt.Printf("⚠️  Skipping %s of %s on %s: %v\n", action.Action, action.Mirror, action.Target, err)
+		case err != nil:
+			failed++
+			fmt.Printf("❌ Failed to %s %s on %s: %v\n", action.Action, action.Mirror, action.Target, err)
+		default:
+			applied++
+		}
+	}
+
+	if client.state != nil {
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	fmt.Printf("\n📊 Apply Summary:\n")
+	fmt.Printf("   Applied: %d\n", applied)
+	fmt.Printf("   Out of date: %d\n", stale)
+	fmt.Printf("   Failed: %d\n", failed)
+	if notApplied > 0 {
+		fmt.Printf("   Not applied (interrupted): %d\n", notApplied)
+		return 130
+	}
+	if stale > 0 {
+		fmt.Println("\n⚠️  Parts of the plan were out of date; run plan again to review the remaining changes")
+	}
+	if failed+stale > 0 {
+		return 1
+	}
+	return 0
+}
+
+// applyAction executes one planned action on this target after checking that
+// Forgejo is still in the state the plan was made for
+func (c *Client) applyAction(ctx context.Context, action PlanAction, repo *GitHubRepo) error {
+	if action.Repo != "" && repo == nil {
+		return fmt.Errorf("%w: %s is no longer selected on GitHub", errStale, action.Repo)
+	}
+	owner, name, _ := strings.Cut(action.Mirror, "/")
+	current, err := c.getRepoFields(ctx, owner, name)
+	if err != nil {
+		return err
+	}
+	if current == nil && action.Action != "create" && action.Action != "recreate" {
+		return fmt.Errorf("%w: %s no longer exists", errStale, action.Mirror)
+	}
+
+	switch action.Action {
+	case "create", "recreate":
+		if action.Action == "create" && current != nil {
+			return fmt.Errorf("%w: %s exists already", errStale, action.Mirror)
+		}
+		if mirror := c.ownerFor(repo) + "/" + repo.Name; mirror != action.Mirror {
+			return fmt.Errorf("%w: %s would now be mirrored to %s", errStale, action.Repo, mirror)
+		}
+		if current != nil {
+			if err := c.DeleteRepo(ctx, owner, name); err != nil {
+				return err
+			}
+		}
+		result, detail := c.mirrorRepo(ctx, repo, action.Note == "empty repository")
+		c.state.RecordResult(repo, c, result, detail, c.clock.Now())
+		if !result.Succeeded() {
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(detail, "❌")))
+		}
+		return nil
+
+	case "update", "sync":
+		edit, archive, err := plannedEdit(action.Changes, current)
+		if err != nil {
+			return err
+		}
+		if edit != nil {
+			if err := c.EditRepo(ctx, owner, name, edit); err != nil {
+				return err
+			}
+			fmt.Printf("🔧 Updated settings: %s\n", action.Mirror)
+		}
+		result := ResultAlreadyExists
+		if action.Action == "sync" {
+			if err := c.withRetry(ctx, "Sync of "+name, func() error { return c.SyncMirror(ctx, owner, name) }); err != nil {
+				return err
+			}
+			result = ResultSynced
+		}
+		// Archive after the final sync so the mirror has the last state of the project
+		if archive {
+			if err := c.ArchiveRepo(ctx, owner, name); err != nil {
+				return err
+			}
+		}
+		if repo != nil {
+			c.state.RecordResult(repo, c, result, "", c.clock.Now())
+		}
+		return nil
+
+	case "rename":
+		if len(action.Changes) != 1 || action.Changes[0].Setting != "full_name" {
+			return fmt.Errorf("invalid rename in plan")
+		}
+		newOwner, newName, _ := strings.Cut(action.Changes[0].To, "/")
+		if name != newName {
+			if err := c.EditRepo(ctx, owner, name, &EditRepoOption{Name: &newName}); err != nil {
+				return err
+			}
+		}
+		if !strings.EqualFold(owner, newOwner) {
+			if repo != nil {
+				if err := c.ensureOwner(ctx, repo); err != nil {
+					return err
+				}
+			}
+			if err := c.TransferRepo(ctx, owner, newName, newOwner); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✏️  Renamed %s to %s\n", action.Mirror, action.Changes[0].To)
+		if action.Previous != "" {
+			c.state.RenameTarget(action.Previous, action.Repo, c.name)
+		}
+		return nil
+
+	case "archive":
+		return c.ArchiveRepo(ctx, owner, name)
+
+	case "delete":
+		return c.DeleteRepo(ctx, owner, name)
+	}
+	return fmt.Errorf("unknown action %q in plan", action.Action)
+}
+
+// plannedEdit turns planned setting changes back into repository settings. It
+// reports separately whether the repository is to be archived, which has to
+// wait until it is synced, and fails if a setting no longer has the value the
+// change was planned from.
+func plannedEdit(changes []SettingChange, current map[string]interface{}) (*EditRepoOption, bool, error) {
+	settings := make(map[string]interface{})
+	archive := false
+	for _, change := range changes {
+		if formatSetting(current[change.Setting]) != change.From {
+			return nil, false, fmt.Errorf("%w: %s is %s now, not %s", errStale, change.Setting, formatSetting(current[change.Setting]), change.From)
+		}
+		value, err := parseSetting(change.To)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid value for %s in plan: %w", change.Setting, err)
+		}
+		if change.Setting == "archived" && value == true {
+			archive = true
+			continue
+		}
+		settings[change.Setting] = value
+	}
+	if len(settings) == 0 {
+		return nil, archive, nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, false, err
+	}
+	var opt EditRepoOption
+	if err := json.Unmarshal(data, &opt); err != nil {
+		return nil, false, fmt.Errorf("invalid setting in plan: %w", err)
+	}
+	return &opt, archive, nil
+}
+
+// parseSetting reverses formatSetting
+func parseSetting(value string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case value == "true" || value == "false":
+		return value == "true", nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
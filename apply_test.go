@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseSetting(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{`"main"`, "main", false},
+		{`"a \"quoted\" description"`, `a "quoted" description`, false},
+		{"true", true, false},
+		{"false", false, false},
+		{"42", 42.0, false},
+		{`"unterminated`, nil, true},
+		{"(unset)", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSetting(tt.value)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("parseSetting(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+			continue
+		}
+		// parseSetting reverses formatSetting
+		if !tt.wantErr && formatSetting(got) != tt.value {
+			t.Errorf("formatSetting(parseSetting(%q)) = %s", tt.value, formatSetting(got))
+		}
+	}
+}
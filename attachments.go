@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// attachmentPattern matches the URLs of files attached to GitHub issues,
+// comments and discussions
+var attachmentPattern = regexp.MustCompile(`https://(?:github\.com/user-attachments/(?:assets|files)/|github\.com/[\w.-]+/[\w.-]+/assets/\d+/|user-images\.githubusercontent\.com/)[^\s()<>"'\]]+`)
+
+// attachmentExtensions names uploads of attachments whose URL has no file
+// extension, which Forgejo needs to accept them
+var attachmentExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// forgejoAttachment is a file attached to a Forgejo issue or comment
+type forgejoAttachment struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// copyAttachments uploads the GitHub attachments linked in the body of a
+// Forgejo issue or comment, given by the path segments after the repository,
+// and points the links at the copies if --copy-attachments is set. GitHub
+// attachment URLs of private repositories need a GitHub login and may stop
+// working, copies on Forgejo don't. Attachments uploaded by an earlier sync
+// are reused. Ones that can't be downloaded keep their GitHub link.
+func (c *Client) copyAttachments(ctx context.Context, owner string, repo *GitHubRepo, body string, segments ...string) error {
+	if !c.config.CopyAttachments {
+		return nil
+	}
+	links := attachmentPattern.FindAllString(body, -1)
+	if len(links) == 0 {
+		return nil
+	}
+	assetsPath := repoPath(owner, repo.Name, append(segments, "assets")...)
+	status, respBody, err := c.forgejoRequest(ctx, "GET", assetsPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("listing attachments of "+repo.Name, status, respBody)
+	}
+	var existing []forgejoAttachment
+	if err := json.Unmarshal(respBody, &existing); err != nil {
+		return fmt.Errorf("failed to decode attachments: %w", err)
+	}
+	// Matched without the extension an upload may have been given
+	copies := make(map[string]string)
+	for _, attachment := range existing {
+		copies[strings.TrimSuffix(attachment.Name, path.Ext(attachment.Name))] = attachment.DownloadURL
+	}
+
+	var replacements []string
+	for _, link := range links {
+		name := path.Base(link)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		copyURL, ok := copies[strings.TrimSuffix(name, path.Ext(name))]
+		if !ok {
+			copyURL, err = c.uploadAttachment(ctx, repo, assetsPath, link, name)
+			if err != nil {
+				fmt.Printf("⚠️  Couldn't copy attachment %s of %s: %v\n", link, repo.Name, err)
+				continue
+			}
+			copies[strings.TrimSuffix(name, path.Ext(name))] = copyURL
+		}
+		replacements = append(replacements, link, copyURL)
+	}
+	rewritten := strings.NewReplacer(replacements...).Replace(body)
+	if rewritten == body {
+		return nil
+	}
+
+	status, respBody, err = c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, segments...), map[string]string{"body": rewritten})
+	if err != nil {
+		return fmt.Errorf("failed to update links to attachments: %w", err)
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return forgejoError("updating links to attachments in "+repo.Name, status, respBody)
+	}
+	return nil
+}
+
+// uploadAttachment downloads a GitHub attachment and uploads it to Forgejo,
+// returning the URL of the copy
+func (c *Client) uploadAttachment(ctx context.Context, repo *GitHubRepo, assetsPath, link, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	// Attachments of private repositories need the token. It isn't sent along
+	// when GitHub redirects to its storage on another host.
+	if _, token, err := c.migrationCredentials(repo); err == nil && token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to download: %w", err)
+	}
+
+	if path.Ext(name) == "" {
+		contentType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+		name += attachmentExtensions[strings.TrimSpace(contentType)]
+	}
+	status, body, err := c.forgejoUpload(ctx, assetsPath+"?name="+url.QueryEscape(name), "attachment", name, bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload: %w", err)
+	}
+	if status != http.StatusCreated {
+		return "", forgejoError("uploading attachment "+name, status, body)
+	}
+	var attachment forgejoAttachment
+	if err := json.Unmarshal(body, &attachment); err != nil {
+		return "", fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	return attachment.DownloadURL, nil
+}
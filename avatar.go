@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// socialPreviewQuery fetches a repository's Open Graph image. The REST API
+// doesn't expose it, so this goes through GraphQL.
+const socialPreviewQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    openGraphImageUrl
+    usesCustomOpenGraphImage
+  }
+}`
+
+// GetSocialPreview returns the URL of the custom social preview image of a
+// GitHub repository, or "" if it uses the generated default card
+func (c *Client) GetSocialPreview(ctx context.Context, repo *GitHubRepo) (string, error) {
+	gh := c.githubFor(repo)
+	payload := map[string]interface{}{
+		"query":     socialPreviewQuery,
+		"variables": map[string]string{"owner": repo.Owner, "name": repo.githubName()},
+	}
+	req, err := gh.NewRequest("POST", "graphql", payload)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Data struct {
+			Repository struct {
+				OpenGraphImageURL        string `json:"openGraphImageUrl"`
+				UsesCustomOpenGraphImage bool   `json:"usesCustomOpenGraphImage"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := gh.Do(ctx, req, &result); err != nil {
+		return "", fmt.Errorf("failed to query social preview: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return "", fmt.Errorf("failed to query social preview: %s", result.Errors[0].Message)
+	}
+	if !result.Data.Repository.UsesCustomOpenGraphImage {
+		return "", nil
+	}
+	return result.Data.Repository.OpenGraphImageURL, nil
+}
+
+// CopyRepoAvatar uploads the GitHub social preview image of a repository as
+// the avatar of its Forgejo mirror
+func (c *Client) CopyRepoAvatar(ctx context.Context, repo *GitHubRepo) error {
+	imageURL, err := c.GetSocialPreview(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if imageURL == "" {
+		return nil
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would copy social preview of %s as avatar\n", repo.Name)
+		return nil
+	}
+
+	image, err := c.download(ctx, imageURL)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{"image": base64.StdEncoding.EncodeToString(image)}
+	status, body, err := c.forgejoRequest(ctx, "POST", repoPath(c.ownerFor(repo), repo.Name, "avatar"), payload)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent && status != http.StatusOK {
+		return forgejoError("avatar upload", status, body)
+	}
+	if c.config.Verbose {
+		fmt.Printf("🖼️  Copied social preview of %s as avatar\n", repo.Name)
+	}
+	return nil
+}
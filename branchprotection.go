@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// branchProtection is the part of a Forgejo branch protection rule that
+// GitHub rulesets translate to
+type branchProtection struct {
+	RuleName              string   `json:"rule_name"`
+	EnablePush            bool     `json:"enable_push"`
+	EnableStatusCheck     bool     `json:"enable_status_check"`
+	StatusCheckContexts   []string `json:"status_check_contexts"`
+	RequiredApprovals     int      `json:"required_approvals"`
+	DismissStaleApprovals bool     `json:"dismiss_stale_approvals"`
+	BlockOnOutdatedBranch bool     `json:"block_on_outdated_branch"`
+	RequireSignedCommits  bool     `json:"require_signed_commits"`
+}
+
+// merge tightens a protection with the rules of another ruleset that applies
+// to the same branches
+func (p *branchProtection) merge(other branchProtection) {
+	p.EnablePush = p.EnablePush && other.EnablePush
+	p.EnableStatusCheck = p.EnableStatusCheck || other.EnableStatusCheck
+	for _, check := range other.StatusCheckContexts {
+		if !slices.Contains(p.StatusCheckContexts, check) {
+			p.StatusCheckContexts = append(p.StatusCheckContexts, check)
+		}
+	}
+	p.RequiredApprovals = max(p.RequiredApprovals, other.RequiredApprovals)
+	p.DismissStaleApprovals = p.DismissStaleApprovals || other.DismissStaleApprovals
+	p.BlockOnOutdatedBranch = p.BlockOnOutdatedBranch || other.BlockOnOutdatedBranch
+	p.RequireSignedCommits = p.RequireSignedCommits || other.RequireSignedCommits
+}
+
+// CopyRulesets creates Forgejo branch protection rules for the branch
+// rulesets of a GitHub repository, including those of its organization.
+// Rulesets that apply to the same branches are combined into one rule. Rules
+// Forgejo has no equivalent for are reported. Existing branch protection rules
+// are left alone.
+func (c *Client) CopyRulesets(ctx context.Context, repo *GitHubRepo) error {
+	rulesets, err := c.githubRulesets(ctx, repo, "branch")
+	if err != nil || len(rulesets) == 0 {
+		return err
+	}
+
+	protections := make(map[string]*branchProtection)
+	var patterns, unsupported []string
+	for _, ruleset := range rulesets {
+		rules, missing, err := translateRuleset(ruleset)
+		if err != nil {
+			return err
+		}
+		unsupported = append(unsupported, missing...)
+		for _, pattern := range rulesetPatterns(ruleset, repo) {
+			if p, ok := protections[pattern]; ok {
+				p.merge(rules)
+				continue
+			}
+			p := rules
+			p.RuleName = pattern
+			p.StatusCheckContexts = slices.Clone(rules.StatusCheckContexts)
+			protections[pattern] = &p
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(unsupported) > 0 {
+		fmt.Printf("⚠️  Rulesets of %s with rules Forgejo has no equivalent for: %s\n", repo.Name, strings.Join(unsupported, "; "))
+	}
+
+	owner := c.ownerFor(repo)
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, repo.Name, "branch_protections"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list branch protections: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("listing branch protections of "+repo.Name, status, body)
+	}
+	var existing []branchProtection
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return fmt.Errorf("failed to decode branch protections: %w", err)
+	}
+
+	for _, pattern := range patterns {
+		if slices.ContainsFunc(existing, func(p branchProtection) bool { return p.RuleName == pattern }) {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would protect branches matching %s on %s\n", pattern, repo.Name)
+			continue
+		}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "branch_protections"), protections[pattern])
+		if err != nil {
+			return fmt.Errorf("failed to protect branches matching %s: %w", pattern, err)
+		}
+		if status != http.StatusCreated {
+			return forgejoError("protecting branches matching "+pattern, status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("🔒 Protected branches matching %s on %s\n", pattern, repo.Name)
+		}
+	}
+	return nil
+}
+
+// translateRuleset returns the Forgejo branch protection settings of a GitHub
+// ruleset and describes the rules it can't translate
+func translateRuleset(ruleset *github.Ruleset) (branchProtection, []string, error) {
+	p := branchProtection{EnablePush: true}
+	var unsupported []string
+	if ruleset.Conditions != nil && ruleset.Conditions.RefName != nil && len(ruleset.Conditions.RefName.Exclude) > 0 {
+		unsupported = append(unsupported, fmt.Sprintf("%s: excluded branches", ruleset.Name))
+	}
+	for _, rule := range ruleset.Rules {
+		switch rule.Type {
+		case "deletion", "non_fast_forward":
+			// Forgejo never allows these on protected branches
+		case "update":
+			p.EnablePush = false
+		case "required_signatures":
+			p.RequireSignedCommits = true
+		case "pull_request":
+			var params github.PullRequestRuleParameters
+			if err := decodeRuleParameters(rule, &params); err != nil {
+				return p, nil, err
+			}
+			p.EnablePush = false
+			p.RequiredApprovals = params.RequiredApprovingReviewCount
+			p.DismissStaleApprovals = params.DismissStaleReviewsOnPush
+			if params.RequireCodeOwnerReview {
+				unsupported = append(unsupported, fmt.Sprintf("%s: code owner review", ruleset.Name))
+			}
+			if params.RequireLastPushApproval {
+				unsupported = append(unsupported, fmt.Sprintf("%s: approval of the last push", ruleset.Name))
+			}
+			if params.RequiredReviewThreadResolution {
+				unsupported = append(unsupported, fmt.Sprintf("%s: resolved review threads", ruleset.Name))
+			}
+		case "required_status_checks":
+			var params github.RequiredStatusChecksRuleParameters
+			if err := decodeRuleParameters(rule, &params); err != nil {
+				return p, nil, err
+			}
+			p.EnableStatusCheck = true
+			for _, check := range params.RequiredStatusChecks {
+				p.StatusCheckContexts = append(p.StatusCheckContexts, check.Context)
+			}
+			p.BlockOnOutdatedBranch = params.StrictRequiredStatusChecksPolicy
+		default:
+			unsupported = append(unsupported, fmt.Sprintf("%s: %s", ruleset.Name, rule.Type))
+		}
+	}
+	return p, unsupported, nil
+}
+
+// decodeRuleParameters decodes the parameters of a ruleset rule
+func decodeRuleParameters(rule *github.RepositoryRule, params interface{}) error {
+	if rule.Parameters == nil {
+		return nil
+	}
+	if err := json.Unmarshal(*rule.Parameters, params); err != nil {
+		return fmt.Errorf("failed to decode %s rule: %w", rule.Type, err)
+	}
+	return nil
+}
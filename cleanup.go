@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FindOrphans returns the Forgejo mirrors of the target owner whose source
+// repository no longer exists on GitHub. Mirrors are matched to GitHub by the
+// address they pull from, not by name, so a mirror whose name happens to match
+// a GitHub repository can still be an orphan and one named differently isn't.
+// Only mirrors of repositories the GitHub listing covers can be orphans:
+// mirrors of repositories that were filtered out, excluded with
+// --only/--exclude, owned by other GitHub accounts or not from GitHub at all
+// are left alone, and so are mirrors of renamed repositories.
+func (c *Client) FindOrphans(githubRepos []*GitHubRepo, forgejoRepos []*ForgejoRepo) []*ForgejoRepo {
+	owners := map[string]bool{strings.ToLower(c.targetOwner()): true}
+	for _, repo := range githubRepos {
+		owners[strings.ToLower(c.ownerFor(repo))] = true
+	}
+	// Renamed repositories still pull from their former address until renamed
+	renamed := make(map[string]bool)
+	for _, mirror := range c.renames {
+		renamed[strings.ToLower(mirror.owner+"/"+mirror.name)] = true
+	}
+
+	var orphans []*ForgejoRepo
+	for _, forgejoRepo := range forgejoRepos {
+		// The listing includes repos of every org the user belongs to
+		owner, _, _ := strings.Cut(forgejoRepo.FullName, "/")
+		if !owners[strings.ToLower(owner)] {
+			continue
+		}
+		if !forgejoRepo.Mirror || renamed[strings.ToLower(forgejoRepo.FullName)] {
+			continue
+		}
+		sourceOwner, sourceName, ok := githubRepoFromURL(forgejoRepo.OriginalURL)
+		if !ok || !c.listing.inScope(sourceOwner) || c.shouldSkipRepo(sourceName) {
+			continue
+		}
+		if listed, _ := c.listing.listed(sourceOwner, sourceName); listed {
+			continue
+		}
+		orphans = append(orphans, forgejoRepo)
+	}
+	return orphans
+}
+
+// confirm asks the user to confirm a destructive action, unless --yes was given
+func confirm(config *Config, prompt string) bool {
+	if config.AssumeYes {
+		return true
+	}
+	fmt.Printf("%s [y/N]: ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// CleanupOrphans applies the cleanup policy to the orphaned mirrors of this
+// client's target and records the outcome in stats
+func (c *Client) CleanupOrphans(ctx context.Context, githubRepos []*GitHubRepo, forgejoRepos []*ForgejoRepo, stats *targetStats) {
+	fmt.Printf("\n🧹 Cleaning up orphaned mirrors on %s...\n", c.name)
+	orphans := c.FindOrphans(githubRepos, forgejoRepos)
+	for _, orphan := range orphans {
+		fmt.Printf("🗑️  Found orphaned mirror: %s\n", orphan.FullName)
+	}
+
+	switch {
+	case len(orphans) == 0 || c.config.CleanupPolicy == "report":
+	case c.config.CleanupPolicy == "archive":
+		for _, orphan := range orphans {
+			if orphan.Archived {
+				continue
+			}
+			if c.config.DryRun {
+				c.plan.add(PlanAction{Action: "archive", Target: c.name, Mirror: orphan.FullName, Note: "orphaned"})
+				continue
+			}
+			owner, _, _ := strings.Cut(orphan.FullName, "/")
+			if err := c.ArchiveRepo(ctx, owner, orphan.Name); err != nil {
+				fmt.Printf("❌ Failed to archive %s: %v\n", orphan.Name, err)
+				stats.add(ResultFailed)
+				continue
+			}
+			stats.archived++
+		}
+	case c.config.DryRun || confirm(c.config, fmt.Sprintf("Delete %d orphaned mirrors on %s?", len(orphans), c.name)):
+		for _, orphan := range orphans {
+			if c.config.DryRun {
+				c.plan.add(PlanAction{Action: "delete", Target: c.name, Mirror: orphan.FullName, Note: "orphaned"})
+				continue
+			}
+			owner, _, _ := strings.Cut(orphan.FullName, "/")
+			if err := c.DeleteRepo(ctx, owner, orphan.Name); err != nil {
+				fmt.Printf("❌ Failed to delete %s: %v\n", orphan.Name, err)
+				stats.add(ResultFailed)
+				continue
+			}
+			stats.add(ResultDeleted)
+		}
+	default:
+		fmt.Println("   Deletion cancelled")
+	}
+}
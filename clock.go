@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// Clock provides the current time. Timestamps, scheduling and staleness
+// calculations go through it so reports can be made reproducible.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+}
+
+// realClock reads the system clock
+type realClock struct{}
+
+func (realClock) Now() time.Time                  { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+// frozenClock always reports the same instant
+type frozenClock struct {
+	now time.Time
+}
+
+func (c frozenClock) Now() time.Time                  { return c.now }
+func (c frozenClock) Since(t time.Time) time.Duration { return c.now.Sub(t) }
+
+// newClock returns a frozen clock if a freeze time is configured, otherwise the system clock
+func newClock(config *Config) Clock {
+	if !config.FreezeTime.IsZero() {
+		return frozenClock{now: config.FreezeTime}
+	}
+	return realClock{}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// collaboratorPermissions maps the roles of GitHub collaborators to Forgejo
+// permissions. Forgejo has no roles between them, so maintainers get write
+// and triagers read access, never more than on GitHub.
+var collaboratorPermissions = map[string]string{
+	"admin":    "admin",
+	"maintain": "write",
+	"write":    "write",
+	"triage":   "read",
+	"read":     "read",
+}
+
+// CopyCollaborators adds the Forgejo accounts --user-map maps the direct
+// collaborators of a GitHub repository to as collaborators of its copy, with
+// the equivalent permission. Mirrors are read-only and are left alone, and so
+// are existing collaborators.
+func (c *Client) CopyCollaborators(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Mirror {
+		return err
+	}
+
+	var collaborators []*github.User
+	opts := &github.ListCollaboratorsOptions{Affiliation: "direct", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := c.githubFor(repo).Repositories.ListCollaborators(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return githubError("listing collaborators of "+repo.FullName, err)
+		}
+		collaborators = append(collaborators, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	existing, err := forgejoList[struct {
+		Login string `json:"login"`
+	}](ctx, c, repoPath(owner, repo.Name, "collaborators"), "collaborators of "+repo.Name)
+	if err != nil {
+		return err
+	}
+	present := map[string]bool{strings.ToLower(owner): true}
+	for _, user := range existing {
+		present[strings.ToLower(user.Login)] = true
+	}
+
+	var unmapped []string
+	for _, collaborator := range collaborators {
+		account, ok := matchRule(c.config.UserMap, collaborator.GetLogin())
+		if !ok {
+			unmapped = append(unmapped, collaborator.GetLogin())
+			continue
+		}
+		permission, ok := collaboratorPermissions[collaborator.GetRoleName()]
+		if !ok {
+			// Custom repository roles are based on one of the above
+			permission = "read"
+			if collaborator.GetPermissions()["push"] {
+				permission = "write"
+			}
+		}
+		if present[strings.ToLower(account)] {
+			continue
+		}
+		present[strings.ToLower(account)] = true
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would add %s to %s with %s access\n", account, repo.Name, permission)
+			continue
+		}
+		status, body, err := c.forgejoRequest(ctx, "PUT", repoPath(owner, repo.Name, "collaborators", url.PathEscape(account)), map[string]string{"permission": permission})
+		if err != nil {
+			return fmt.Errorf("failed to add collaborator %s: %w", account, err)
+		}
+		if status != http.StatusNoContent {
+			return forgejoError("adding collaborator "+account+" to "+repo.Name, status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("👥 Added %s to %s with %s access\n", account, repo.Name, permission)
+		}
+	}
+	if len(unmapped) > 0 && c.config.Verbose {
+		fmt.Printf("ℹ️  Collaborators of %s without a Forgejo account in the user map: %s\n", repo.Name, strings.Join(unmapped, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// runConvert turns the selected Forgejo mirrors into regular repositories and
+// returns the exit code. Forgejo converts a mirror in place with only its git
+// data, so with --convert-with-history the mirrors are migrated again as
+// regular repositories instead, which brings the issues and pull requests
+// along with their reviews, inline code comments and review threads. With
+// --import-pulls, mirrors converted in place get their issues and pull
+// requests copied afterwards.
+func runConvert(ctx context.Context, config *Config, client *Client) int {
+	fmt.Printf("🔓 Converting mirrors on %s into regular repositories\n\n", config.ForgejoURL)
+
+	forgejoRepos, err := client.GetForgejoRepos(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch Forgejo repositories: %v", err)
+	}
+
+	var mirrors []*ForgejoRepo
+	for _, repo := range forgejoRepos {
+		owner, _, _ := strings.Cut(repo.FullName, "/")
+		if repo.Mirror && strings.EqualFold(owner, client.targetOwner()) && !client.shouldSkipRepo(repo.Name) {
+			mirrors = append(mirrors, repo)
+			fmt.Printf("   %s\n", repo.FullName)
+		}
+	}
+	if len(mirrors) == 0 {
+		fmt.Println("   No matching mirrors found")
+		return 0
+	}
+	fmt.Println()
+
+	var failed int
+	sources := make(map[string]*GitHubRepo)
+	prompt := fmt.Sprintf("Convert %d mirrors? They will stop syncing from GitHub.", len(mirrors))
+	if config.ConvertWithHistory || config.ImportPulls {
+		githubRepos, err := client.GetGitHubRepos(ctx)
+		if err != nil {
+			log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+		}
+		for _, repo := range githubRepos {
+			sources[strings.ToLower(client.ownerFor(repo)+"/"+repo.Name)] = repo
+		}
+		var found []*ForgejoRepo
+		for _, mirror := range mirrors {
+			if sources[strings.ToLower(mirror.FullName)] == nil {
+				fmt.Printf("❌ %s: no selected GitHub repository is mirrored there\n", mirror.FullName)
+				failed++
+				continue
+			}
+			found = append(found, mirror)
+		}
+		mirrors = found
+	}
+	if config.ConvertWithHistory {
+		prompt = fmt.Sprintf("Delete %d mirrors and migrate them again as regular repositories with their issues and pull requests? Changes made on the mirrors are lost.", len(mirrors))
+	}
+
+	if len(mirrors) > 0 && !config.DryRun && !confirm(config, prompt) {
+		fmt.Println("   Conversion cancelled")
+		return 1
+	}
+
+	var converted int
+	for _, repo := range mirrors {
+		var err error
+		if config.ConvertWithHistory {
+			err = client.migrateWithHistory(ctx, sources[strings.ToLower(repo.FullName)], client.targetOwner())
+		} else {
+			err = client.ConvertMirror(ctx, client.targetOwner(), repo.Name)
+			if err == nil && config.ImportPulls {
+				err = client.ImportPulls(ctx, sources[strings.ToLower(repo.FullName)])
+			}
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to convert %s: %v\n", repo.Name, err)
+			failed++
+			continue
+		}
+		converted++
+	}
+	if config.ImportPulls && !config.DryRun {
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	fmt.Printf("\n📊 Conversion Summary:\n")
+	fmt.Printf("   Converted: %d\n", converted)
+	fmt.Printf("   Failed: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// migrateWithHistory replaces a mirror with a one-time migration of its GitHub
+// repository, which Forgejo imports with issues, pull requests, reviews and
+// review comments anchored to their diff positions
+func (c *Client) migrateWithHistory(ctx context.Context, repo *GitHubRepo, owner string) error {
+	if err := c.DeleteRepo(ctx, owner, repo.Name); err != nil {
+		return err
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would migrate %s with its issues and pull requests\n", repo.Name)
+		return nil
+	}
+	result, err := c.submitMigration(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if !result.Succeeded() {
+		return fmt.Errorf("migration %s", result)
+	}
+	fmt.Printf("🔓 Migrated %s again as a regular repository with its issues and pull requests\n", repo.Name)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// forgejoDeployKey is a deploy key of a Forgejo repository
+type forgejoDeployKey struct {
+	ID  int64  `json:"id"`
+	Key string `json:"key"`
+}
+
+// CopyDeployKeys adds the read-only deploy keys of a GitHub repository to its
+// Forgejo repository, so servers and CI systems that pull with them can pull
+// from Forgejo too. Keys with write access aren't copied, and neither is the
+// key Forgejo itself clones with over SSH.
+func (c *Client) CopyDeployKeys(ctx context.Context, repo *GitHubRepo) error {
+	var keys []*github.Key
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Repositories.ListKeys(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return githubError("listing deploy keys of "+repo.FullName, err)
+		}
+		keys = append(keys, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	owner := c.ownerFor(repo)
+	existing, err := forgejoList[forgejoDeployKey](ctx, c, repoPath(owner, repo.Name, "keys"), "deploy keys of "+repo.Name)
+	if err != nil {
+		return err
+	}
+
+	var writable []string
+	for _, key := range keys {
+		if !key.GetReadOnly() {
+			writable = append(writable, key.GetTitle())
+			continue
+		}
+		if c.config.SSHPublicKey != "" && sameSSHKey(key.GetKey(), c.config.SSHPublicKey) {
+			continue
+		}
+		if hasDeployKey(existing, key.GetKey()) {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would add deploy key %q to %s\n", key.GetTitle(), repo.Name)
+			continue
+		}
+
+		payload := map[string]interface{}{"title": key.GetTitle(), "key": key.GetKey(), "read_only": true}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "keys"), payload)
+		if err != nil {
+			return fmt.Errorf("failed to add deploy key %q: %w", key.GetTitle(), err)
+		}
+		if status == http.StatusUnprocessableEntity {
+			// Forgejo refuses keys that belong to a user account
+			fmt.Printf("⚠️  Deploy key %q of %s can't be added on Forgejo: %s\n", key.GetTitle(), repo.Name, excerptOf(body))
+			continue
+		}
+		if status != http.StatusCreated {
+			return forgejoError("adding deploy key "+key.GetTitle(), status, body)
+		}
+		existing = append(existing, forgejoDeployKey{Key: key.GetKey()})
+		if c.config.Verbose {
+			fmt.Printf("🔑 Added deploy key %q to %s\n", key.GetTitle(), repo.Name)
+		}
+	}
+	if len(writable) > 0 && c.config.Verbose {
+		fmt.Printf("   Not copying deploy keys with write access to %s: %s\n", repo.Name, strings.Join(writable, ", "))
+	}
+	return nil
+}
+
+// hasDeployKey reports whether a public key is among the deploy keys
+func hasDeployKey(keys []forgejoDeployKey, key string) bool {
+	for _, k := range keys {
+		if sameSSHKey(k.Key, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// metadataBranch is the orphan branch the GitHub metadata of a
+	// repository is committed to on Forgejo
+	metadataBranch = "github-metadata"
+	// deploymentsFile holds the environments and deployments on that branch
+	deploymentsFile = "deployments.json"
+	// recentDeployments is how many of the latest deployments are kept
+	recentDeployments = 100
+)
+
+// deploymentMetadata is the content of deploymentsFile
+type deploymentMetadata struct {
+	Repository   string                `json:"repository"`
+	Environments []environmentMetadata `json:"environments"`
+	Deployments  []deploymentRecord    `json:"deployments"`
+}
+
+// environmentMetadata is a GitHub environment with its protection rules
+type environmentMetadata struct {
+	Name              string    `json:"name"`
+	URL               string    `json:"url"`
+	Created           time.Time `json:"created_at"`
+	WaitTimer         int       `json:"wait_timer,omitempty"`
+	Reviewers         []string  `json:"reviewers,omitempty"`
+	PreventSelfReview bool      `json:"prevent_self_review,omitempty"`
+	AdminsBypass      bool      `json:"can_admins_bypass"`
+	ProtectedBranches bool      `json:"protected_branches_only,omitempty"`
+	BranchPolicies    []string  `json:"branch_policies,omitempty"`
+	OtherRules        []string  `json:"other_rules,omitempty"`
+}
+
+// deploymentRecord is a GitHub deployment with its latest status
+type deploymentRecord struct {
+	ID             int64     `json:"id"`
+	Environment    string    `json:"environment"`
+	Ref            string    `json:"ref"`
+	SHA            string    `json:"sha"`
+	Task           string    `json:"task,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	Creator        string    `json:"creator"`
+	Created        time.Time `json:"created_at"`
+	State          string    `json:"state,omitempty"`
+	EnvironmentURL string    `json:"environment_url,omitempty"`
+	StateChanged   time.Time `json:"state_changed_at,omitzero"`
+}
+
+// ExportDeployments commits the environments of a GitHub repository, their
+// protection rules and its recent deployments as deploymentsFile to the
+// metadataBranch of its Forgejo copy, which Forgejo has no place for. The file
+// is only committed when it changed. Mirrors can't be pushed to, so their
+// metadata is stored once they are converted.
+func (c *Client) ExportDeployments(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil {
+		return err
+	}
+	if forgejoRepo.Mirror {
+		if c.config.Verbose {
+			fmt.Printf("ℹ️  Deployments of %s are stored once its mirror is converted, mirrors can't be pushed to\n", repo.Name)
+		}
+		return nil
+	}
+
+	metadata, err := c.githubDeployments(ctx, repo)
+	if err != nil || len(metadata.Environments) == 0 && len(metadata.Deployments) == 0 {
+		return err
+	}
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode deployments: %w", err)
+	}
+	content = append(content, '\n')
+	current, err := c.GetForgejoFileSHA(ctx, owner, repo.Name, deploymentsFile, metadataBranch)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", deploymentsFile, err)
+	}
+	if current == blobSHA(content) {
+		return nil
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would commit %d environments and %d deployments of %s to %s\n", len(metadata.Environments), len(metadata.Deployments), repo.Name, metadataBranch)
+		return nil
+	}
+
+	target := c.forgejoCloneURL(owner, repo.Name)
+	auth := []gitAuth{c.forgejoAuth(target)}
+	dir, err := os.MkdirTemp("", "metadata-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if _, err := runGit(ctx, dir, auth, "init", "--quiet"); err != nil {
+		return err
+	}
+	// Builds on the branch if it exists, as an orphan otherwise
+	if current != "" {
+		if _, err := runGit(ctx, dir, auth, "fetch", "--quiet", target, "refs/heads/"+metadataBranch); err != nil {
+			return err
+		}
+		if _, err := runGit(ctx, dir, auth, "reset", "--quiet", "FETCH_HEAD"); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, deploymentsFile), content, 0o644); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, dir, auth, "add", deploymentsFile); err != nil {
+		return err
+	}
+	email := "github-forgejo-mirror@noreply.localhost"
+	if u, err := url.Parse(c.config.ForgejoURL); err == nil && u.Hostname() != "" {
+		email = "github-forgejo-mirror@noreply." + u.Hostname()
+	}
+	if _, err := runGit(ctx, dir, auth, "-c", "user.name=github-forgejo-mirror", "-c", "user.email="+email, "commit", "--quiet", "-m", "Update environments and deployments from GitHub"); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, dir, auth, "push", "--quiet", target, "HEAD:refs/heads/"+metadataBranch); err != nil {
+		return err
+	}
+	if c.config.Verbose {
+		fmt.Printf("🚀 Committed %d environments and %d deployments of %s to %s\n", len(metadata.Environments), len(metadata.Deployments), repo.Name, metadataBranch)
+	}
+	return nil
+}
+
+// githubDeployments collects the environments of a GitHub repository and its
+// recentDeployments latest deployments, newest first
+func (c *Client) githubDeployments(ctx context.Context, repo *GitHubRepo) (*deploymentMetadata, error) {
+	gh := c.githubFor(repo)
+	metadata := &deploymentMetadata{Repository: repo.FullName, Environments: []environmentMetadata{}, Deployments: []deploymentRecord{}}
+	opts := &github.EnvironmentListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := gh.Repositories.ListEnvironments(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return nil, githubError("listing environments of "+repo.FullName, err)
+		}
+		for _, env := range list.Environments {
+			environment, err := c.environmentMetadata(ctx, repo, env)
+			if err != nil {
+				return nil, err
+			}
+			metadata.Environments = append(metadata.Environments, environment)
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	deployments, _, err := gh.Repositories.ListDeployments(ctx, repo.Owner, repo.githubName(), &github.DeploymentsListOptions{ListOptions: github.ListOptions{PerPage: recentDeployments}})
+	if err != nil {
+		return nil, githubError("listing deployments of "+repo.FullName, err)
+	}
+	for _, deployment := range deployments {
+		record := deploymentRecord{
+			ID:          deployment.GetID(),
+			Environment: deployment.GetEnvironment(),
+			Ref:         deployment.GetRef(),
+			SHA:         deployment.GetSHA(),
+			Task:        deployment.GetTask(),
+			Description: deployment.GetDescription(),
+			Creator:     deployment.GetCreator().GetLogin(),
+			Created:     deployment.GetCreatedAt().Time,
+		}
+		statuses, _, err := gh.Repositories.ListDeploymentStatuses(ctx, repo.Owner, repo.githubName(), deployment.GetID(), &github.ListOptions{PerPage: 1})
+		if err != nil {
+			return nil, githubError(fmt.Sprintf("listing statuses of deployment %d of %s", deployment.GetID(), repo.FullName), err)
+		}
+		if len(statuses) > 0 {
+			record.State = statuses[0].GetState()
+			record.EnvironmentURL = statuses[0].GetEnvironmentURL()
+			record.StateChanged = statuses[0].GetCreatedAt().Time
+		}
+		metadata.Deployments = append(metadata.Deployments, record)
+	}
+	return metadata, nil
+}
+
+// environmentMetadata describes a GitHub environment and its protection
+// rules. Reviewers are given as "user:login" or "team:slug".
+func (c *Client) environmentMetadata(ctx context.Context, repo *GitHubRepo, env *github.Environment) (environmentMetadata, error) {
+	environment := environmentMetadata{
+		Name:         env.GetName(),
+		URL:          env.GetHTMLURL(),
+		Created:      env.GetCreatedAt().Time,
+		AdminsBypass: env.GetCanAdminsBypass(),
+	}
+	for _, rule := range env.ProtectionRules {
+		switch rule.GetType() {
+		case "wait_timer":
+			environment.WaitTimer = rule.GetWaitTimer()
+		case "required_reviewers":
+			environment.PreventSelfReview = rule.GetPreventSelfReview()
+			for _, reviewer := range rule.Reviewers {
+				var account struct {
+					Login string `json:"login"`
+					Slug  string `json:"slug"`
+				}
+				if raw, err := json.Marshal(reviewer.Reviewer); err == nil {
+					json.Unmarshal(raw, &account)
+				}
+				if reviewer.GetType() == "Team" {
+					environment.Reviewers = append(environment.Reviewers, "team:"+account.Slug)
+				} else {
+					environment.Reviewers = append(environment.Reviewers, "user:"+account.Login)
+				}
+			}
+		case "branch_policy":
+			// Described by the deployment branch policy below
+		default:
+			environment.OtherRules = append(environment.OtherRules, rule.GetType())
+		}
+	}
+	if policy := env.DeploymentBranchPolicy; policy != nil {
+		environment.ProtectedBranches = policy.GetProtectedBranches()
+		if policy.GetCustomBranchPolicies() {
+			policies, _, err := c.githubFor(repo).Repositories.ListDeploymentBranchPolicies(ctx, repo.Owner, repo.githubName(), env.GetName())
+			if err != nil {
+				return environment, githubError(fmt.Sprintf("listing branch policies of environment %s of %s", env.GetName(), repo.FullName), err)
+			}
+			for _, p := range policies.BranchPolicies {
+				kind := p.GetType()
+				if kind == "" {
+					kind = "branch"
+				}
+				environment.BranchPolicies = append(environment.BranchPolicies, kind+":"+p.GetName())
+			}
+		}
+	}
+	return environment, nil
+}
+
+// blobSHA returns the git blob SHA of content, which is what Forgejo reports
+// for files
+func blobSHA(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// discussionLabel is the label of issues imported from GitHub Discussions
+const discussionLabel = "discussion"
+
+// discussionsQuery lists the discussions of a repository, most recently
+// updated first. The REST API has no discussions.
+const discussionsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    hasDiscussionsEnabled
+    discussions(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes { number title body url createdAt updatedAt closed author { login } reactions(first: 100) { nodes { content user { login } } } }
+    }
+  }
+}`
+
+// discussionCommentsQuery lists the comments of a discussion with up to 100
+// replies each
+const discussionCommentsQuery = `query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    discussion(number: $number) {
+      comments(first: 50, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { ...comment replies(first: 100) { nodes { ...comment } } }
+      }
+    }
+  }
+}
+
+fragment comment on DiscussionComment {
+  databaseId body url createdAt updatedAt isAnswer author { login }
+  reactions(first: 100) { nodes { content user { login } } }
+}`
+
+// pageInfo is the position in a GraphQL connection
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// githubDiscussion is a GitHub discussion
+type githubDiscussion struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Closed    bool      `json:"closed"`
+	Author    struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Reactions graphQLReactionNodes `json:"reactions"`
+}
+
+// discussionComment is a comment on a GitHub discussion or a reply to one
+type discussionComment struct {
+	ID        int64     `json:"databaseId"`
+	Body      string    `json:"body"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	IsAnswer  bool      `json:"isAnswer"`
+	Author    struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Reactions graphQLReactionNodes `json:"reactions"`
+	Replies   struct {
+		Nodes []discussionComment `json:"nodes"`
+	} `json:"replies"`
+}
+
+// githubGraphQL runs a GraphQL query against GitHub and decodes its data into
+// data. op names the query in errors.
+func (c *Client) githubGraphQL(ctx context.Context, repo *GitHubRepo, op, query string, variables map[string]interface{}, data interface{}) error {
+	gh := c.githubFor(repo)
+	req, err := gh.NewRequest("POST", "graphql", map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := gh.Do(ctx, req, &result); err != nil {
+		return githubError(op, err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s failed: %s", op, result.Errors[0].Message)
+	}
+	return json.Unmarshal(result.Data, data)
+}
+
+// ImportDiscussions imports the GitHub Discussions of a repository as Forgejo
+// issues labeled "discussion", with their comments and replies. Each run
+// imports new discussions and comments and updates the ones edited since the
+// last run, which the state file keeps track of.
+func (c *Client) ImportDiscussions(ctx context.Context, repo *GitHubRepo) error {
+	if c.state == nil {
+		return nil
+	}
+	owner := c.ownerFor(repo)
+	synced := c.state.DiscussionSync(repo.FullName, c.name)
+	if synced == nil {
+		synced = &IssueSyncState{Issues: make(map[int]int64), Comments: make(map[int64]int64)}
+	}
+	now := c.clock.Now()
+
+	discussions, err := c.updatedDiscussions(ctx, repo, synced.Since)
+	if err != nil || len(discussions) == 0 {
+		return err
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would import %d discussions of %s\n", len(discussions), repo.Name)
+		return nil
+	}
+	labelID, err := c.ensureLabel(ctx, owner, repo, forgejoLabel{Name: discussionLabel, Color: "#cc317c", Description: "Imported from GitHub Discussions"})
+	if err != nil {
+		return err
+	}
+
+	var created, updated int
+	// Oldest first, so the issues are numbered in the order the discussions were
+	for i := len(discussions) - 1; i >= 0; i-- {
+		discussion := discussions[i]
+		index, isNew, err := c.importDiscussion(ctx, owner, repo, discussion, labelID, synced)
+		if err != nil {
+			return err
+		}
+		if isNew {
+			created++
+		} else {
+			updated++
+		}
+		if c.config.CopyReactions {
+			if err := c.copyReactions(ctx, owner, repo, discussion.Reactions.reactions(), "issues", strconv.FormatInt(index, 10)); err != nil {
+				return err
+			}
+		}
+		if err := c.importDiscussionComments(ctx, owner, repo, discussion.Number, index, synced); err != nil {
+			return err
+		}
+		// Saved after every discussion so a failed import isn't repeated from the start
+		c.state.SetDiscussionSync(repo, c.name, synced)
+	}
+	synced.Since = now
+	c.state.SetDiscussionSync(repo, c.name, synced)
+	if c.config.Verbose {
+		fmt.Printf("💬 Imported discussions of %s: %d created, %d updated\n", repo.Name, created, updated)
+	}
+	return nil
+}
+
+// updatedDiscussions lists the discussions of a GitHub repository updated
+// since the given time, most recently updated first
+func (c *Client) updatedDiscussions(ctx context.Context, repo *GitHubRepo, since time.Time) ([]githubDiscussion, error) {
+	var discussions []githubDiscussion
+	variables := map[string]interface{}{"owner": repo.Owner, "name": repo.githubName(), "cursor": nil}
+	for {
+		var data struct {
+			Repository struct {
+				HasDiscussionsEnabled bool `json:"hasDiscussionsEnabled"`
+				Discussions           struct {
+					PageInfo pageInfo           `json:"pageInfo"`
+					Nodes    []githubDiscussion `json:"nodes"`
+				} `json:"discussions"`
+			} `json:"repository"`
+		}
+		if err := c.githubGraphQL(ctx, repo, "listing discussions of "+repo.FullName, discussionsQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		if !data.Repository.HasDiscussionsEnabled {
+			return nil, nil
+		}
+		for _, discussion := range data.Repository.Discussions.Nodes {
+			if !discussion.UpdatedAt.After(since) {
+				return discussions, nil
+			}
+			discussions = append(discussions, discussion)
+		}
+		if !data.Repository.Discussions.PageInfo.HasNextPage {
+			return discussions, nil
+		}
+		variables["cursor"] = data.Repository.Discussions.PageInfo.EndCursor
+	}
+}
+
+// importDiscussion creates or updates the Forgejo issue of a discussion and
+// returns its index and whether it was created
+func (c *Client) importDiscussion(ctx context.Context, owner string, repo *GitHubRepo, discussion githubDiscussion, labelID int64, synced *IssueSyncState) (int64, bool, error) {
+	author, body := c.authored(discussionAuthor(discussion.Author.Login), discussion.URL, discussion.CreatedAt, c.references(repo).rewrite(discussion.Body))
+	if index, ok := synced.Issues[discussion.Number]; ok {
+		state := "open"
+		if discussion.Closed {
+			state = "closed"
+		}
+		payload := map[string]interface{}{"title": discussion.Title, "body": body, "state": state}
+		status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10)), payload)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to update issue #%d: %w", index, err)
+		}
+		if status != http.StatusCreated && status != http.StatusOK {
+			return 0, false, forgejoError(fmt.Sprintf("updating issue #%d of %s", index, repo.Name), status, respBody)
+		}
+		return index, false, c.copyAttachments(ctx, owner, repo, body, "issues", strconv.FormatInt(index, 10))
+	}
+
+	payload := map[string]interface{}{"title": discussion.Title, "body": body, "closed": discussion.Closed}
+	if author == "" {
+		payload["labels"] = []int64{labelID}
+	}
+	status, respBody, err := c.forgejoRequestAs(ctx, author, "POST", repoPath(owner, repo.Name, "issues"), payload)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create issue: %w", err)
+	}
+	if status != http.StatusCreated {
+		return 0, false, forgejoError(fmt.Sprintf("creating issue for discussion %d of %s", discussion.Number, repo.FullName), status, respBody)
+	}
+	var createdIssue forgejoIssue
+	if err := json.Unmarshal(respBody, &createdIssue); err != nil {
+		return 0, false, fmt.Errorf("failed to decode issue: %w", err)
+	}
+	synced.Issues[discussion.Number] = createdIssue.Index
+	if author != "" {
+		// Forgejo drops the labels of issues opened by accounts without write access
+		status, respBody, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "issues", strconv.FormatInt(createdIssue.Index, 10), "labels"), map[string][]int64{"labels": {labelID}})
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to label issue #%d: %w", createdIssue.Index, err)
+		}
+		if status != http.StatusOK {
+			return 0, false, forgejoError(fmt.Sprintf("labeling issue #%d of %s", createdIssue.Index, repo.Name), status, respBody)
+		}
+	}
+	return createdIssue.Index, true, c.copyAttachments(ctx, owner, repo, body, "issues", strconv.FormatInt(createdIssue.Index, 10))
+}
+
+// importDiscussionComments creates the Forgejo comments of the comments and
+// replies of a discussion that weren't imported yet and updates the ones
+// edited since the last run. Replies follow the comment they reply to.
+func (c *Client) importDiscussionComments(ctx context.Context, owner string, repo *GitHubRepo, number int, index int64, synced *IssueSyncState) error {
+	variables := map[string]interface{}{"owner": repo.Owner, "name": repo.githubName(), "number": number, "cursor": nil}
+	for {
+		var data struct {
+			Repository struct {
+				Discussion struct {
+					Comments struct {
+						PageInfo pageInfo            `json:"pageInfo"`
+						Nodes    []discussionComment `json:"nodes"`
+					} `json:"comments"`
+				} `json:"discussion"`
+			} `json:"repository"`
+		}
+		if err := c.githubGraphQL(ctx, repo, fmt.Sprintf("listing comments of discussion %d of %s", number, repo.FullName), discussionCommentsQuery, variables, &data); err != nil {
+			return err
+		}
+		for _, comment := range data.Repository.Discussion.Comments.Nodes {
+			if err := c.importDiscussionComment(ctx, owner, repo, index, comment, synced); err != nil {
+				return err
+			}
+			for _, reply := range comment.Replies.Nodes {
+				if err := c.importDiscussionComment(ctx, owner, repo, index, reply, synced); err != nil {
+					return err
+				}
+			}
+		}
+		if !data.Repository.Discussion.Comments.PageInfo.HasNextPage {
+			return nil
+		}
+		variables["cursor"] = data.Repository.Discussion.Comments.PageInfo.EndCursor
+	}
+}
+
+// importDiscussionComment creates or updates the Forgejo comment of a
+// discussion comment
+func (c *Client) importDiscussionComment(ctx context.Context, owner string, repo *GitHubRepo, index int64, comment discussionComment, synced *IssueSyncState) error {
+	author, body := c.authored(discussionAuthor(comment.Author.Login), comment.URL, comment.CreatedAt, c.references(repo).rewrite(comment.Body))
+	if comment.IsAnswer {
+		body += "\n\n✅ Marked as the answer on GitHub"
+	}
+	if id, ok := synced.Comments[comment.ID]; ok {
+		if !comment.UpdatedAt.After(synced.Since) {
+			return nil
+		}
+		status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "issues", "comments", strconv.FormatInt(id, 10)), map[string]string{"body": body})
+		if err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+		if status != http.StatusOK {
+			return forgejoError(fmt.Sprintf("updating a comment on issue #%d of %s", index, repo.Name), status, respBody)
+		}
+		if err := c.copyAttachments(ctx, owner, repo, body, "issues", "comments", strconv.FormatInt(id, 10)); err != nil {
+			return err
+		}
+		return c.copyDiscussionCommentReactions(ctx, owner, repo, comment, id)
+	}
+
+	status, respBody, err := c.forgejoRequestAs(ctx, author, "POST", repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10), "comments"), map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	if status != http.StatusCreated {
+		return forgejoError(fmt.Sprintf("commenting on issue #%d of %s", index, repo.Name), status, respBody)
+	}
+	var createdComment forgejoIssue
+	if err := json.Unmarshal(respBody, &createdComment); err != nil {
+		return fmt.Errorf("failed to decode comment: %w", err)
+	}
+	synced.Comments[comment.ID] = createdComment.ID
+	if err := c.copyAttachments(ctx, owner, repo, body, "issues", "comments", strconv.FormatInt(createdComment.ID, 10)); err != nil {
+		return err
+	}
+	return c.copyDiscussionCommentReactions(ctx, owner, repo, comment, createdComment.ID)
+}
+
+// copyDiscussionCommentReactions copies the reactions on a discussion comment
+// to the Forgejo comment with the given ID if --copy-reactions is set
+func (c *Client) copyDiscussionCommentReactions(ctx context.Context, owner string, repo *GitHubRepo, comment discussionComment, id int64) error {
+	if !c.config.CopyReactions {
+		return nil
+	}
+	return c.copyReactions(ctx, owner, repo, comment.Reactions.reactions(), "issues", "comments", strconv.FormatInt(id, 10))
+}
+
+// discussionAuthor returns the login of an author, who is missing when the
+// GitHub account was deleted
+func discussionAuthor(login string) string {
+	if login == "" {
+		return "ghost"
+	}
+	return login
+}
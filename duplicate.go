@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// duplicateSuffix is appended to the name of a duplicate moved aside by
+// --on-duplicate=rename
+const duplicateSuffix = "-old"
+
+// confirmReplace keeps the prompts of concurrent workers from interleaving
+var confirmReplace sync.Mutex
+
+// duplicate returns the existing Forgejo repository that has the name of a
+// repository's mirror but isn't a mirror or migration of it, such as one
+// created by hand before the first run
+func (c *Client) duplicate(repo *GitHubRepo) *ForgejoRepo {
+	existing, ok := c.existing[strings.ToLower(c.ownerFor(repo)+"/"+repo.Name)]
+	if !ok || existing.Mirror {
+		return nil
+	}
+	// Mirrors converted to regular repositories keep their original address
+	if sameRemote(existing.OriginalURL, repo.CloneURL) || sameRemote(existing.OriginalURL, repo.SSHURL) {
+		return nil
+	}
+	return existing
+}
+
+// placeholder reports whether a duplicate is the empty repository created by
+// --empty-repos=create for a GitHub repository without commits. While the
+// GitHub repository is still empty it is left alone, an empty one being all
+// there is to create. Once commits land on GitHub, the state file tells the
+// placeholder apart from an empty repository created by hand.
+func (c *Client) placeholder(repo *GitHubRepo, dup *ForgejoRepo, empty bool) bool {
+	if !dup.Empty {
+		return false
+	}
+	if empty {
+		return true
+	}
+	target, ok := c.state.Target(repo.FullName, c.name)
+	return ok && target.EmptyRepo
+}
+
+// replacePlaceholder deletes the placeholder of a GitHub repository that got
+// its first commits, so it can be mirrored in its place
+func (c *Client) replacePlaceholder(ctx context.Context, repo *GitHubRepo, dup *ForgejoRepo) error {
+	owner, _, _ := strings.Cut(dup.FullName, "/")
+	if c.config.DryRun {
+		c.plan.add(PlanAction{Action: "delete", Target: c.name, Mirror: dup.FullName, Note: "empty repository replaced by a mirror of " + repo.FullName})
+		return nil
+	}
+	if err := c.DeleteRepo(ctx, owner, dup.Name); err != nil {
+		return err
+	}
+	c.state.SetEmptyRepo(repo, c.name, false)
+	fmt.Printf("📭 Replacing the empty repository %s with a mirror, %s has commits now\n", dup.FullName, repo.FullName)
+	return nil
+}
+
+// handleDuplicate applies the --on-duplicate policy to a duplicate. It returns
+// false if the duplicate is out of the way and the repository can be migrated.
+func (c *Client) handleDuplicate(ctx context.Context, repo *GitHubRepo, dup *ForgejoRepo) (Result, string, bool) {
+	owner, _, _ := strings.Cut(dup.FullName, "/")
+	switch c.config.OnDuplicate {
+	case "skip":
+		fmt.Printf("⏭️  Skipping %s: %s already exists on %s and isn't its mirror\n", repo.Name, dup.FullName, c.name)
+		return ResultSkipped, skipDuplicate, true
+	case "rename":
+		aside := dup.Name + duplicateSuffix
+		if c.config.DryRun {
+			c.plan.add(PlanAction{
+				Action:  "rename",
+				Target:  c.name,
+				Mirror:  dup.FullName,
+				Note:    "not a mirror of " + repo.FullName,
+				Changes: []SettingChange{{Setting: "full_name", From: dup.FullName, To: owner + "/" + aside}},
+			})
+			return 0, "", false
+		}
+		if err := c.EditRepo(ctx, owner, dup.Name, &EditRepoOption{Name: &aside}); err != nil {
+			return ResultFailed, fmt.Sprintf("❌ Failed to move %s aside: %v", dup.FullName, err), true
+		}
+		fmt.Printf("📦 Moved %s aside to %s/%s, it isn't a mirror of %s\n", dup.FullName, owner, aside, repo.FullName)
+		return 0, "", false
+	case "replace":
+		if c.config.DryRun {
+			c.plan.add(PlanAction{Action: "delete", Target: c.name, Mirror: dup.FullName, Note: "replaced by a mirror of " + repo.FullName})
+			return 0, "", false
+		}
+		confirmReplace.Lock()
+		ok := confirm(c.config, fmt.Sprintf("Delete %s on %s, which isn't a mirror of %s, to replace it with one? Anything stored only there is lost.", dup.FullName, c.name, repo.FullName))
+		confirmReplace.Unlock()
+		if !ok {
+			fmt.Printf("⏭️  Skipping %s: %s was kept\n", repo.Name, dup.FullName)
+			return ResultSkipped, skipDuplicate, true
+		}
+		if err := c.DeleteRepo(ctx, owner, dup.Name); err != nil {
+			return ResultFailed, fmt.Sprintf("❌ Failed to delete %s: %v", dup.FullName, err), true
+		}
+		fmt.Printf("🗑️  Deleted %s to replace it with a mirror of %s\n", dup.FullName, repo.FullName)
+		return 0, "", false
+	}
+	return ResultConflict, fmt.Sprintf("⚠️  Duplicate: %s already exists on %s but isn't a mirror of %s (use --on-duplicate to replace, rename or skip it)", dup.FullName, c.name, repo.FullName), true
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestPlaceholder(t *testing.T) {
+	repo := &GitHubRepo{FullName: "acme/api", Name: "api"}
+	created := &State{Repos: make(map[string]*RepoState)}
+	created.SetEmptyRepo(repo, "forgejo", true)
+
+	tests := []struct {
+		name  string
+		state *State
+		dup   *ForgejoRepo
+		empty bool
+		want  bool
+	}{
+		{"still empty on both sides", nil, &ForgejoRepo{Empty: true}, true, true},
+		{"commits on GitHub, created by the tool", created, &ForgejoRepo{Empty: true}, false, true},
+		{"commits on GitHub, no state", nil, &ForgejoRepo{Empty: true}, false, false},
+		{"commits on GitHub, not created by the tool", &State{Repos: make(map[string]*RepoState)}, &ForgejoRepo{Empty: true}, false, false},
+		{"pushed to on Forgejo", created, &ForgejoRepo{}, false, false},
+		{"has content, GitHub empty", nil, &ForgejoRepo{}, true, false},
+	}
+	for _, tt := range tests {
+		c := &Client{state: tt.state, name: "forgejo"}
+		if got := c.placeholder(repo, tt.dup, tt.empty); got != tt.want {
+			t.Errorf("%s: placeholder = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
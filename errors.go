@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// maxExcerpt caps how much of a response body is included in error messages
+const maxExcerpt = 300
+
+// APIError is a failed Forgejo or GitHub API request with an excerpt of the
+// response and, where the cause is known, a hint how to fix it
+type APIError struct {
+	Service string // "Forgejo" or "GitHub"
+	Op      string // what failed, e.g. "migration of api"
+	Status  int
+	Excerpt string
+	Hint    string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s failed: %s returned %d %s", e.Op, e.Service, e.Status, http.StatusText(e.Status))
+	if e.Excerpt != "" {
+		msg += ": " + e.Excerpt
+	}
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+// AuthError means the token was rejected
+type AuthError struct{ APIError }
+
+// PermissionError means the token is valid but not allowed to do the request
+type PermissionError struct{ APIError }
+
+// QuotaError means the Forgejo owner is out of storage quota
+type QuotaError struct{ APIError }
+
+// NotFoundError means the repository, owner or endpoint doesn't exist or
+// isn't visible to the token
+type NotFoundError struct{ APIError }
+
+// RateLimitedError means the service is throttling requests
+type RateLimitedError struct{ APIError }
+
+// forgejoError classifies a failed Forgejo API response. Server errors are
+// marked transient so they are retried.
+func forgejoError(op string, status int, body []byte) error {
+	excerpt := excerptOf(body)
+	e := APIError{Service: "Forgejo", Op: op, Status: status, Excerpt: excerpt}
+	lower := strings.ToLower(excerpt)
+
+	switch {
+	case strings.Contains(lower, "quota"):
+		e.Hint = "the owner is over its Forgejo storage quota; free up space or ask an admin to raise it"
+		return &QuotaError{e}
+	case status == http.StatusUnauthorized:
+		e.Hint = "FORGEJO_TOKEN is invalid, expired or revoked"
+		return &AuthError{e}
+	case status == http.StatusForbidden:
+		e.Hint = forgejoPermissionHint(op, lower)
+		return &PermissionError{e}
+	case status == http.StatusNotFound:
+		e.Hint = "check the owner and repository name; Forgejo also answers 404 when the token can't see the repository"
+		return &NotFoundError{e}
+	case status == http.StatusTooManyRequests:
+		e.Hint = "Forgejo is rate limiting requests; lower --concurrent"
+		return &RateLimitedError{e}
+	case strings.Contains(lower, "disallowed") || strings.Contains(lower, "allowed_domains"):
+		e.Hint = "add github.com to ALLOWED_DOMAINS in the [migrations] section of Forgejo's app.ini"
+	case strings.Contains(lower, "authentication failed") || strings.Contains(lower, "could not read username"):
+		e.Hint = "Forgejo couldn't clone from GitHub; check that GITHUB_TOKEN can read the repository"
+	case strings.Contains(lower, "mirror") && strings.Contains(lower, "disabled"):
+		e.Hint = "pull mirrors are disabled on this instance; use --mode=migrate or enable [mirror] in app.ini"
+	}
+	return transientIf(status, &e)
+}
+
+// forgejoPermissionHint explains a 403 from Forgejo
+func forgejoPermissionHint(op, message string) string {
+	switch {
+	case strings.Contains(message, "scope"):
+		return "create a Forgejo token with the scopes named in the message"
+	case strings.Contains(op, "organization"):
+		return "the token lacks the write:organization scope or the user isn't an owner of the organization"
+	case strings.Contains(op, "admin") || strings.Contains(op, "sudo"):
+		return "this needs the token of a Forgejo site administrator"
+	}
+	return "the token lacks the write:repository scope or the user has no write access to the owner"
+}
+
+// githubError classifies an error returned by go-github. Errors without a
+// response, such as network errors, are returned as they are.
+func githubError(op string, err error) error {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateErr):
+		e := APIError{Service: "GitHub", Op: op, Status: statusOf(rateErr.Response), Excerpt: rateErr.Message}
+		e.Hint = fmt.Sprintf("the rate limit resets at %s; a GitHub App gets higher limits", rateErr.Rate.Reset.Format("15:04:05"))
+		return &RateLimitedError{e}
+	case errors.As(err, &abuseErr):
+		e := APIError{Service: "GitHub", Op: op, Status: statusOf(abuseErr.Response), Excerpt: abuseErr.Message}
+		e.Hint = "GitHub's secondary rate limit was hit; lower --concurrent"
+		return &RateLimitedError{e}
+	case !errors.As(err, &respErr) || respErr.Response == nil:
+		return fmt.Errorf("%s failed: %w", op, err)
+	}
+
+	e := APIError{Service: "GitHub", Op: op, Status: respErr.Response.StatusCode, Excerpt: respErr.Message}
+	switch e.Status {
+	case http.StatusUnauthorized:
+		e.Hint = "GITHUB_TOKEN is invalid, expired or revoked"
+		return &AuthError{e}
+	case http.StatusForbidden:
+		e.Hint = "the GitHub token needs the repo scope for private repositories and read:org for organization repositories, or read access to them if it is fine-grained"
+		return &PermissionError{e}
+	case http.StatusNotFound:
+		e.Hint = "the repository or account doesn't exist, or the token can't see it"
+		return &NotFoundError{e}
+	case http.StatusTooManyRequests:
+		return &RateLimitedError{e}
+	}
+	return transientIf(e.Status, &e)
+}
+
+// statusOf returns the status code of a response that may be missing, assuming
+// GitHub's usual 403 for rate limits
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return http.StatusForbidden
+	}
+	return resp.StatusCode
+}
+
+// excerptOf returns the message of a JSON error response, or the start of
+// any other response body
+func excerptOf(body []byte) string {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	text := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		text = apiErr.Message
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxExcerpt {
+		cut := maxExcerpt
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	return text
+}
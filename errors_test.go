@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExcerptOf(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"JSON message", `{"message":"repository already exists","url":"https://forgejo.example.com/api/swagger"}`, "repository already exists"},
+		{"JSON without message", `{"errors":["invalid"]}`, `{"errors":["invalid"]}`},
+		{"plain text", "  Bad\n\tGateway \n", "Bad Gateway"},
+		{"empty", "", ""},
+		{"long", strings.Repeat("x", maxExcerpt+50), strings.Repeat("x", maxExcerpt) + "..."},
+		// ä is two bytes, the cut must not split the one straddling the limit
+		{"long multibyte", "x" + strings.Repeat("ä", maxExcerpt), "x" + strings.Repeat("ä", (maxExcerpt-1)/2) + "..."},
+	}
+	for _, tt := range tests {
+		if got := excerptOf([]byte(tt.body)); got != tt.want {
+			t.Errorf("%s: excerptOf = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
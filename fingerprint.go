@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fingerprint summarizes the GitHub metadata a mirror depends on: when the
+// repository was last pushed to, its description, visibility, archive state,
+// default branch and topics
+func (c *Client) fingerprint(repo *GitHubRepo) string {
+	topics := append([]string(nil), repo.Topics...)
+	sort.Strings(topics)
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%t\n%t\n%s\n%s", repo.PushedAt, c.descriptionFor(repo), repo.Private, repo.Archived, repo.DefaultBranch, strings.Join(topics, ","))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// unchanged reports whether a repository was mirrored successfully to this
+// target at the last run and neither its GitHub metadata nor the way it is
+// mirrored has changed since
+func (c *Client) unchanged(repo *GitHubRepo) bool {
+	if !c.config.SkipUnchanged {
+		return false
+	}
+	target, ok := c.state.Target(repo.FullName, c.name)
+	return ok &&
+		target.LastStatus == "ok" &&
+		target.Fingerprint == c.fingerprint(repo) &&
+		target.Owner == c.ownerFor(repo) &&
+		target.Name == repo.Name &&
+		target.Mode == c.config.Mode &&
+		target.Interval == c.mirrorIntervalFor(repo)
+}
+
+// pushedSinceSync reports whether a repository may have been pushed to on
+// GitHub since its mirror on this target was last created or synced. Without
+// --since-last-run, or without a record of that sync, it is always true.
+func (c *Client) pushedSinceSync(repo *GitHubRepo) bool {
+	if !c.config.SinceLastRun {
+		return true
+	}
+	target, ok := c.state.Target(repo.FullName, c.name)
+	return !ok || target.LastStatus != "ok" || target.PushedAt == "" || target.PushedAt != repo.PushedAt
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// GetBranchTips returns the tip commit SHA of every branch of a GitHub repository
+func (c *Client) GetBranchTips(ctx context.Context, repo *GitHubRepo) (map[string]string, error) {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	tips := make(map[string]string)
+
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		branches, resp, err := c.githubFor(repo).Repositories.ListBranches(ctx, owner, name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches of %s: %w", repo.FullName, err)
+		}
+		for _, branch := range branches {
+			tips[branch.GetName()] = branch.GetCommit().GetSHA()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return tips, nil
+}
+
+// DetectForcePushes compares the current branch tips with the ones recorded at
+// the last run and returns the current tips and the branches whose history was
+// rewritten (the recorded tip is no longer an ancestor of the current one)
+func (c *Client) DetectForcePushes(ctx context.Context, repo *GitHubRepo, recorded map[string]string) (map[string]string, []string, error) {
+	current, err := c.GetBranchTips(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	var rewritten []string
+	for branch, oldSHA := range recorded {
+		newSHA, ok := current[branch]
+		if !ok || newSHA == oldSHA {
+			continue
+		}
+
+		comparison, _, err := c.githubFor(repo).Repositories.CompareCommits(ctx, owner, name, oldSHA, newSHA, &github.ListOptions{PerPage: 1})
+		if err != nil {
+			// The old tip is gone entirely, which only happens after a rewrite
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotFound {
+				rewritten = append(rewritten, branch)
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to compare %s on %s: %w", branch, repo.FullName, err)
+		}
+		if status := comparison.GetStatus(); status == "diverged" || status == "behind" {
+			rewritten = append(rewritten, branch)
+		}
+	}
+
+	sort.Strings(rewritten)
+	return current, rewritten, nil
+}
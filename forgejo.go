@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// targetOwner returns the Forgejo user or organization that owns the mirrors
+func (c *Client) targetOwner() string {
+	if c.config.Organization != "" {
+		return c.config.Organization
+	}
+	return c.config.ForgejoUser
+}
+
+// forgejoRequest sends an authenticated request to the Forgejo API and returns
+// the response status code and body. The payload, if any, is sent as JSON.
+func (c *Client) forgejoRequest(ctx context.Context, method, path string, payload interface{}) (int, []byte, error) {
+	return c.forgejoRequestAs(ctx, "", method, path, payload)
+}
+
+// forgejoRequestAs is like forgejoRequest, but an admin token acts on behalf
+// of the sudo user if one is given
+func (c *Client) forgejoRequestAs(ctx context.Context, sudo, method, path string, payload interface{}) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.ForgejoURL+"/api/v1"+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
+	req.Header.Set("User-Agent", userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if sudo != "" {
+		req.Header.Set("Sudo", sudo)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// forgejoList fetches every page of a Forgejo API listing. what names the
+// listed objects in error messages.
+func forgejoList[T any](ctx context.Context, c *Client, path, what string) ([]T, error) {
+	var all []T
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	for page := 1; ; page++ {
+		status, body, err := c.forgejoRequest(ctx, "GET", fmt.Sprintf("%s%slimit=50&page=%d", path, separator, page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", what, err)
+		}
+		if status != http.StatusOK {
+			return nil, forgejoError("listing "+what, status, body)
+		}
+		var items []T
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+		}
+		if len(items) == 0 {
+			break
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// forgejoUpload sends a file to the Forgejo API as a multipart form field,
+// streaming it from content
+func (c *Client) forgejoUpload(ctx context.Context, path, field, filename string, content io.Reader) (int, []byte, error) {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile(field, filename)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.ForgejoURL+"/api/v1"+path, body)
+	if err != nil {
+		body.Close()
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	// Large files take longer than the client's usual timeout, the context
+	// still bounds the upload
+	uploader := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := uploader.Do(req)
+	if err != nil {
+		body.Close()
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// repoPath builds the API path for a repository, optionally followed by sub-path segments
+func repoPath(owner, name string, segments ...string) string {
+	path := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+	for _, segment := range segments {
+		path += "/" + segment
+	}
+	return path
+}
+
+// escapeFilePath escapes each segment of a repository file path for use in a URL
+func escapeFilePath(filePath string) string {
+	parts := strings.Split(filePath, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// GetForgejoRepo fetches a single repository from Forgejo. It returns nil
+// without an error if the repository does not exist.
+func (c *Client) GetForgejoRepo(ctx context.Context, owner, name string) (*ForgejoRepo, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Forgejo repo %s: %w", name, err)
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, forgejoError("fetching repository "+name, status, body)
+	}
+
+	var repo ForgejoRepo
+	if err := json.Unmarshal(body, &repo); err != nil {
+		return nil, fmt.Errorf("failed to decode Forgejo repo %s: %w", name, err)
+	}
+	return &repo, nil
+}
+
+// migrationPoll is how often a freshly migrated repository is checked for
+// content
+const migrationPoll = 3 * time.Second
+
+// errStillMigrating reports that Forgejo hadn't finished cloning a migrated
+// repository within --migration-wait
+var errStillMigrating = errors.New("Forgejo is still migrating it")
+
+// migrationTimeout is how long Forgejo may take to clone a repository in the
+// background after accepting a migration. Forgejo's own clone timeout for
+// migrations is 10 minutes by default, LFS objects can take longer.
+const migrationTimeout = time.Hour
+
+// migrationInProgress reports whether Forgejo is probably still cloning a
+// repository: it was created recently and is still empty, although GitHub
+// has commits. Forgejo has no API for its migration tasks.
+func migrationInProgress(repo *GitHubRepo, forgejoRepo *ForgejoRepo, now time.Time) bool {
+	return forgejoRepo.Empty && repo.Size > 0 && now.Sub(forgejoRepo.Created) < migrationTimeout
+}
+
+// WaitForMigration polls a freshly migrated repository until it has content,
+// for at most --migration-wait. Forgejo answers /repos/migrate with 201 even
+// when the clone fails in the background, leaving an empty repository or
+// removing it again. A repository that is still empty afterwards returns
+// errStillMigrating unless it is older than migrationTimeout. The wait is
+// counted in polls rather than read from the clock, so a frozen clock can't
+// stall it.
+func (c *Client) WaitForMigration(ctx context.Context, owner string, repo *GitHubRepo) error {
+	for waited := time.Duration(0); ; waited += migrationPoll {
+		forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+		if err != nil {
+			return err
+		}
+		if forgejoRepo == nil {
+			return fmt.Errorf("repository disappeared after migration, the clone from GitHub most likely failed")
+		}
+		if !forgejoRepo.Empty {
+			return nil
+		}
+		if waited >= c.config.MigrationWait {
+			if migrationInProgress(repo, forgejoRepo, c.clock.Now()) {
+				return errStillMigrating
+			}
+			return fmt.Errorf("repository is still empty after %s, the clone from GitHub most likely failed", c.clock.Since(forgejoRepo.Created).Round(time.Second))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationPoll):
+		}
+	}
+}
+
+// GetForgejoFileSHA returns the git blob SHA of a file in a Forgejo repository
+func (c *Client) GetForgejoFileSHA(ctx context.Context, owner, name, filePath, ref string) (string, error) {
+	path := repoPath(owner, name, "contents", escapeFilePath(filePath)) + "?ref=" + url.QueryEscape(ref)
+	status, body, err := c.forgejoRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusNotFound {
+		return "", nil
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var content struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.Unmarshal(body, &content); err != nil {
+		return "", fmt.Errorf("failed to decode file contents: %w", err)
+	}
+	return content.SHA, nil
+}
+
+// CreateEmptyRepo creates a regular, empty repository on Forgejo for a GitHub
+// repository that has no commits to migrate
+func (c *Client) CreateEmptyRepo(ctx context.Context, repo *GitHubRepo) (Result, error) {
+	if c.config.DryRun {
+		if _, ok := c.existing[strings.ToLower(c.ownerFor(repo)+"/"+repo.Name)]; ok {
+			return ResultAlreadyExists, nil
+		}
+		c.plan.add(PlanAction{Action: "create", Target: c.name, Repo: repo.FullName, Mirror: c.ownerFor(repo) + "/" + repo.Name, Note: "empty repository"})
+		return ResultCreated, nil
+	}
+
+	path := "/user/repos"
+	transferTo := ""
+	owner, isOrg := c.resolveOwner(repo)
+	if isOrg {
+		if err := c.ensureOwner(ctx, repo); err != nil {
+			return ResultFailed, err
+		}
+		path = "/orgs/" + url.PathEscape(owner) + "/repos"
+		if c.config.TransferToOrg {
+			existing, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+			if err != nil {
+				return ResultFailed, err
+			}
+			if existing != nil {
+				fmt.Printf("⚠️  Repository already exists: %s\n", repo.Name)
+				return ResultAlreadyExists, nil
+			}
+			path, transferTo = "/user/repos", owner
+		}
+	} else if c.config.ForgejoAdmin {
+		// Admins create repositories on behalf of other users
+		path = "/admin/users/" + url.PathEscape(owner) + "/repos"
+	}
+	payload := map[string]interface{}{
+		"name":        repo.Name,
+		"description": c.descriptionFor(repo),
+		"private":     repo.Private,
+		"template":    repo.IsTemplate,
+	}
+
+	status, body, err := c.forgejoRequest(ctx, "POST", path, payload)
+	if err != nil {
+		return ResultFailed, fmt.Errorf("failed to create repository: %w", err)
+	}
+	switch status {
+	case http.StatusCreated:
+		fmt.Printf("📭 Created empty repository: %s\n", repo.Name)
+		if transferTo != "" {
+			if err := c.TransferRepo(ctx, c.config.ForgejoUser, repo.Name, transferTo); err != nil {
+				return ResultFailed, err
+			}
+			owner = transferTo
+		}
+		if err := c.StarAndWatch(ctx, owner, repo.Name); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+		return ResultCreated, nil
+	case http.StatusConflict:
+		fmt.Printf("⚠️  Repository already exists: %s\n", repo.Name)
+		return ResultAlreadyExists, nil
+	}
+	return ResultFailed, forgejoError("creating "+repo.Name, status, body)
+}
+
+// GetForgejoBranchSHA returns the tip commit SHA of a branch in a Forgejo
+// repository, or an empty string if the branch does not exist
+func (c *Client) GetForgejoBranchSHA(ctx context.Context, owner, name, branch string) (string, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, name, "branches", escapeFilePath(branch)), nil)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusNotFound {
+		return "", nil
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var result struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode branch: %w", err)
+	}
+	return result.Commit.ID, nil
+}
+
+// EditRepoOption is the payload of Forgejo's repository edit API. Only the
+// fields that are set are changed.
+type EditRepoOption struct {
+	Name                          *string `json:"name,omitempty"`
+	Description                   *string `json:"description,omitempty"`
+	Private                       *bool   `json:"private,omitempty"`
+	HasIssues                     *bool   `json:"has_issues,omitempty"`
+	HasWiki                       *bool   `json:"has_wiki,omitempty"`
+	HasProjects                   *bool   `json:"has_projects,omitempty"`
+	DefaultBranch                 *string `json:"default_branch,omitempty"`
+	Template                      *bool   `json:"template,omitempty"`
+	AllowMergeCommits             *bool   `json:"allow_merge_commits,omitempty"`
+	AllowSquashMerge              *bool   `json:"allow_squash_merge,omitempty"`
+	AllowRebase                   *bool   `json:"allow_rebase,omitempty"`
+	DefaultMergeStyle             *string `json:"default_merge_style,omitempty"`
+	DefaultDeleteBranchAfterMerge *bool   `json:"default_delete_branch_after_merge,omitempty"`
+	Archived                      *bool   `json:"archived,omitempty"`
+	MirrorInterval                *string `json:"mirror_interval,omitempty"`
+}
+
+// EditRepo updates the settings of a Forgejo repository
+func (c *Client) EditRepo(ctx context.Context, owner, name string, opt *EditRepoOption) error {
+	status, body, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, name), opt)
+	if err != nil {
+		return fmt.Errorf("failed to edit repository: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("editing "+name, status, body)
+	}
+	return nil
+}
+
+// ArchiveRepo makes a Forgejo repository read-only
+func (c *Client) ArchiveRepo(ctx context.Context, owner, name string) error {
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would archive repository: %s\n", name)
+		return nil
+	}
+
+	archived := true
+	if err := c.EditRepo(ctx, owner, name, &EditRepoOption{Archived: &archived}); err != nil {
+		return err
+	}
+	fmt.Printf("📦 Archived repository: %s\n", name)
+	return nil
+}
+
+// ConvertMirror turns a Forgejo pull mirror into a regular repository
+func (c *Client) ConvertMirror(ctx context.Context, owner, name string) error {
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would convert mirror: %s\n", name)
+		return nil
+	}
+
+	status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, name, "convert"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to convert mirror: %w", err)
+	}
+	if status == http.StatusNotFound && c.config.TargetType == "gitea" {
+		return fmt.Errorf("Gitea has no API to convert mirrors; use the repository settings page instead")
+	}
+	if status != http.StatusOK {
+		return forgejoError("converting "+name, status, body)
+	}
+	fmt.Printf("🔓 Converted to regular repository: %s\n", name)
+	return nil
+}
+
+// TransferRepo moves a repository to another owner. Forgejo transfers into
+// organizations the token can administer immediately; otherwise the transfer
+// has to be accepted by the new owner.
+func (c *Client) TransferRepo(ctx context.Context, owner, name, newOwner string) error {
+	payload := map[string]string{"new_owner": newOwner}
+	status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, name, "transfer"), payload)
+	if err != nil {
+		return fmt.Errorf("failed to transfer repository: %w", err)
+	}
+	switch status {
+	case http.StatusAccepted:
+		fmt.Printf("📦 Transferred %s to %s\n", name, newOwner)
+		return nil
+	case http.StatusCreated:
+		return fmt.Errorf("transfer of %s to %s is waiting to be accepted by the new owner", name, newOwner)
+	}
+	return forgejoError(fmt.Sprintf("transfer of %s to organization %s", name, newOwner), status, body)
+}
+
+// download fetches a public URL, such as an avatar image
+func (c *Client) download(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed with status %d", rawURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// ForgejoUser is the account a Forgejo token belongs to
+type ForgejoUser struct {
+	Login   string `json:"login"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+// GetTokenUser returns the account the Forgejo token belongs to
+func (c *Client) GetTokenUser(ctx context.Context) (*ForgejoUser, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", "/user", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Forgejo user: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var user ForgejoUser
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("failed to decode Forgejo user: %w", err)
+	}
+	return &user, nil
+}
+
+// requireAdmin fails unless the Forgejo token belongs to a site administrator
+func (c *Client) requireAdmin(ctx context.Context) error {
+	user, err := c.GetTokenUser(ctx)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin {
+		return fmt.Errorf("admin mode requires an administrator token, but %s on %s is not an admin", user.Login, c.name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// authURL embeds basic auth credentials into an HTTPS URL. Only for URLs
+// sent to Forgejo's API, git gets its credentials through gitAuth.
+func authURL(rawURL, username, password string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.User = url.UserPassword(username, password)
+	return u.String(), nil
+}
+
+// gitAuth is the username and token git authenticates with at a remote and
+// every address below it
+type gitAuth struct {
+	url      string
+	username string
+	password string
+}
+
+// header returns the HTTP basic authorization header of a
+func (a gitAuth) header() string {
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(a.username+":"+a.password))
+}
+
+// gitAuthEnv returns the environment that makes git send the credentials of
+// auth as http.<url>.extraHeader. Unlike remote URLs with credentials, the
+// environment isn't visible to other local users in the process list and
+// isn't written to the config of clones.
+func gitAuthEnv(auth []gitAuth) []string {
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(auth))}
+	for i, a := range auth {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s.extraHeader", i, a.url),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, a.header()))
+	}
+	return env
+}
+
+// runGit runs a git command in dir with the credentials of auth and returns
+// its output. The credentials are removed from error messages in case git
+// echoes them.
+func runGit(ctx context.Context, dir string, auth []gitAuth, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), gitAuthEnv(auth)...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		output := strings.TrimSpace(string(out))
+		for _, a := range auth {
+			if a.password != "" {
+				output = strings.ReplaceAll(output, a.password, "***")
+				output = strings.ReplaceAll(output, a.header(), "***")
+			}
+		}
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, output)
+	}
+	return string(out), nil
+}
+
+// remoteMissing reports whether git failed because the remote repository
+// doesn't exist
+func remoteMissing(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "not found")
+}
+
+// githubAuth returns the credentials git uses for remote, an address of repo
+// on GitHub
+func (c *Client) githubAuth(repo *GitHubRepo, remote string) (gitAuth, error) {
+	username, token, err := c.migrationCredentials(repo)
+	if err != nil {
+		return gitAuth{}, err
+	}
+	return gitAuth{url: remote, username: username, password: token}, nil
+}
+
+// forgejoAuth returns the credentials git uses for remote, an address on
+// this Forgejo target
+func (c *Client) forgejoAuth(remote string) gitAuth {
+	return gitAuth{url: remote, username: c.config.ForgejoUser, password: c.config.ForgejoToken}
+}
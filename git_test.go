@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunGitAuth(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, r.URL.Path+" "+r.Header.Get("Authorization"))
+		http.Error(w, "not a git server", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	auth := []gitAuth{
+		{url: server.URL + "/acme/api.git", username: "bot", password: "s3cret"},
+		{url: server.URL + "/other/api.git", username: "someone", password: "else"},
+	}
+	_, err := runGit(context.Background(), "", auth, "ls-remote", server.URL+"/acme/api.git")
+	if err == nil {
+		t.Fatal("ls-remote against a server that fails succeeded")
+	}
+	if strings.Contains(err.Error(), "s3cret") || strings.Contains(err.Error(), auth[0].header()) {
+		t.Errorf("error reveals the credentials: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) == 0 {
+		t.Fatal("git sent no request")
+	}
+	for _, request := range got {
+		if want := "/acme/api.git/info/refs Basic Ym90OnMzY3JldA=="; request != want {
+			t.Errorf("request = %q, want %q", request, want)
+		}
+	}
+}
+
+func TestRemoteMissing(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("git ls-remote failed: exit status 128: remote: Repository not found.\nfatal: repository 'https://github.com/acme/api.wiki.git/' not found"), true},
+		{errors.New("git ls-remote failed: exit status 128: fatal: Authentication failed for 'https://github.com/acme/api.wiki.git/'"), false},
+		{errors.New("git ls-remote failed: exit status 128: fatal: unable to access 'https://github.com/acme/api.wiki.git/': Could not resolve host: github.com"), false},
+	}
+	for _, tt := range tests {
+		if got := remoteMissing(tt.err); got != tt.want {
+			t.Errorf("remoteMissing(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"golang.org/x/oauth2"
+)
+
+// installation is a GitHub App installation with its own auto-refreshing token
+type installation struct {
+	id      int64
+	account string
+	tokens  oauth2.TokenSource
+	client  *github.Client
+}
+
+// installationTokenSource mints installation access tokens signed with the app key
+type installationTokenSource struct {
+	config *Config
+	id     int64
+}
+
+// Token creates a new installation access token
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+	appClient, err := newAppClient(s.config)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := appClient.Apps.CreateInstallationToken(ctx, s.id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token for %d: %w", s.id, err)
+	}
+	return &oauth2.Token{AccessToken: token.GetToken(), Expiry: token.GetExpiresAt().Time}, nil
+}
+
+// loadAppKey reads an RSA private key in PKCS#1 or PKCS#8 PEM format
+func loadAppKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT creates the short-lived JWT used to authenticate as the GitHub App
+func appJWT(config *Config, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(config.GitHubAppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, config.GitHubAppKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// newAppClient creates a GitHub client authenticated as the app itself
+func newAppClient(config *Config) (*github.Client, error) {
+	// GitHub validates the JWT against its own clock, so this always uses real time
+	jwt, err := appJWT(config, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: jwt})
+	return newGitHubClient(config, ts), nil
+}
+
+// getInstallations returns the app installations to mirror from, limited to
+// the configured installation or GitHub organization if set
+func (c *Client) getInstallations(ctx context.Context) ([]*installation, error) {
+	appClient, err := newAppClient(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*installation
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		installs, resp, err := appClient.Apps.ListInstallations(ctx, opts)
+		if err != nil {
+			return nil, githubError("listing app installations", err)
+		}
+		for _, inst := range installs {
+			if c.config.GitHubAppInstallationID != 0 && inst.GetID() != c.config.GitHubAppInstallationID {
+				continue
+			}
+			account := inst.GetAccount().GetLogin()
+			if c.config.GitHubOrg != "" && !containsFold(parseStringSlice(c.config.GitHubOrg), account) {
+				continue
+			}
+
+			tokens := oauth2.ReuseTokenSource(nil, &installationTokenSource{config: c.config, id: inst.GetID()})
+			client := newGitHubClient(c.config, tokens)
+			result = append(result, &installation{
+				id:      inst.GetID(),
+				account: account,
+				tokens:  tokens,
+				client:  client,
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no matching GitHub App installations found")
+	}
+	return result, nil
+}
+
+// getRepos lists the repositories an installation has access to, together
+// with the number GitHub says it has access to
+func (inst *installation) getRepos(ctx context.Context) ([]*github.Repository, int, error) {
+	var repos []*github.Repository
+	var total int
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := inst.client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, 0, githubError("listing repositories of installation "+inst.account, err)
+		}
+		repos = append(repos, list.Repositories...)
+		total = list.GetTotalCount()
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return repos, total, nil
+}
+
+// githubFor returns the GitHub client that has access to the repository
+func (c *Client) githubFor(repo *GitHubRepo) *github.Client {
+	if repo.installation != nil {
+		return repo.installation.client
+	}
+	return c.github
+}
+
+// githubClients returns the GitHub clients of the listed repositories, those
+// of owner first. A repository that is no longer listed under its old name
+// can only be read with the installation that has access to it now, which
+// differs from the old owner's after a transfer.
+func (c *Client) githubClients(owner string, repos []*GitHubRepo) []*github.Client {
+	var clients []*github.Client
+	for _, ownerFirst := range []bool{true, false} {
+		for _, repo := range repos {
+			client := c.githubFor(repo)
+			if strings.EqualFold(repo.Owner, owner) == ownerFirst && !slices.Contains(clients, client) {
+				clients = append(clients, client)
+			}
+		}
+	}
+	if len(clients) == 0 {
+		clients = append(clients, c.github)
+	}
+	return clients
+}
+
+// migrationCredentials returns the username and token Forgejo should use to
+// pull the repository from GitHub
+func (c *Client) migrationCredentials(repo *GitHubRepo) (string, string, error) {
+	if repo.installation == nil {
+		return c.config.GitHubUser, c.config.GitHubToken, nil
+	}
+	token, err := repo.installation.tokens.Token()
+	if err != nil {
+		return "", "", err
+	}
+	return "x-access-token", token.AccessToken, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// runHealth checks when every GitHub pull mirror on the Forgejo targets last
+// synced, without calling GitHub, and returns the exit code
+func runHealth(ctx context.Context, config *Config, client *Client) int {
+	var healthy, unhealthy, paused int
+	for _, target := range client.targets() {
+		fmt.Printf("💓 Checking mirror health on %s\n", target.config.ForgejoURL)
+		repos, err := target.GetForgejoRepos(ctx)
+		if err != nil {
+			log.Fatalf("Failed to fetch Forgejo repositories: %v", err)
+		}
+		sort.Slice(repos, func(i, j int) bool { return repos[i].FullName < repos[j].FullName })
+
+		for _, repo := range repos {
+			if !repo.Mirror || repo.Archived {
+				continue
+			}
+			if _, _, ok := githubRepoFromURL(repo.OriginalURL); !ok {
+				continue
+			}
+
+			problem, interval := target.mirrorHealth(repo)
+			switch {
+			case problem != "":
+				unhealthy++
+				fmt.Printf("❌ %s: %s\n", repo.FullName, problem)
+			case interval == 0:
+				paused++
+				fmt.Printf("⏸️  %s, automatic sync disabled, last synced %s ago\n", repo.FullName, target.clock.Since(repo.MirrorUpdated).Round(time.Minute))
+			default:
+				healthy++
+				if config.Verbose {
+					fmt.Printf("✅ %s, last synced %s ago\n", repo.FullName, target.clock.Since(repo.MirrorUpdated).Round(time.Minute))
+				}
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("📊 Health Summary:\n")
+	fmt.Printf("   Healthy: %d\n", healthy)
+	fmt.Printf("   Unhealthy: %d\n", unhealthy)
+	if paused > 0 {
+		fmt.Printf("   Sync disabled: %d\n", paused)
+	}
+	if unhealthy > 0 {
+		return 1
+	}
+	return 0
+}
+
+// mirrorHealth describes why a pull mirror is unhealthy, or returns an empty
+// string, together with its sync interval. Forgejo only records successful
+// syncs, so a mirror whose syncs fail shows up as not having synced for longer
+// than --health-factor times its interval.
+func (c *Client) mirrorHealth(repo *ForgejoRepo) (string, time.Duration) {
+	interval, err := time.ParseDuration(repo.MirrorInterval)
+	if err != nil {
+		interval = 8 * time.Hour // Forgejo's default
+	}
+	if repo.MirrorUpdated.IsZero() {
+		return "never synced", interval
+	}
+	if interval == 0 {
+		return "", 0
+	}
+	since := c.clock.Since(repo.MirrorUpdated)
+	if since > time.Duration(c.config.HealthFactor)*interval {
+		return fmt.Sprintf("last synced %s ago, more than %d× its %v interval", since.Round(time.Minute), c.config.HealthFactor, interval), interval
+	}
+	return "", interval
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// forgejoIssue is the part of a Forgejo issue or comment the sync needs
+type forgejoIssue struct {
+	ID     int64          `json:"id"`
+	Index  int64          `json:"number"`
+	Labels []forgejoLabel `json:"labels"`
+}
+
+// Forgejo has neither locked conversations nor close reasons, so the sync
+// labels issues that are locked or closed as not planned on GitHub
+var (
+	lockedLabel     = forgejoLabel{Name: "locked", Color: "#57606a", Description: "Conversation locked on GitHub"}
+	notPlannedLabel = forgejoLabel{Name: "not planned", Color: "#cfd3d7", Description: "Closed as not planned on GitHub"}
+)
+
+// SyncIssues copies GitHub issues and comments created or edited since the
+// last sync to a repository that isn't a mirror, so its issue tracker doesn't
+// freeze at migration time. result is the outcome of the migration, which
+// copies every issue up to then.
+//
+// Forgejo keeps the numbers of migrated issues. Issues opened later get the
+// next free number on Forgejo, which GitHub may have given to a pull request,
+// so the state file records where they ended up.
+//
+// Mirrors are only synced with --reflect-issues, which reflects the issues
+// and comments added or edited on GitHub from then on onto the issue tracker
+// of the mirror, so people watching it follow the discussion upstream.
+func (c *Client) SyncIssues(ctx context.Context, repo *GitHubRepo, result Result) error {
+	if !repo.HasIssues || c.state == nil {
+		return nil
+	}
+	owner := c.ownerFor(repo)
+	synced := c.state.IssueSync(repo.FullName, c.name)
+	now := c.clock.Now()
+
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil {
+		return err
+	}
+	if forgejoRepo.Mirror && !c.config.ReflectIssues || !forgejoRepo.Mirror && !c.config.SyncIssues {
+		return nil
+	}
+	if synced == nil {
+		// Everything up to the migration came with it. A mirror has none of
+		// the issues, and only what happens from now on is reflected.
+		synced = &IssueSyncState{Since: forgejoRepo.Created}
+		if result == ResultCreated || forgejoRepo.Created.IsZero() || forgejoRepo.Mirror {
+			synced.Since = now
+		}
+		if !c.config.DryRun {
+			c.state.SetIssueSync(repo, c.name, synced)
+		}
+		if synced.Since.Equal(now) {
+			return nil
+		}
+	}
+	synced = synced.clone()
+
+	issues, err := c.updatedIssues(ctx, repo, synced.Since)
+	if err != nil {
+		return err
+	}
+	if c.config.DryRun {
+		if len(issues) > 0 {
+			fmt.Printf("[DRY RUN] Would sync %d issues of %s updated since %s\n", len(issues), repo.Name, synced.Since.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	milestones, err := c.milestoneIDs(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	var created, updated int
+	for _, issue := range issues {
+		index, isNew, err := c.syncIssue(ctx, owner, repo, issue, synced, !forgejoRepo.Mirror)
+		if err != nil {
+			return err
+		}
+		if isNew {
+			created++
+		} else {
+			updated++
+		}
+		if err := c.syncActivity(ctx, owner, repo, issue, index, milestones, synced); err != nil {
+			return err
+		}
+		// Saved after every issue so a failed sync isn't repeated from the start
+		c.state.SetIssueSync(repo, c.name, synced)
+	}
+	synced.Since = now
+	c.state.SetIssueSync(repo, c.name, synced)
+	if c.config.Verbose && len(issues) > 0 {
+		fmt.Printf("📝 Synced issues of %s: %d created, %d updated\n", repo.Name, created, updated)
+	}
+	return nil
+}
+
+// milestoneIDs returns the IDs of the milestones of a Forgejo repository by
+// title. Synced issues are linked to the milestone of the same title.
+func (c *Client) milestoneIDs(ctx context.Context, owner string, repo *GitHubRepo) (map[string]int64, error) {
+	existing, err := forgejoList[forgejoMilestone](ctx, c, repoPath(owner, repo.Name, "milestones")+"?state=all", "milestones of "+repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	milestones := make(map[string]int64)
+	for _, milestone := range existing {
+		milestones[milestone.Title] = milestone.ID
+	}
+	return milestones, nil
+}
+
+// syncActivity carries the triage, reactions and comments of a GitHub issue
+// over to the Forgejo issue with the given index
+func (c *Client) syncActivity(ctx context.Context, owner string, repo *GitHubRepo, issue *github.Issue, index int64, milestones map[string]int64, synced *IssueSyncState) error {
+	if err := c.syncTriage(ctx, owner, repo, issue, index, milestones); err != nil {
+		return err
+	}
+	if c.config.CopyReactions && issue.GetReactions().GetTotalCount() > 0 {
+		reactions, err := c.issueReactions(ctx, repo, issue.GetNumber())
+		if err != nil {
+			return err
+		}
+		if err := c.copyReactions(ctx, owner, repo, reactions, "issues", strconv.FormatInt(index, 10)); err != nil {
+			return err
+		}
+	}
+	return c.syncComments(ctx, owner, repo, issue.GetNumber(), index, synced)
+}
+
+// updatedIssues lists the issues of a GitHub repository updated since the
+// given time, oldest first. Pull requests are left out.
+func (c *Client) updatedIssues(ctx context.Context, repo *GitHubRepo, since time.Time) ([]*github.Issue, error) {
+	var issues []*github.Issue
+	opts := &github.IssueListByRepoOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "asc",
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		list, resp, err := c.githubFor(repo).Issues.ListByRepo(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return nil, githubError("listing issues of "+repo.FullName, err)
+		}
+		for _, issue := range list {
+			if !issue.IsPullRequest() {
+				issues = append(issues, issue)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return issues, nil
+}
+
+// syncIssue creates or updates the Forgejo issue of a GitHub issue and returns
+// its index and whether it was created. migration reports whether the issues
+// up to synced.Since came with a migration; a mirror has none of them.
+func (c *Client) syncIssue(ctx context.Context, owner string, repo *GitHubRepo, issue *github.Issue, synced *IssueSyncState, migration bool) (int64, bool, error) {
+	text := c.references(repo).rewrite(issue.GetBody())
+	index, mapped := synced.Issues[issue.GetNumber()]
+	migrated := migration && !mapped && issue.GetCreatedAt().Time.Before(synced.Since) && !synced.used(int64(issue.GetNumber()))
+	if mapped || migrated {
+		_, body := c.authored(issue.GetUser().GetLogin(), issue.GetHTMLURL(), issue.GetCreatedAt().Time, text)
+		if migrated {
+			// Migrated with its GitHub number and without attribution
+			index, body = int64(issue.GetNumber()), text
+		}
+		payload := map[string]interface{}{"title": issue.GetTitle(), "body": body, "state": issue.GetState()}
+		status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10)), payload)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to update issue #%d: %w", index, err)
+		}
+		switch {
+		case status == http.StatusCreated || status == http.StatusOK:
+			return index, false, c.copyAttachments(ctx, owner, repo, body, "issues", strconv.FormatInt(index, 10))
+		case status != http.StatusNotFound || !migrated:
+			return 0, false, forgejoError(fmt.Sprintf("updating issue #%d of %s", index, repo.Name), status, respBody)
+		}
+		// Converted mirrors have no migrated issues, so it is created below
+	}
+
+	author, body := c.authored(issue.GetUser().GetLogin(), issue.GetHTMLURL(), issue.GetCreatedAt().Time, text)
+	payload := map[string]interface{}{"title": issue.GetTitle(), "body": body, "closed": issue.GetState() == "closed"}
+	status, respBody, err := c.forgejoRequestAs(ctx, author, "POST", repoPath(owner, repo.Name, "issues"), payload)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create issue: %w", err)
+	}
+	if status != http.StatusCreated {
+		return 0, false, forgejoError(fmt.Sprintf("creating issue for %s#%d", repo.FullName, issue.GetNumber()), status, respBody)
+	}
+	var createdIssue forgejoIssue
+	if err := json.Unmarshal(respBody, &createdIssue); err != nil {
+		return 0, false, fmt.Errorf("failed to decode issue: %w", err)
+	}
+	synced.Issues[issue.GetNumber()] = createdIssue.Index
+	return createdIssue.Index, true, c.copyAttachments(ctx, owner, repo, body, "issues", strconv.FormatInt(createdIssue.Index, 10))
+}
+
+// index returns the Forgejo index of a GitHub issue: the issue the sync
+// created for it, or its own number, which migrated issues keep. It works on
+// a nil state, for repositories whose issues were never synced.
+func (i *IssueSyncState) index(number int) int64 {
+	if index, ok := i.lookup(number); ok {
+		return index
+	}
+	return int64(number)
+}
+
+// lookup returns the Forgejo index the sync recorded for a GitHub number
+func (i *IssueSyncState) lookup(number int) (int64, bool) {
+	if i == nil {
+		return 0, false
+	}
+	index, ok := i.Issues[number]
+	return index, ok
+}
+
+// syncTriage carries the milestone of a GitHub issue and its assignees that
+// --user-map knows over to its Forgejo issue, and labels it if it is locked or
+// was closed as not planned. milestones are the IDs of the Forgejo milestones
+// by title; an issue whose milestone isn't on Forgejo keeps its own. Assignees
+// and milestones are set by the account of the token, as Forgejo ignores them
+// on issues opened by accounts without write access.
+func (c *Client) syncTriage(ctx context.Context, owner string, repo *GitHubRepo, issue *github.Issue, index int64, milestones map[string]int64) error {
+	path := repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10))
+	payload := make(map[string]interface{})
+	if len(c.config.UserMap) > 0 {
+		assignees := []string{}
+		for _, assignee := range issue.Assignees {
+			if account, ok := matchRule(c.config.UserMap, assignee.GetLogin()); ok {
+				assignees = append(assignees, account)
+			}
+		}
+		payload["assignees"] = assignees
+	}
+	if issue.Milestone == nil {
+		payload["milestone"] = 0
+	} else if id, ok := milestones[issue.Milestone.GetTitle()]; ok {
+		payload["milestone"] = id
+	}
+	method, request := "PATCH", interface{}(payload)
+	if len(payload) == 0 {
+		method, request = "GET", nil
+	}
+	status, body, err := c.forgejoRequest(ctx, method, path, request)
+	if err != nil {
+		return fmt.Errorf("failed to update issue #%d: %w", index, err)
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return forgejoError(fmt.Sprintf("updating issue #%d of %s", index, repo.Name), status, body)
+	}
+	var current forgejoIssue
+	if err := json.Unmarshal(body, &current); err != nil {
+		return fmt.Errorf("failed to decode issue: %w", err)
+	}
+
+	wanted := map[forgejoLabel]bool{
+		lockedLabel:     issue.GetLocked(),
+		notPlannedLabel: issue.GetState() == "closed" && issue.GetStateReason() == "not_planned",
+	}
+	for label, want := range wanted {
+		var labelID int64
+		for _, l := range current.Labels {
+			if l.Name == label.Name {
+				labelID = l.ID
+			}
+		}
+		switch {
+		case want && labelID == 0:
+			id, err := c.ensureLabel(ctx, owner, repo, label)
+			if err != nil {
+				return err
+			}
+			status, body, err = c.forgejoRequest(ctx, "POST", path+"/labels", map[string][]int64{"labels": {id}})
+		case !want && labelID != 0:
+			status, body, err = c.forgejoRequest(ctx, "DELETE", path+"/labels/"+strconv.FormatInt(labelID, 10), nil)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to label issue #%d: %w", index, err)
+		}
+		if status != http.StatusOK && status != http.StatusNoContent {
+			return forgejoError(fmt.Sprintf("labeling issue #%d of %s %q", index, repo.Name, label.Name), status, body)
+		}
+	}
+	return nil
+}
+
+// used reports whether the sync created the Forgejo issue with the given
+// index for another GitHub issue
+func (i *IssueSyncState) used(index int64) bool {
+	for _, created := range i.Issues {
+		if created == index {
+			return true
+		}
+	}
+	return false
+}
+
+// syncComments creates or updates the Forgejo comments of the GitHub comments
+// on an issue added or edited since the last sync. Comments that came with the
+// migration can't be told apart on Forgejo, so edits to them aren't synced.
+func (c *Client) syncComments(ctx context.Context, owner string, repo *GitHubRepo, number int, index int64, synced *IssueSyncState) error {
+	since := synced.Since
+	opts := &github.IssueListCommentsOptions{Since: &since, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := c.githubFor(repo).Issues.ListComments(ctx, repo.Owner, repo.githubName(), number, opts)
+		if err != nil {
+			return githubError(fmt.Sprintf("listing comments of %s#%d", repo.FullName, number), err)
+		}
+		for _, comment := range comments {
+			author, body := c.authored(comment.GetUser().GetLogin(), comment.GetHTMLURL(), comment.GetCreatedAt().Time, c.references(repo).rewrite(comment.GetBody()))
+			if id, ok := synced.Comments[comment.GetID()]; ok {
+				status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "issues", "comments", strconv.FormatInt(id, 10)), map[string]string{"body": body})
+				if err != nil {
+					return fmt.Errorf("failed to update comment: %w", err)
+				}
+				if status != http.StatusOK {
+					return forgejoError(fmt.Sprintf("updating a comment on issue #%d of %s", index, repo.Name), status, respBody)
+				}
+				if err := c.copyAttachments(ctx, owner, repo, body, "issues", "comments", strconv.FormatInt(id, 10)); err != nil {
+					return err
+				}
+				if err := c.syncCommentReactions(ctx, owner, repo, comment, id); err != nil {
+					return err
+				}
+				continue
+			}
+			if comment.GetCreatedAt().Time.Before(synced.Since) {
+				continue
+			}
+			status, respBody, err := c.forgejoRequestAs(ctx, author, "POST", repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10), "comments"), map[string]string{"body": body})
+			if err != nil {
+				return fmt.Errorf("failed to create comment: %w", err)
+			}
+			if status != http.StatusCreated {
+				return forgejoError(fmt.Sprintf("commenting on issue #%d of %s", index, repo.Name), status, respBody)
+			}
+			var createdComment forgejoIssue
+			if err := json.Unmarshal(respBody, &createdComment); err != nil {
+				return fmt.Errorf("failed to decode comment: %w", err)
+			}
+			synced.Comments[comment.GetID()] = createdComment.ID
+			if err := c.copyAttachments(ctx, owner, repo, body, "issues", "comments", strconv.FormatInt(createdComment.ID, 10)); err != nil {
+				return err
+			}
+			if err := c.syncCommentReactions(ctx, owner, repo, comment, createdComment.ID); err != nil {
+				return err
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return nil
+}
+
+// syncCommentReactions copies the reactions on a GitHub comment to the
+// Forgejo comment with the given ID if --copy-reactions is set
+func (c *Client) syncCommentReactions(ctx context.Context, owner string, repo *GitHubRepo, comment *github.IssueComment, id int64) error {
+	if !c.config.CopyReactions || comment.GetReactions().GetTotalCount() == 0 {
+		return nil
+	}
+	reactions, err := c.commentReactions(ctx, repo, comment.GetID())
+	if err != nil {
+		return err
+	}
+	return c.copyReactions(ctx, owner, repo, reactions, "issues", "comments", strconv.FormatInt(id, 10))
+}
+
+// authored returns the Forgejo account that posts a GitHub issue or comment
+// and the text it posts: the account --user-map maps the GitHub author to, or
+// the account of the token ("") with a line crediting the author
+func (c *Client) authored(login, htmlURL string, created time.Time, body string) (string, string) {
+	if account, ok := matchRule(c.config.UserMap, login); ok && login != "" {
+		return account, body
+	}
+	return "", attribution(login, htmlURL, created) + body
+}
+
+// attribution credits the GitHub author of an issue or comment created on
+// Forgejo by the sync, which posts as the owner of the Forgejo token
+func attribution(login, htmlURL string, created time.Time) string {
+	return fmt.Sprintf("> Originally posted by @%s on [GitHub](%s) on %s\n\n", login, htmlURL, created.UTC().Format("2006-01-02 15:04 MST"))
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// forgejoLabel is a label of a Forgejo repository
+type forgejoLabel struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// forgejoMilestone is a milestone of a Forgejo repository
+type forgejoMilestone struct {
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	State        string     `json:"state"`
+	DueOn        *time.Time `json:"due_on"`
+	OpenIssues   int        `json:"open_issues"`
+	ClosedIssues int        `json:"closed_issues"`
+}
+
+// syncCounts counts what a sync changed
+type syncCounts struct {
+	created, updated, deleted int
+}
+
+func (s syncCounts) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d deleted", s.created, s.updated, s.deleted)
+}
+
+// SyncLabels makes the labels and milestones of a repository that isn't a
+// mirror match GitHub exactly. Labels and milestones are matched by name, and
+// ones missing on GitHub are only deleted with --prune-labels. result is the
+// outcome of the migration: Forgejo imports labels and milestones in the
+// background after creating a repository, so they are synced on the next run.
+func (c *Client) SyncLabels(ctx context.Context, repo *GitHubRepo, result Result) error {
+	if result == ResultCreated {
+		return nil
+	}
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Mirror {
+		return err
+	}
+
+	labels, err := c.syncLabels(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	milestones, err := c.syncMilestones(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	if c.config.Verbose {
+		fmt.Printf("🏷️  Synced labels of %s: %s; milestones: %s\n", repo.Name, labels, milestones)
+	}
+	return nil
+}
+
+// syncLabels creates, updates and deletes the labels of a Forgejo repository
+func (c *Client) syncLabels(ctx context.Context, owner string, repo *GitHubRepo) (syncCounts, error) {
+	var counts syncCounts
+	var labels []*github.Label
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Issues.ListLabels(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return counts, githubError("listing labels of "+repo.FullName, err)
+		}
+		labels = append(labels, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	existing, err := forgejoList[forgejoLabel](ctx, c, repoPath(owner, repo.Name, "labels"), "labels of "+repo.Name)
+	if err != nil {
+		return counts, err
+	}
+	byName := make(map[string]forgejoLabel)
+	for _, label := range existing {
+		byName[label.Name] = label
+	}
+
+	for _, label := range labels {
+		payload := map[string]string{"name": label.GetName(), "color": "#" + label.GetColor(), "description": label.GetDescription()}
+		current, ok := byName[label.GetName()]
+		if !ok {
+			// GitHub's label names ignore case and Forgejo's don't, so a label
+			// renamed to other case on GitHub is renamed on Forgejo too
+			for name, candidate := range byName {
+				if strings.EqualFold(strings.TrimSpace(name), label.GetName()) {
+					current, ok = candidate, true
+					break
+				}
+			}
+		}
+		delete(byName, current.Name)
+		switch {
+		case !ok:
+			if _, err := c.labelRequest(ctx, "POST", repoPath(owner, repo.Name, "labels"), payload, "label "+label.GetName()); err != nil {
+				return counts, err
+			}
+			counts.created++
+		case current.Name != label.GetName() || !strings.EqualFold(strings.TrimPrefix(current.Color, "#"), label.GetColor()) || strings.TrimSpace(current.Description) != strings.TrimSpace(label.GetDescription()):
+			if _, err := c.labelRequest(ctx, "PATCH", repoPath(owner, repo.Name, "labels", strconv.FormatInt(current.ID, 10)), payload, "label "+label.GetName()); err != nil {
+				return counts, err
+			}
+			counts.updated++
+		}
+	}
+	if c.config.PruneLabels {
+		for name, label := range byName {
+			if _, err := c.labelRequest(ctx, "DELETE", repoPath(owner, repo.Name, "labels", strconv.FormatInt(label.ID, 10)), nil, "label "+name); err != nil {
+				return counts, err
+			}
+			counts.deleted++
+		}
+	}
+	return counts, nil
+}
+
+// syncMilestones creates, updates and deletes the milestones of a Forgejo
+// repository
+func (c *Client) syncMilestones(ctx context.Context, owner string, repo *GitHubRepo) (syncCounts, error) {
+	var counts syncCounts
+	var milestones []*github.Milestone
+	opts := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := c.githubFor(repo).Issues.ListMilestones(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return counts, githubError("listing milestones of "+repo.FullName, err)
+		}
+		milestones = append(milestones, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	existing, err := forgejoList[forgejoMilestone](ctx, c, repoPath(owner, repo.Name, "milestones")+"?state=all", "milestones of "+repo.Name)
+	if err != nil {
+		return counts, err
+	}
+	byTitle := make(map[string]forgejoMilestone)
+	for _, milestone := range existing {
+		byTitle[milestone.Title] = milestone
+	}
+
+	for _, milestone := range milestones {
+		payload := map[string]interface{}{"title": milestone.GetTitle(), "description": milestone.GetDescription(), "state": milestone.GetState()}
+		var due *time.Time
+		if milestone.DueOn != nil {
+			due = &milestone.DueOn.Time
+			payload["due_on"] = due
+		}
+		current, ok := byTitle[milestone.GetTitle()]
+		delete(byTitle, milestone.GetTitle())
+		switch {
+		case !ok:
+			body, err := c.labelRequest(ctx, "POST", repoPath(owner, repo.Name, "milestones"), payload, "milestone "+milestone.GetTitle())
+			if err != nil {
+				return counts, err
+			}
+			// Forgejo only applies the state when editing
+			var created forgejoMilestone
+			if milestone.GetState() == "closed" && json.Unmarshal(body, &created) == nil && created.ID != 0 {
+				if _, err := c.labelRequest(ctx, "PATCH", repoPath(owner, repo.Name, "milestones", strconv.FormatInt(created.ID, 10)), payload, "milestone "+milestone.GetTitle()); err != nil {
+					return counts, err
+				}
+			}
+			counts.created++
+		case current.Description != milestone.GetDescription() || current.State != milestone.GetState() || !sameDay(current.DueOn, due):
+			if _, err := c.labelRequest(ctx, "PATCH", repoPath(owner, repo.Name, "milestones", strconv.FormatInt(current.ID, 10)), payload, "milestone "+milestone.GetTitle()); err != nil {
+				return counts, err
+			}
+			counts.updated++
+		}
+	}
+	if c.config.PruneLabels {
+		for title, milestone := range byTitle {
+			if _, err := c.labelRequest(ctx, "DELETE", repoPath(owner, repo.Name, "milestones", strconv.FormatInt(milestone.ID, 10)), nil, "milestone "+title); err != nil {
+				return counts, err
+			}
+			counts.deleted++
+		}
+	}
+	return counts, nil
+}
+
+// verifyMilestones compares the milestones of a repository on GitHub and
+// Forgejo by title and describes the ones that are missing or whose state, due
+// date or progress differ. Progress is the share of closed issues and pull
+// requests, which Forgejo counts like GitHub.
+func (c *Client) verifyMilestones(ctx context.Context, repo *GitHubRepo) []string {
+	var milestones []*github.Milestone
+	opts := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := c.githubFor(repo).Issues.ListMilestones(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return []string{githubError("listing milestones of "+repo.FullName, err).Error()}
+		}
+		milestones = append(milestones, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	existing, err := forgejoList[forgejoMilestone](ctx, c, repoPath(c.ownerFor(repo), repo.Name, "milestones")+"?state=all", "milestones of "+repo.Name)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	byTitle := make(map[string]forgejoMilestone)
+	for _, milestone := range existing {
+		byTitle[milestone.Title] = milestone
+	}
+
+	var problems []string
+	for _, milestone := range milestones {
+		title := milestone.GetTitle()
+		current, ok := byTitle[title]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("milestone %q is missing on Forgejo", title))
+			continue
+		}
+		var due *time.Time
+		if milestone.DueOn != nil {
+			due = &milestone.DueOn.Time
+		}
+		if current.State != milestone.GetState() {
+			problems = append(problems, fmt.Sprintf("milestone %q is %s on GitHub but %s on Forgejo", title, milestone.GetState(), current.State))
+		}
+		if !sameDay(current.DueOn, due) {
+			problems = append(problems, fmt.Sprintf("milestone %q is due on another day on Forgejo", title))
+		}
+		want := progress(milestone.GetClosedIssues(), milestone.GetOpenIssues())
+		got := progress(current.ClosedIssues, current.OpenIssues)
+		if milestone.GetClosedIssues() != current.ClosedIssues || milestone.GetOpenIssues() != current.OpenIssues {
+			problems = append(problems, fmt.Sprintf("milestone %q is %d%% done on GitHub (%d of %d) but %d%% on Forgejo (%d of %d)",
+				title, want, milestone.GetClosedIssues(), milestone.GetClosedIssues()+milestone.GetOpenIssues(),
+				got, current.ClosedIssues, current.ClosedIssues+current.OpenIssues))
+		}
+	}
+	return problems
+}
+
+// progress returns the share of closed issues in percent
+func progress(closed, open int) int {
+	if closed+open == 0 {
+		return 0
+	}
+	return closed * 100 / (closed + open)
+}
+
+// ensureLabel returns the ID of the label of a Forgejo repository with the
+// name of want, creating it if needed
+func (c *Client) ensureLabel(ctx context.Context, owner string, repo *GitHubRepo, want forgejoLabel) (int64, error) {
+	labels, err := forgejoList[forgejoLabel](ctx, c, repoPath(owner, repo.Name, "labels"), "labels of "+repo.Name)
+	if err != nil {
+		return 0, err
+	}
+	for _, label := range labels {
+		if label.Name == want.Name {
+			return label.ID, nil
+		}
+	}
+	payload := map[string]string{"name": want.Name, "color": want.Color, "description": want.Description}
+	body, err := c.labelRequest(ctx, "POST", repoPath(owner, repo.Name, "labels"), payload, "label "+want.Name)
+	if err != nil {
+		return 0, err
+	}
+	var label forgejoLabel
+	if err := json.Unmarshal(body, &label); err != nil {
+		return 0, fmt.Errorf("failed to decode label: %w", err)
+	}
+	return label.ID, nil
+}
+
+// labelRequest creates (POST), updates (PATCH) or deletes (DELETE) a label or
+// milestone, or prints what it would do in a dry run. what names it in
+// messages, e.g. "label bug".
+func (c *Client) labelRequest(ctx context.Context, method, path string, payload interface{}, what string) ([]byte, error) {
+	verb, want := "update", http.StatusOK
+	switch method {
+	case "POST":
+		verb, want = "create", http.StatusCreated
+	case "DELETE":
+		verb, want = "delete", http.StatusNoContent
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would %s %s\n", verb, what)
+		return nil, nil
+	}
+	status, body, err := c.forgejoRequest(ctx, method, path, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", verb, what, err)
+	}
+	if status != want {
+		return nil, forgejoError(strings.TrimSuffix(verb, "e")+"ing "+what, status, body)
+	}
+	return body, nil
+}
+
+// sameDay reports whether two optional due dates fall on the same day
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.UTC().Format(time.DateOnly) == b.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// lfsEndpoint returns the HTTPS Git LFS endpoint of a GitHub repository
+func lfsEndpoint(repo *GitHubRepo) string {
+	return strings.TrimSuffix(repo.CloneURL, ".git") + ".git/info/lfs"
+}
+
+// lfsPatterns returns the patterns of the root .gitattributes file that are
+// stored with Git LFS
+func lfsPatterns(attributes []byte) []string {
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(attributes))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				patterns = append(patterns, fields[0])
+				break
+			}
+		}
+	}
+	return patterns
+}
+
+// matchesLFS reports whether a file path matches one of the LFS patterns.
+// Patterns without a slash match the file name in any directory, like git does.
+func matchesLFS(patterns []string, filePath string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "/")
+		target := filePath
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(filePath)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+		// "dir/**" stores a whole directory in LFS
+		if prefix, found := strings.CutSuffix(pattern, "/**"); found && strings.HasPrefix(filePath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// lfsPointerSize returns the object size recorded in a Git LFS pointer file
+func lfsPointerSize(pointer []byte) (int64, bool) {
+	if !bytes.HasPrefix(pointer, []byte("version https://git-lfs")) {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(pointer), "\n") {
+		if value, found := strings.CutPrefix(line, "size "); found {
+			size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return size, err == nil
+		}
+	}
+	return 0, false
+}
+
+// getForgejoMediaSize returns the size of a file as served by Forgejo's media
+// endpoint, which resolves LFS pointers to the stored object. A missing object
+// is served as the pointer itself.
+func (c *Client) getForgejoMediaSize(ctx context.Context, owner, name, filePath, ref string) (int64, error) {
+	reqURL := c.config.ForgejoURL + "/api/v1" + repoPath(owner, name, "media", escapeFilePath(filePath)) + "?ref=" + url.QueryEscape(ref)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
+	req.Header.Set("User-Agent", userAgent)
+
+	// Only the headers are needed, so the body is never read
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Forgejo API returned status %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
+}
+
+// verifyLFS checks that every LFS object on the default branch of a GitHub
+// repository exists on Forgejo with the size recorded in its pointer. It
+// returns the number of objects, their total size and any problems.
+func (c *Client) verifyLFS(ctx context.Context, repo *GitHubRepo) (int, int64, []string) {
+	gh := c.githubFor(repo)
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	tree, _, err := gh.Git.GetTree(ctx, owner, name, repo.DefaultBranch, true)
+	if err != nil {
+		return 0, 0, []string{fmt.Sprintf("failed to list files on GitHub: %v", err)}
+	}
+
+	var attributesSHA string
+	for _, entry := range tree.Entries {
+		if entry.GetPath() == ".gitattributes" {
+			attributesSHA = entry.GetSHA()
+		}
+	}
+	if attributesSHA == "" {
+		return 0, 0, nil
+	}
+	attributes, _, err := gh.Git.GetBlobRaw(ctx, owner, name, attributesSHA)
+	if err != nil {
+		return 0, 0, []string{fmt.Sprintf("failed to read .gitattributes: %v", err)}
+	}
+	patterns := lfsPatterns(attributes)
+	if len(patterns) == 0 {
+		return 0, 0, nil
+	}
+
+	var count int
+	var total int64
+	var problems []string
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" || !matchesLFS(patterns, entry.GetPath()) {
+			continue
+		}
+		pointer, _, err := gh.Git.GetBlobRaw(ctx, owner, name, entry.GetSHA())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("failed to read LFS pointer %s: %v", entry.GetPath(), err))
+			continue
+		}
+		want, ok := lfsPointerSize(pointer)
+		if !ok {
+			// Committed without LFS despite the attributes
+			continue
+		}
+		count++
+		total += want
+
+		got, err := c.getForgejoMediaSize(ctx, c.ownerFor(repo), repo.Name, entry.GetPath(), repo.DefaultBranch)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("failed to fetch LFS object %s from Forgejo: %v", entry.GetPath(), err))
+		case got != want:
+			problems = append(problems, fmt.Sprintf("LFS object %s is missing on Forgejo (expected %d bytes, got %d)", entry.GetPath(), want, got))
+		}
+	}
+	return count, total, problems
+}
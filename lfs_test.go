@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLFSPatterns(t *testing.T) {
+	attributes := []byte(`# Binary assets
+*.psd filter=lfs diff=lfs merge=lfs -text
+assets/** filter=lfs diff=lfs merge=lfs -text
+
+*.txt text eol=lf
+/video/*.mp4 -text filter=lfs
+#*.zip filter=lfs
+*.bin
+`)
+	want := []string{"*.psd", "assets/**", "/video/*.mp4"}
+	if got := lfsPatterns(attributes); !slices.Equal(got, want) {
+		t.Errorf("lfsPatterns = %v, want %v", got, want)
+	}
+	if got := lfsPatterns(nil); len(got) != 0 {
+		t.Errorf("lfsPatterns(nil) = %v, want none", got)
+	}
+}
+
+func TestMatchesLFS(t *testing.T) {
+	patterns := []string{"*.psd", "assets/**", "/video/*.mp4"}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"logo.psd", true},
+		{"design/deep/logo.psd", true},
+		{"assets/img/logo.png", true},
+		{"other/assets/logo.png", false},
+		{"video/intro.mp4", true},
+		{"video/raw/intro.mp4", false},
+		{"intro.mp4", false},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchesLFS(patterns, tt.path); got != tt.want {
+			t.Errorf("matchesLFS(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLFSPointerSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		pointer string
+		size    int64
+		ok      bool
+	}{
+		{"pointer", "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n", 12345, true},
+		{"no size", "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a\n", 0, false},
+		{"invalid size", "version https://git-lfs.github.com/spec/v1\nsize many\n", 0, false},
+		{"regular file", "size 12345\n", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		size, ok := lfsPointerSize([]byte(tt.pointer))
+		if size != tt.size || ok != tt.ok {
+			t.Errorf("%s: lfsPointerSize = %d, %v, want %d, %v", tt.name, size, ok, tt.size, tt.ok)
+		}
+	}
+}
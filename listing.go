@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// githubListing is the last GitHub listing before filters, shared by all
+// targets. It tells repositories that were filtered out apart from ones that
+// are gone.
+type githubListing struct {
+	// Visibility of every listed repository, by lowercase full name
+	private map[string]bool
+	// Lowercase GitHub owners the listing covers. For searches, the owners
+	// of their user: and org: qualifiers.
+	owners map[string]bool
+}
+
+// newGitHubListing returns an empty listing
+func newGitHubListing() *githubListing {
+	return &githubListing{private: make(map[string]bool), owners: make(map[string]bool)}
+}
+
+// record replaces the listing with the given repositories and owners
+func (l *githubListing) record(repos map[string]bool, owners []string) {
+	l.private = repos
+	l.owners = make(map[string]bool)
+	for _, owner := range owners {
+		l.owners[strings.ToLower(owner)] = true
+	}
+}
+
+// listed reports whether a repository was part of the listing, and whether it is private
+func (l *githubListing) listed(owner, name string) (listed, private bool) {
+	private, listed = l.private[strings.ToLower(owner+"/"+name)]
+	return listed, private
+}
+
+// inScope reports whether repositories of a GitHub owner are covered by the listing
+func (l *githubListing) inScope(owner string) bool {
+	return l.owners[strings.ToLower(owner)]
+}
+
+// searchOwners returns the owners a GitHub search query is limited to by its
+// user: and org: qualifiers. A query without them can match any repository,
+// so it doesn't tell which mirrors are orphans.
+func searchOwners(query string) []string {
+	var owners []string
+	for _, term := range strings.Fields(query) {
+		qualifier, owner, ok := strings.Cut(term, ":")
+		switch strings.ToLower(qualifier) {
+		case "user", "org":
+			if ok && owner != "" {
+				owners = append(owners, strings.Trim(owner, `"`))
+			}
+		}
+	}
+	return owners
+}
+
+// doubtListing records a reason to believe the GitHub listing is incomplete.
+// Cleanup treats every mirror missing from an incomplete listing as an
+// orphan, so --strict skips it when there is any doubt.
+func (c *Client) doubtListing(format string, args ...interface{}) {
+	c.listingIssues = append(c.listingIssues, fmt.Sprintf(format, args...))
+}
+
+// checkOrgListing compares the number of repositories listed for a GitHub
+// organization with the count GitHub reports for it. The private count is
+// only visible to organization owners; without it only public repositories
+// are checked.
+func (c *Client) checkOrgListing(ctx context.Context, org string, listed int) {
+	info, _, err := c.github.Organizations.Get(ctx, org)
+	if err != nil {
+		c.doubtListing("failed to read the repository count of %s: %v", org, err)
+		return
+	}
+	expected := info.GetPublicRepos() + int(info.GetTotalPrivateRepos())
+	if listed < expected {
+		c.doubtListing("listed %d repositories of %s, but GitHub counts %d", listed, org, expected)
+	}
+}
+
+// checkUserListing compares the number of repositories listed for the
+// authenticated user with the count GitHub reports for the account
+func (c *Client) checkUserListing(ctx context.Context, listed int) {
+	user, _, err := c.github.Users.Get(ctx, "")
+	if err != nil {
+		c.doubtListing("failed to read the repository count of the GitHub user: %v", err)
+		return
+	}
+	expected := user.GetPublicRepos() + int(user.GetOwnedPrivateRepos())
+	if listed < expected {
+		c.doubtListing("listed %d repositories of %s, but GitHub counts %d", listed, user.GetLogin(), expected)
+	}
+}
+
+// checkListingShrink compares the size of the listing with the last run's
+func (c *Client) checkListingShrink(listed int) {
+	previous := c.state.PreviousListed()
+	if previous > 0 && listed < previous/2 {
+		c.doubtListing("listed %d repositories, less than half of the %d listed by the last run", listed, previous)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchOwners(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"org:acme topic:platform archived:false", []string{"acme"}},
+		{"user:alice org:Acme", []string{"alice", "Acme"}},
+		{"ORG:acme", []string{"acme"}},
+		{"topic:platform language:go", nil},
+		{"-org:acme topic:platform", nil},
+		{"repo:acme/api", nil},
+		{"org: topic:x", nil},
+	}
+	for _, tt := range tests {
+		if got := searchOwners(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("searchOwners(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestListingInScope(t *testing.T) {
+	listing := newGitHubListing()
+	listing.record(nil, searchOwners("topic:platform"))
+	if listing.inScope("acme") {
+		t.Error("a search without owners must not put any owner in scope")
+	}
+	listing.record(nil, []string{"Acme"})
+	if !listing.inScope("acme") || listing.inScope("beta") {
+		t.Error("only the listed owners are in scope, regardless of case")
+	}
+}
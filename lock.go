@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often a run waiting for the lock checks again
+const lockPollInterval = 5 * time.Second
+
+// heldLock keeps the lock file open, and so locked, until the process exits.
+// The operating system releases the lock even if the process is killed.
+var heldLock *os.File
+
+// acquireLock takes an exclusive lock on path so overlapping runs can't race
+// each other creating the same repositories or writing the state file. If
+// another run holds the lock, it waits up to wait for it to be released.
+func acquireLock(path string, wait time.Duration) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	announced := false
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+
+		holder, _ := os.ReadFile(path)
+		if time.Now().After(deadline) {
+			file.Close()
+			return fmt.Errorf("another run holds the lock %s (%s); use --lock-wait to wait for it", path, strings.TrimSpace(string(holder)))
+		}
+		if !announced {
+			fmt.Printf("🔒 Waiting up to %v for another run to finish (%s)\n", wait, strings.TrimSpace(string(holder)))
+			announced = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	// Record who holds the lock for the benefit of the next run
+	if err := file.Truncate(0); err == nil {
+		fmt.Fprintf(file, "pid %d, started %s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+	}
+	heldLock = file
+	return nil
+}
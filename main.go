@@ -7,14 +7,20 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v57/github"
@@ -40,24 +46,128 @@ type Config struct {
 	IncludeForks   bool
 	DryRun         bool
 	CleanupOrphans bool
+	Strict         bool
 	Recreate       bool
+	OnExists       string
+	OnDuplicate    string
+	Unarchive      bool
 	Concurrent     int
-	Verbose        bool
-	OnlyRepos      []string
-	ExcludeRepos   []string
+	// Concurrency limits for migrations and sync triggers, per target
+	ConcurrentMigrate  int
+	ConcurrentSync     int
+	Verbose            bool
+	OnlyRepos          []string
+	ExcludeRepos       []string
+	CloneProtocol      string
+	SSHDeployKey       string
+	SSHPublicKey       string
+	SampleFiles        int
+	HealthFactor       int
+	VerifyLFS          bool
+	VerifyMilestones   bool
+	VerifyRefs         string
+	ReportFile         string
+	RetryFailed        string
+	Resume             bool
+	RollbackOnFailure  bool
+	FixRemotes         bool
+	ConvertWithHistory bool
+	ImportPulls        bool
+	SyncIssues         bool
+	ReflectIssues      bool
+	ImportDiscussions  bool
+	CopyReactions      bool
+	CopyAttachments    bool
+	PinIssues          bool
+	RewriteReferences  bool
+	SyncReleases       bool
+	SyncLabels         bool
+	PruneLabels        bool
+	RetryAttempts      int
+	RetryDelay         time.Duration
+	RepoTimeout        time.Duration
+	MigrationWait      time.Duration
+
+	GitHubAppID             int64
+	GitHubAppKey            *rsa.PrivateKey
+	GitHubAppInstallationID int64
+
+	StateFile          string
+	LockFile           string
+	LockWait           time.Duration
+	PlanFile           string
+	MaxWait            time.Duration
+	DetectForcePush    bool
+	SkipUnchanged      bool
+	SinceLastRun       bool
+	EmptyRepos         string
+	FreezeTime         time.Time
+	WikiFallback       bool
+	LFS                bool
+	MirrorNotes        bool
+	SyncWikis          bool
+	CopyAvatars        bool
+	CopyWebhooks       bool
+	CopyDeployKeys     bool
+	CopyTagProtection  bool
+	ScaffoldActions    bool
+	CopyRulesets       bool
+	StatusBranches     []string
+	ExportDeployments  bool
+	CopyPackages       bool
+	CheckActions       bool
+	ActionsIssue       bool
+	WebhookURLTemplate string
+	WebhookSecret      string
+	SelftestRepo       string
+	GitHubSearch       string
+	AssumeYes          bool
+	CleanupPolicy      string
+	Mode               string
+	MirrorIntervals    map[string]string
+	ExtraTargets       []Target
+	MapOrgs            bool
+	OwnerMap           []mappingRule
+	UserMap            []mappingRule
+	CopyCollaborators  bool
+	AccessMap          []accessGrant
+	ActionsValues      []actionsValue
+	DescriptionSuffix  string
+	CollisionName      string
+	SanitizeName       string
+	TransferToOrg      bool
+	Star               bool
+	Watch              bool
+	StarWatchUsers     []string
+	ForgejoAdmin       bool
+	TargetType         string
 }
 
 // GitHubRepo represents a GitHub repository
 type GitHubRepo struct {
-	Name        string `json:"name"`
-	FullName    string `json:"full_name"`
-	Description string `json:"description"`
-	CloneURL    string `json:"clone_url"`
-	Private     bool   `json:"private"`
-	Fork        bool   `json:"fork"`
-	Language    string `json:"language"`
-	Stars       int    `json:"stargazers_count"`
-	UpdatedAt   string `json:"updated_at"`
+	ID            int64    `json:"id"`
+	Name          string   `json:"name"`
+	FullName      string   `json:"full_name"`
+	Owner         string   `json:"owner"`
+	Description   string   `json:"description"`
+	CloneURL      string   `json:"clone_url"`
+	SSHURL        string   `json:"ssh_url"`
+	DefaultBranch string   `json:"default_branch"`
+	Private       bool     `json:"private"`
+	Fork          bool     `json:"fork"`
+	Language      string   `json:"language"`
+	Stars         int      `json:"stargazers_count"`
+	UpdatedAt     string   `json:"updated_at"`
+	Size          int      `json:"size"`
+	HasWiki       bool     `json:"has_wiki"`
+	HasIssues     bool     `json:"has_issues"`
+	HasProjects   bool     `json:"has_projects"`
+	IsTemplate    bool     `json:"is_template"`
+	Archived      bool     `json:"archived"`
+	PushedAt      string   `json:"pushed_at"`
+	Topics        []string `json:"topics"`
+
+	installation *installation
 }
 
 // ForgejoMigrationRequest represents a Forgejo migration API request
@@ -79,59 +189,147 @@ type ForgejoMigrationRequest struct {
 	Wiki           bool   `json:"wiki"`
 	Milestones     bool   `json:"milestones"`
 	Labels         bool   `json:"labels"`
+	LFS            bool   `json:"lfs"`
+	LFSEndpoint    string `json:"lfs_endpoint,omitempty"`
 }
 
 // ForgejoRepo represents a Forgejo repository
 type ForgejoRepo struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	FullName string `json:"full_name"`
-	Mirror   bool   `json:"mirror"`
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	FullName       string    `json:"full_name"`
+	Mirror         bool      `json:"mirror"`
+	Private        bool      `json:"private"`
+	Empty          bool      `json:"empty"`
+	Archived       bool      `json:"archived"`
+	DefaultBranch  string    `json:"default_branch"`
+	OriginalURL    string    `json:"original_url"`
+	MirrorInterval string    `json:"mirror_interval"`
+	MirrorUpdated  time.Time `json:"mirror_updated"`
+	Created        time.Time `json:"created_at"`
 }
 
 // Client wraps HTTP client with custom methods
 type Client struct {
 	httpClient *http.Client
+	github     *github.Client
 	config     *Config
+	state      *State
+	clock      Clock
+	name       string
+	knownOrgs  *orgCache
+	merges     *mergeCache
+	quota      *quotaTracker
+	migrations slots
+	syncs      slots
+	renames    map[string]renamedMirror
+	existing   map[string]*ForgejoRepo
+	plan       *Plan
+
+	// The last GitHub listing and doubts about its completeness
+	listing       *githubListing
+	listingIssues []string
 }
 
 // NewClient creates a new HTTP client with custom configuration
 func NewClient(config *Config) *Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.GitHubToken})
+	gh := newGitHubClient(config, ts)
+
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		config: config,
+		github:     gh,
+		config:     config,
+		clock:      newClock(config),
+		name:       targetName(config.ForgejoURL),
+		knownOrgs:  newOrgCache(),
+		merges:     newMergeCache(),
+		quota:      newQuotaTracker(),
+		listing:    newGitHubListing(),
+		plan:       newPlan(),
+		migrations: newSlots(config.ConcurrentMigrate),
+		syncs:      newSlots(config.ConcurrentSync),
 	}
 }
 
 // GetGitHubRepos fetches all repositories for a user
 func (c *Client) GetGitHubRepos(ctx context.Context) ([]*GitHubRepo, error) {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.config.GitHubToken})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-	client.UserAgent = userAgent
+	client := c.github
+	c.listingIssues = nil
 
 	var allRepos []*github.Repository
+	installations := make(map[int64]*installation)
 
-	if c.config.GitHubOrg != "" {
-		opts := &github.RepositoryListByOrgOptions{
-			Type:        "all",
+	if c.config.GitHubSearch != "" {
+		opts := &github.SearchOptions{
 			Sort:        "updated",
-			Direction:   "desc",
+			Order:       "desc",
 			ListOptions: github.ListOptions{PerPage: 100},
 		}
 		for {
-			repos, resp, err := client.Repositories.ListByOrg(ctx, c.config.GitHubOrg, opts)
+			result, resp, err := client.Search.Repositories(ctx, c.config.GitHubSearch, opts)
 			if err != nil {
-				return nil, fmt.Errorf("failed to fetch GitHub org repos: %w", err)
+				return nil, githubError("searching GitHub repositories", err)
+			}
+			allRepos = append(allRepos, result.Repositories...)
+			if result.GetIncompleteResults() {
+				c.doubtListing("GitHub timed out and returned incomplete search results")
 			}
-			allRepos = append(allRepos, repos...)
 			if resp.NextPage == 0 {
+				// GitHub returns at most 1000 search results
+				if result.GetTotal() > len(allRepos) {
+					log.Printf("Warning: Search matched %d repos but only %d can be listed; narrow the query", result.GetTotal(), len(allRepos))
+					c.doubtListing("search matched %d repositories but only %d could be listed", result.GetTotal(), len(allRepos))
+				}
 				break
 			}
 			opts.Page = resp.NextPage
 		}
+	} else if c.config.GitHubAppID != 0 {
+		insts, err := c.getInstallations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, inst := range insts {
+			repos, total, err := inst.getRepos(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if len(repos) < total {
+				c.doubtListing("listed %d repositories of installation %s, but GitHub counts %d", len(repos), inst.account, total)
+			}
+			for _, repo := range repos {
+				installations[repo.GetID()] = inst
+			}
+			allRepos = append(allRepos, repos...)
+		}
+	} else if c.config.GitHubOrg != "" {
+		for _, org := range parseStringSlice(c.config.GitHubOrg) {
+			listed := len(allRepos)
+			opts := &github.RepositoryListByOrgOptions{
+				Type:        "all",
+				Sort:        "updated",
+				Direction:   "desc",
+				ListOptions: github.ListOptions{PerPage: 100},
+			}
+			for {
+				repos, resp, err := client.Repositories.ListByOrg(ctx, org, opts)
+				if err != nil {
+					return nil, githubError("listing repositories of GitHub organization "+org, err)
+				}
+				allRepos = append(allRepos, repos...)
+				if resp.NextPage == 0 {
+					break
+				}
+				opts.Page = resp.NextPage
+			}
+			// Only cleanup depends on the listing being complete
+			if c.config.CleanupOrphans {
+				c.checkOrgListing(ctx, org, len(allRepos)-listed)
+			}
+		}
 	} else {
 		opts := &github.RepositoryListOptions{
 			Type:        "owner",
@@ -142,7 +340,7 @@ func (c *Client) GetGitHubRepos(ctx context.Context) ([]*GitHubRepo, error) {
 		for {
 			repos, resp, err := client.Repositories.List(ctx, "", opts)
 			if err != nil {
-				return nil, fmt.Errorf("failed to fetch GitHub repos: %w", err)
+				return nil, githubError("listing GitHub repositories", err)
 			}
 			allRepos = append(allRepos, repos...)
 			if resp.NextPage == 0 {
@@ -150,7 +348,33 @@ func (c *Client) GetGitHubRepos(ctx context.Context) ([]*GitHubRepo, error) {
 			}
 			opts.Page = resp.NextPage
 		}
+		if c.config.CleanupOrphans {
+			c.checkUserListing(ctx, len(allRepos))
+		}
+	}
+	if c.config.CleanupOrphans {
+		c.checkListingShrink(len(allRepos))
 	}
+	c.state.SetListed(len(allRepos))
+
+	listed := make(map[string]bool, len(allRepos))
+	for _, repo := range allRepos {
+		listed[strings.ToLower(repo.GetFullName())] = repo.GetPrivate()
+	}
+	var owners []string
+	switch {
+	case c.config.GitHubSearch != "":
+		owners = searchOwners(c.config.GitHubSearch)
+	case c.config.GitHubAppID != 0:
+		for _, inst := range installations {
+			owners = append(owners, inst.account)
+		}
+	case c.config.GitHubOrg != "":
+		owners = parseStringSlice(c.config.GitHubOrg)
+	default:
+		owners = []string{c.config.GitHubUser}
+	}
+	c.listing.record(listed, owners)
 
 	var result []*GitHubRepo
 	for _, repo := range allRepos {
@@ -165,81 +389,106 @@ func (c *Client) GetGitHubRepos(ctx context.Context) ([]*GitHubRepo, error) {
 			continue
 		}
 
-		result = append(result, &GitHubRepo{
-			Name:        repo.GetName(),
-			FullName:    repo.GetFullName(),
-			Description: repo.GetDescription(),
-			CloneURL:    repo.GetCloneURL(),
-			Private:     repo.GetPrivate(),
-			Fork:        repo.GetFork(),
-			Language:    repo.GetLanguage(),
-			Stars:       repo.GetStargazersCount(),
-			UpdatedAt:   repo.GetUpdatedAt().Format(time.RFC3339),
-		})
+		result = append(result, newGitHubRepo(repo, installations[repo.GetID()]))
 	}
 
+	c.sanitizeNames(result)
+	c.resolveNameCollisions(result)
 	return result, nil
 }
 
-// GetForgejoRepos fetches all repositories from Forgejo
-func (c *Client) GetForgejoRepos(ctx context.Context) ([]*ForgejoRepo, error) {
-	url := fmt.Sprintf("%s/api/v1/user/repos?limit=100", c.config.ForgejoURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// newGitHubRepo converts a go-github repository into a GitHubRepo
+func newGitHubRepo(repo *github.Repository, inst *installation) *GitHubRepo {
+	return &GitHubRepo{
+		ID:            repo.GetID(),
+		Name:          repo.GetName(),
+		FullName:      repo.GetFullName(),
+		Owner:         repo.GetOwner().GetLogin(),
+		Description:   repo.GetDescription(),
+		CloneURL:      repo.GetCloneURL(),
+		SSHURL:        repo.GetSSHURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Private:       repo.GetPrivate(),
+		Fork:          repo.GetFork(),
+		Language:      repo.GetLanguage(),
+		Stars:         repo.GetStargazersCount(),
+		UpdatedAt:     repo.GetUpdatedAt().Format(time.RFC3339),
+		Size:          repo.GetSize(),
+		HasWiki:       repo.GetHasWiki(),
+		HasIssues:     repo.GetHasIssues(),
+		HasProjects:   repo.GetHasProjects(),
+		IsTemplate:    repo.GetIsTemplate(),
+		Archived:      repo.GetArchived(),
+		PushedAt:      repo.GetPushedAt().Format(time.RFC3339),
+		Topics:        repo.Topics,
+
+		installation: inst,
 	}
+}
 
-	req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
-	req.Header.Set("User-Agent", userAgent)
+// GetForgejoRepos fetches all repositories from Forgejo
+func (c *Client) GetForgejoRepos(ctx context.Context) ([]*ForgejoRepo, error) {
+	var allRepos []*ForgejoRepo
+	// Forgejo caps the page size at its MAX_RESPONSE_ITEMS setting, so keep
+	// going until an empty page rather than until a short one
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/api/v1/user/repos?limit=100&page=%d", c.config.ForgejoURL, page)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch Forgejo repos: %w", err)
-	}
-	defer resp.Body.Close()
+		req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
+		req.Header.Set("User-Agent", userAgent)
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch Forgejo repos: %w", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
 
-	if c.config.Verbose && len(bodyBytes) > 0 {
-		fmt.Printf("📋 GetForgejoRepos response (status %d):\n", resp.StatusCode)
-		// Try to pretty print JSON if possible
-		var prettyJSON bytes.Buffer
-		if err := json.Indent(&prettyJSON, bodyBytes, "   ", "  "); err == nil {
-			fmt.Printf("   %s\n", prettyJSON.String())
-		} else {
-			fmt.Printf("   %s\n", string(bodyBytes))
+		if c.config.Verbose && len(bodyBytes) > 0 {
+			fmt.Printf("📋 GetForgejoRepos response (page %d, status %d):\n", page, resp.StatusCode)
+			// Try to pretty print JSON if possible
+			var prettyJSON bytes.Buffer
+			if err := json.Indent(&prettyJSON, bodyBytes, "   ", "  "); err == nil {
+				fmt.Printf("   %s\n", prettyJSON.String())
+			} else {
+				fmt.Printf("   %s\n", string(bodyBytes))
+			}
 		}
-	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Forgejo API returned status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
+		if resp.StatusCode != http.StatusOK {
+			return nil, forgejoError("listing repositories", resp.StatusCode, bodyBytes)
+		}
 
-	var repos []*ForgejoRepo
-	if err := json.Unmarshal(bodyBytes, &repos); err != nil {
-		return nil, fmt.Errorf("failed to decode Forgejo repos: %w", err)
+		var repos []*ForgejoRepo
+		if err := json.Unmarshal(bodyBytes, &repos); err != nil {
+			return nil, fmt.Errorf("failed to decode Forgejo repos: %w", err)
+		}
+		if len(repos) == 0 {
+			break
+		}
+		allRepos = append(allRepos, repos...)
 	}
 
-	return repos, nil
+	return allRepos, nil
 }
 
-// MigrateRepo creates a mirrored repository in Forgejo
-func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) error {
+// MigrateRepo creates a mirrored repository in Forgejo and reports whether it
+// was created, already existed or was synced
+func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) (Result, error) {
 	if c.config.DryRun {
-		if c.config.Recreate {
-			fmt.Printf("[DRY RUN] Would delete and recreate: %s\n", repo.Name)
-		} else {
-			fmt.Printf("[DRY RUN] Would migrate: %s\n", repo.Name)
-		}
-		return nil
+		return c.planRepo(ctx, repo)
 	}
 
 	// If recreate flag is set, delete the repository first
 	if c.config.Recreate {
-		if err := c.DeleteRepo(ctx, repo.Name); err != nil {
+		if err := c.DeleteRepo(ctx, c.ownerFor(repo), repo.Name); err != nil {
 			// Log the error but continue with migration
 			if c.config.Verbose {
 				fmt.Printf("⚠️  Failed to delete %s: %v (continuing with migration)\n", repo.Name, err)
@@ -249,34 +498,98 @@ func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) error {
 		time.Sleep(500 * time.Millisecond)
 	}
 
+	// Repositories in the Forgejo listing only need refreshing, which doesn't
+	// take up a migration slot
+	if _, ok := c.existing[strings.ToLower(c.ownerFor(repo)+"/"+repo.Name)]; ok && !c.config.Recreate && c.duplicate(repo) == nil {
+		fmt.Printf("⚠️  Repository already exists: %s\n", repo.Name)
+		return c.refreshExisting(ctx, repo)
+	}
+	return c.submitMigration(ctx, repo)
+}
+
+// submitMigration asks Forgejo to migrate a repository that doesn't exist
+// there yet and waits for it to complete
+func (c *Client) submitMigration(ctx context.Context, repo *GitHubRepo) (Result, error) {
+	authUsername, authToken, err := c.migrationCredentials(repo)
+	if err != nil {
+		return ResultFailed, fmt.Errorf("failed to get GitHub credentials for %s: %w", repo.Name, err)
+	}
+
+	if c.config.CloneProtocol == "ssh" && c.config.SSHPublicKey != "" {
+		if err := c.EnsureDeployKey(ctx, repo); err != nil {
+			return ResultFailed, err
+		}
+	}
+
 	migration := &ForgejoMigrationRequest{
 		CloneAddr:      repo.CloneURL,
 		RepoName:       repo.Name,
-		RepoOwner:      c.config.ForgejoUser,
-		Description:    repo.Description,
+		RepoOwner:      c.ownerFor(repo),
+		Description:    c.descriptionFor(repo),
 		Private:        repo.Private,
-		Mirror:         true,
+		Mirror:         c.config.Mode == "mirror",
 		Service:        "github",
-		MirrorInterval: c.config.MirrorInterval,
-		AuthToken:      c.config.GitHubToken,
-		AuthPassword:   c.config.GitHubToken,
-		AuthUsername:   c.config.GitHubUser,
-		Issues:         true,
+		MirrorInterval: c.mirrorIntervalFor(repo),
+		AuthToken:      authToken,
+		AuthPassword:   authToken,
+		AuthUsername:   authUsername,
+		Issues:         repo.HasIssues,
 		PullRequests:   true,
 		Releases:       true,
-		Wiki:           true,
+		Wiki:           repo.HasWiki,
 		Milestones:     true,
 		Labels:         true,
+		LFS:            c.config.LFS,
 	}
 
-	// Override owner if organization is specified
-	if c.config.Organization != "" {
-		migration.RepoOwner = c.config.Organization
+	if err := c.ensureOwner(ctx, repo); err != nil {
+		return ResultFailed, err
+	}
+
+	// Some setups only let the token create repositories in its own namespace
+	// and accept transfers into organizations afterwards
+	transferTo := ""
+	if owner, isOrg := c.resolveOwner(repo); isOrg && c.config.TransferToOrg {
+		existing, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+		if err != nil {
+			return ResultFailed, err
+		}
+		if existing != nil && !c.config.Recreate {
+			fmt.Printf("⚠️  Repository already exists: %s\n", repo.Name)
+			return c.refreshExisting(ctx, repo)
+		}
+		transferTo = owner
+		migration.RepoOwner = c.config.ForgejoUser
+	}
+
+	// A one-time migration has no sync schedule
+	if !migration.Mirror {
+		migration.MirrorInterval = ""
+	}
+
+	// SSH clones authenticate with the deploy key held by Forgejo, so no
+	// credentials are embedded in the request
+	if c.config.CloneProtocol == "ssh" {
+		migration.CloneAddr = repo.SSHURL
+		migration.Service = "git"
+		migration.AuthToken = ""
+		migration.AuthPassword = ""
+		migration.AuthUsername = ""
+
+		// LFS objects are only served over HTTPS, so point Forgejo at the
+		// HTTPS endpoint with the GitHub credentials
+		if c.config.LFS {
+			endpoint, err := authURL(lfsEndpoint(repo), authUsername, authToken)
+			if err != nil {
+				return ResultFailed, fmt.Errorf("failed to build LFS endpoint for %s: %w", repo.Name, err)
+			}
+			migration.LFSEndpoint = endpoint
+		}
 	}
 
 	body, err := json.Marshal(migration)
 	if err != nil {
-		return fmt.Errorf("failed to marshal migration request: %w", err)
+		return ResultFailed, fmt.Errorf("failed to marshal migration request: %w", err)
 	}
 
 	if c.config.Verbose {
@@ -290,10 +603,17 @@ func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) error {
 		}
 	}
 
+	// Held while Forgejo clones in the background, which is the load
+	// --concurrent-migrate limits, but for no longer than --migration-wait
+	if err := c.migrations.acquire(ctx); err != nil {
+		return ResultFailed, err
+	}
+	defer c.migrations.release()
+
 	url := fmt.Sprintf("%s/api/v1/repos/migrate", c.config.ForgejoURL)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return ResultFailed, err
 	}
 
 	req.Header.Set("Authorization", "token "+c.config.ForgejoToken)
@@ -302,7 +622,7 @@ func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to migrate repository: %w", err)
+		return ResultFailed, &transientError{err: fmt.Errorf("failed to migrate repository: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -324,40 +644,190 @@ func (c *Client) MigrateRepo(ctx context.Context, repo *GitHubRepo) error {
 	}
 
 	if resp.StatusCode == http.StatusCreated {
+		if err := c.WaitForMigration(ctx, migration.RepoOwner, repo); errors.Is(err, errStillMigrating) {
+			return ResultSkipped, err
+		} else if err != nil {
+			return ResultFailed, fmt.Errorf("migration was accepted but did not complete: %w", err)
+		}
 		if c.config.Recreate {
 			fmt.Printf("✅ Successfully recreated: %s\n", repo.Name)
 		} else {
 			fmt.Printf("✅ Successfully migrated: %s\n", repo.Name)
 		}
-		return nil
+		if transferTo != "" {
+			if err := c.TransferRepo(ctx, migration.RepoOwner, repo.Name, transferTo); err != nil {
+				return ResultFailed, err
+			}
+			migration.RepoOwner = transferTo
+		}
+		if err := c.StarAndWatch(ctx, migration.RepoOwner, repo.Name); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+		// Forgejo doesn't always honour the interval from the migration request
+		// and sometimes picks a different default branch than GitHub
+		if err := c.EditRepo(ctx, migration.RepoOwner, repo.Name, c.desiredSettings(ctx, repo, true)); err != nil {
+			return ResultFailed, fmt.Errorf("failed to apply repository settings: %w", err)
+		}
+		if repo.Archived {
+			if err := c.ArchiveRepo(ctx, migration.RepoOwner, repo.Name); err != nil {
+				return ResultFailed, err
+			}
+		}
+		return ResultCreated, nil
 	} else if resp.StatusCode == http.StatusConflict {
 		if !c.config.Recreate {
 			fmt.Printf("⚠️  Repository already exists: %s\n", repo.Name)
-			return nil
+			return c.refreshExisting(ctx, repo)
 		}
 		// If recreate was enabled but we still get conflict, it's an error
-		return fmt.Errorf("repository still exists after deletion: %s", repo.Name)
+		return ResultFailed, fmt.Errorf("repository still exists after deletion: %s", repo.Name)
+	}
+
+	return ResultFailed, forgejoError("migration of "+repo.Name, resp.StatusCode, bodyBytes)
+}
+
+// refreshExisting applies the --on-exists policy to an existing repository. By
+// default it converges the repository with GitHub so re-runs pick up changes,
+// and triggers a sync if it is a mirror so scheduled runs also refresh mirrors
+// between their regular sync intervals.
+func (c *Client) refreshExisting(ctx context.Context, repo *GitHubRepo) (Result, error) {
+	if c.config.OnExists == "skip" {
+		if c.config.Verbose {
+			fmt.Printf("⏭️  Leaving existing repository untouched: %s\n", repo.Name)
+		}
+		return ResultAlreadyExists, nil
+	}
+
+	owner := c.ownerFor(repo)
+	existing, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil {
+		return ResultFailed, err
+	}
+	if existing == nil {
+		// Deleted since the Forgejo listing, e.g. by a remote fix whose
+		// migration is being retried
+		return c.submitMigration(ctx, repo)
+	}
+
+	if existing != nil && existing.Archived {
+		if repo.Archived {
+			// Frozen on both sides, nothing to refresh
+			return ResultAlreadyExists, nil
+		}
+		if !c.config.Unarchive {
+			fmt.Printf("📦 %s is archived on Forgejo but not on GitHub, leaving it as is (use --unarchive to reactivate it)\n", repo.Name)
+			return ResultAlreadyExists, nil
+		}
+		unarchived := false
+		if err := c.EditRepo(ctx, owner, repo.Name, &EditRepoOption{Archived: &unarchived}); err != nil {
+			return ResultFailed, fmt.Errorf("failed to unarchive: %w", err)
+		}
+		fmt.Printf("📂 Unarchived %s: it is active again on GitHub\n", repo.Name)
+	}
+
+	if reason := c.remoteMismatch(repo, existing.OriginalURL); reason != "" && existing.Mirror {
+		if c.config.FixRemotes {
+			return c.recreateMirror(ctx, repo, owner, reason)
+		}
+		fmt.Printf("⚠️  The mirror %s/%s %s (use --fix-remotes to recreate it)\n", owner, repo.Name, reason)
+	}
+
+	if err := c.UpdateRepoSettings(ctx, repo); err != nil {
+		return ResultFailed, fmt.Errorf("failed to update settings of existing repo: %w", err)
+	}
+	if existing != nil && existing.Private != repo.Private && !c.config.DryRun {
+		reportVisibility(repo.Name, repo.Private)
+	}
+	result := ResultAlreadyExists
+	if existing != nil && existing.Mirror && c.config.OnExists == "sync" && !c.pushedSinceSync(repo) {
+		if c.config.Verbose {
+			fmt.Printf("⏭️  No pushes since the last sync, not syncing: %s\n", repo.Name)
+		}
+	} else if existing != nil && existing.Mirror && c.config.OnExists == "sync" {
+		// Transient sync failures are retried together with the migration
+		if err := c.SyncMirror(ctx, owner, repo.Name); err != nil {
+			return ResultFailed, err
+		}
+		result = ResultSynced
+	}
+	// Archive after the final sync so the mirror has the last state of the project
+	if existing != nil && repo.Archived {
+		if err := c.ArchiveRepo(ctx, owner, repo.Name); err != nil {
+			return ResultFailed, err
+		}
+	}
+	return result, nil
+}
+
+// IsEmptyGitHubRepo reports whether a GitHub repository has no commits. Forgejo
+// migrations of empty repositories fail without a useful error.
+func (c *Client) IsEmptyGitHubRepo(ctx context.Context, repo *GitHubRepo) (bool, error) {
+	if repo.Size > 0 {
+		return false, nil
+	}
+
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	_, resp, err := c.githubFor(repo).Repositories.ListCommits(ctx, owner, name, &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		return true, nil
+	}
+	if err != nil {
+		return false, githubError("checking commits of "+repo.FullName, err)
+	}
+	return false, nil
+}
+
+// EnsureDeployKey registers the configured SSH public key as a read-only
+// deploy key on the GitHub repository, so Forgejo can clone it over SSH
+func (c *Client) EnsureDeployKey(ctx context.Context, repo *GitHubRepo) error {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		keys, resp, err := c.githubFor(repo).Repositories.ListKeys(ctx, owner, name, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list deploy keys for %s: %w", repo.FullName, err)
+		}
+		for _, key := range keys {
+			if sameSSHKey(key.GetKey(), c.config.SSHPublicKey) {
+				return nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
-	// Include response body in error for non-verbose mode if there's an error
-	if !c.config.Verbose && len(bodyBytes) > 0 {
-		return fmt.Errorf("migration failed with status %d for repo %s: %s", resp.StatusCode, repo.Name, string(bodyBytes))
+	key := &github.Key{
+		Title:    github.String("forgejo-mirror"),
+		Key:      github.String(c.config.SSHPublicKey),
+		ReadOnly: github.Bool(true),
+	}
+	if _, _, err := c.githubFor(repo).Repositories.CreateKey(ctx, owner, name, key); err != nil {
+		return fmt.Errorf("failed to add deploy key to %s: %w", repo.FullName, err)
+	}
+	if c.config.Verbose {
+		fmt.Printf("🔑 Added deploy key to: %s\n", repo.FullName)
 	}
-	return fmt.Errorf("migration failed with status %d for repo %s", resp.StatusCode, repo.Name)
+	return nil
+}
+
+// sameSSHKey compares two public keys by type and key material, ignoring comments
+func sameSSHKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	return len(fa) >= 2 && len(fb) >= 2 && fa[0] == fb[0] && fa[1] == fb[1]
 }
 
 // DeleteRepo deletes a repository from Forgejo
-func (c *Client) DeleteRepo(ctx context.Context, repoName string) error {
+func (c *Client) DeleteRepo(ctx context.Context, owner, repoName string) error {
 	if c.config.DryRun {
 		fmt.Printf("[DRY RUN] Would delete repository: %s\n", repoName)
 		return nil
 	}
 
-	owner := c.config.ForgejoUser
-	if c.config.Organization != "" {
-		owner = c.config.Organization
-	}
-
 	url := fmt.Sprintf("%s/api/v1/repos/%s/%s", c.config.ForgejoURL, owner, repoName)
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
@@ -373,13 +843,11 @@ func (c *Client) DeleteRepo(ctx context.Context, repoName string) error {
 	}
 	defer resp.Body.Close()
 
-	// Read response body for verbose logging
-	if c.config.Verbose {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err == nil && len(bodyBytes) > 0 {
-			fmt.Printf("📋 Delete response from Forgejo (status %d):\n", resp.StatusCode)
-			fmt.Printf("   %s\n", string(bodyBytes))
-		}
+	// Read response body for verbose logging and error details
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if c.config.Verbose && len(bodyBytes) > 0 {
+		fmt.Printf("📋 Delete response from Forgejo (status %d):\n", resp.StatusCode)
+		fmt.Printf("   %s\n", string(bodyBytes))
 	}
 
 	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
@@ -390,20 +858,20 @@ func (c *Client) DeleteRepo(ctx context.Context, repoName string) error {
 		return nil
 	}
 
-	return fmt.Errorf("delete failed with status %d for repo %s", resp.StatusCode, repoName)
+	return forgejoError("deleting "+repoName, resp.StatusCode, bodyBytes)
 }
 
 // SyncMirror triggers a sync for an existing mirror
-func (c *Client) SyncMirror(ctx context.Context, repoName string) error {
+func (c *Client) SyncMirror(ctx context.Context, owner, repoName string) error {
 	if c.config.DryRun {
 		fmt.Printf("[DRY RUN] Would sync mirror: %s\n", repoName)
 		return nil
 	}
 
-	owner := c.config.ForgejoUser
-	if c.config.Organization != "" {
-		owner = c.config.Organization
+	if err := c.syncs.acquire(ctx); err != nil {
+		return err
 	}
+	defer c.syncs.release()
 
 	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/mirror-sync", c.config.ForgejoURL, owner, repoName)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
@@ -416,17 +884,15 @@ func (c *Client) SyncMirror(ctx context.Context, repoName string) error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to sync mirror: %w", err)
+		return &transientError{err: fmt.Errorf("failed to sync mirror: %w", err)}
 	}
 	defer resp.Body.Close()
 
-	// Read response body for verbose logging
-	if c.config.Verbose {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err == nil && len(bodyBytes) > 0 {
-			fmt.Printf("📋 Sync response from Forgejo (status %d):\n", resp.StatusCode)
-			fmt.Printf("   %s\n", string(bodyBytes))
-		}
+	// Read response body for verbose logging and error details
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if c.config.Verbose && len(bodyBytes) > 0 {
+		fmt.Printf("📋 Sync response from Forgejo (status %d):\n", resp.StatusCode)
+		fmt.Printf("   %s\n", string(bodyBytes))
 	}
 
 	if resp.StatusCode == http.StatusOK {
@@ -434,7 +900,7 @@ func (c *Client) SyncMirror(ctx context.Context, repoName string) error {
 		return nil
 	}
 
-	return fmt.Errorf("sync failed with status %d for repo %s", resp.StatusCode, repoName)
+	return forgejoError("sync of "+repoName, resp.StatusCode, bodyBytes)
 }
 
 // shouldSkipRepo checks if a repository should be skipped based on filters
@@ -459,6 +925,28 @@ func (c *Client) shouldSkipRepo(repoName string) bool {
 	return false
 }
 
+// mirrorIntervalFor returns the sync interval for a repository, preferring a
+// per-repo override over the global setting
+func (c *Client) mirrorIntervalFor(repo *GitHubRepo) string {
+	if interval, ok := c.config.MirrorIntervals[repo.githubName()]; ok {
+		return interval
+	}
+	return c.config.MirrorInterval
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs into a map
+func parseKeyValues(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, part := range parseStringSlice(s) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", part)
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
+
 // parseStringSlice parses a comma-separated string into a slice
 func parseStringSlice(s string) []string {
 	if s == "" {
@@ -474,26 +962,142 @@ func parseStringSlice(s string) []string {
 	return result
 }
 
+// envOrDefault returns the environment variable value or the fallback if unset
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 // loadConfig loads configuration from environment variables and flags
-func loadConfig() *Config {
+func loadConfig(args []string) *Config {
 	config := &Config{}
 
 	// Command line flags
 	flag.StringVar(&config.GitHubToken, "github-token", os.Getenv("GITHUB_TOKEN"), "GitHub personal access token")
 	flag.StringVar(&config.GitHubUser, "github-user", os.Getenv("GITHUB_USER"), "GitHub username")
-	flag.StringVar(&config.GitHubOrg, "github-org", os.Getenv("GITHUB_ORG"), "GitHub organization (optional, lists org repos instead of user repos)")
+	flag.StringVar(&config.GitHubOrg, "github-org", os.Getenv("GITHUB_ORG"), "GitHub organization(s), comma-separated (optional, lists org repos instead of user repos)")
+	flag.BoolVar(&config.MapOrgs, "map-orgs", os.Getenv("MAP_ORGS") == "true", "Place each mirror in a Forgejo org named after its GitHub owner, creating orgs as needed")
+	flag.StringVar(&config.GitHubSearch, "github-search", os.Getenv("GITHUB_SEARCH"), "GitHub repository search query selecting the repos to mirror (e.g. 'org:acme topic:platform archived:false')")
 	flag.StringVar(&config.ForgejoURL, "forgejo-url", os.Getenv("FORGEJO_URL"), "Forgejo instance URL")
 	flag.StringVar(&config.ForgejoToken, "forgejo-token", os.Getenv("FORGEJO_TOKEN"), "Forgejo access token")
 	flag.StringVar(&config.ForgejoUser, "forgejo-user", os.Getenv("FORGEJO_USER"), "Forgejo username")
+	flag.BoolVar(&config.ForgejoAdmin, "forgejo-admin", os.Getenv("FORGEJO_ADMIN") == "true", "Use an admin token to mirror into any user account (--forgejo-user or owner map), not just the token owner")
+	flag.StringVar(&config.TargetType, "target-type", envOrDefault("TARGET_TYPE", "forgejo"), "Software running on the target: 'forgejo' or 'gitea'")
+	flag.BoolVar(&config.TransferToOrg, "transfer-to-org", os.Getenv("TRANSFER_TO_ORG") == "true", "Create repos under --forgejo-user and transfer them to their organization, for tokens that can't create repos in the org")
 	flag.StringVar(&config.Organization, "organization", os.Getenv("FORGEJO_ORG"), "Forgejo organization (optional)")
 	flag.StringVar(&config.MirrorInterval, "mirror-interval", os.Getenv("MIRROR_INTERVAL"), "Mirror sync interval (e.g., '10m', '1h', '24h'). Empty for default.")
+	flag.StringVar(&config.Mode, "mode", envOrDefault("MIGRATION_MODE", "mirror"), "'mirror' for pull mirrors, 'migrate' for regular repos with issues, PRs and releases converted")
 	flag.BoolVar(&config.IncludePrivate, "include-private", os.Getenv("INCLUDE_PRIVATE") == "true", "Include private repositories")
 	flag.BoolVar(&config.IncludeForks, "include-forks", os.Getenv("INCLUDE_FORKS") == "true", "Include forked repositories")
 	flag.BoolVar(&config.DryRun, "dry-run", false, "Show what would be done without making changes")
 	flag.BoolVar(&config.CleanupOrphans, "cleanup", false, "Remove mirrors that no longer exist on GitHub")
-	flag.BoolVar(&config.Recreate, "recreate", os.Getenv("RECREATE_REPOS") == "true", "Delete and recreate existing repositories")
+	flag.BoolVar(&config.Strict, "strict", os.Getenv("STRICT") == "true", "Skip cleanup unless the GitHub listing is verifiably complete")
+	flag.StringVar(&config.CleanupPolicy, "cleanup-policy", envOrDefault("CLEANUP_POLICY", "delete"), "What --cleanup does with orphaned mirrors: 'archive', 'delete' or 'report'")
+	flag.BoolVar(&config.AssumeYes, "yes", false, "Don't ask for confirmation before deleting repositories")
+	flag.BoolVar(&config.Recreate, "recreate", os.Getenv("RECREATE_REPOS") == "true", "Delete and recreate existing repositories (same as --on-exists=recreate)")
+	flag.StringVar(&config.OnDuplicate, "on-duplicate", envOrDefault("ON_DUPLICATE", "report"), "What to do with existing repositories that aren't mirrors of their GitHub repository: 'report', 'skip', 'rename' or 'replace' (asks for confirmation unless --yes)")
+	flag.StringVar(&config.OnExists, "on-exists", envOrDefault("ON_EXISTS", "sync"), "What to do with repositories that already exist on Forgejo: 'skip', 'sync', 'update-settings' or 'recreate'")
+	flag.BoolVar(&config.ConvertWithHistory, "convert-with-history", os.Getenv("CONVERT_WITH_HISTORY") == "true", "With the convert command, migrate the mirrors again as regular repositories with their issues, pull requests and reviews instead of converting them in place")
+	flag.BoolVar(&config.ImportPulls, "import-pulls", os.Getenv("IMPORT_PULLS") == "true", "With the convert command, import the issues and pull requests of converted mirrors, keeping GitHub's numbers (requires --state-file)")
+	flag.BoolVar(&config.FixRemotes, "fix-remotes", os.Getenv("FIX_REMOTES") == "true", "Recreate mirrors that pull from a different address than GitHub's current clone URL")
+	flag.BoolVar(&config.Unarchive, "unarchive", os.Getenv("UNARCHIVE") == "true", "Unarchive mirrors whose GitHub repository is no longer archived")
 	flag.IntVar(&config.Concurrent, "concurrent", 3, "Number of concurrent migrations")
+	flag.IntVar(&config.ConcurrentMigrate, "concurrent-migrate", 0, "Number of migrations running at once per target (default: --concurrent)")
+	flag.IntVar(&config.ConcurrentSync, "concurrent-sync", 0, "Number of mirror syncs triggered at once per target (default: --concurrent)")
+	flag.StringVar(&config.ReportFile, "report", os.Getenv("REPORT_FILE"), "Write the repositories that failed to this JSON file at the end of a run")
+	flag.StringVar(&config.RetryFailed, "retry-failed", "", "Only process the repositories listed as failed in this report from a previous run")
+	flag.BoolVar(&config.Resume, "resume", false, "Continue an interrupted run, skipping the repositories it already handled (requires --state-file)")
+	flag.BoolVar(&config.RollbackOnFailure, "rollback-on-failure", os.Getenv("ROLLBACK_ON_FAILURE") == "true", "Delete the repositories a run created if it is interrupted or any repository fails")
+	flag.IntVar(&config.RetryAttempts, "retry-attempts", 3, "Attempts per migration or sync when Forgejo fails with a server error or timeout")
+	flag.DurationVar(&config.RetryDelay, "retry-delay", 5*time.Second, "Delay before the first retry, doubled on every further attempt")
+	flag.DurationVar(&config.RepoTimeout, "repo-timeout", 0, "Give up on a repository that takes longer than this to mirror (0 for no limit)")
+	flag.DurationVar(&config.MigrationWait, "migration-wait", 2*time.Minute, "How long to wait for Forgejo to clone a migrated repository before counting it as still migrating")
+	flag.IntVar(&config.HealthFactor, "health-factor", 3, "Mark mirrors unhealthy in health when they haven't synced for this many times their sync interval")
 	flag.BoolVar(&config.Verbose, "verbose", false, "Enable verbose logging")
+	flag.StringVar(&config.CloneProtocol, "clone-protocol", envOrDefault("CLONE_PROTOCOL", "https"), "Protocol Forgejo uses to clone from GitHub: 'https' or 'ssh'")
+	flag.StringVar(&config.SSHDeployKey, "ssh-deploy-key", os.Getenv("SSH_DEPLOY_KEY"), "Path to the public key file of Forgejo's SSH key, registered as a read-only deploy key on each GitHub repo (ssh only)")
+
+	flag.IntVar(&config.SampleFiles, "sample-files", 0, "Number of random files per repo to compare by hash during verify (0 disables)")
+	flag.StringVar(&config.VerifyRefs, "verify-refs", "", "Compare branches and tags during verify: 'counts', 'names' or 'deep' to diff every ref and SHA with git ls-remote (empty disables)")
+	flag.BoolVar(&config.VerifyLFS, "verify-lfs", false, "Check during verify that every Git LFS object on the default branch is stored on Forgejo with the right size")
+	flag.BoolVar(&config.VerifyMilestones, "verify-milestones", false, "Check during verify that the milestones of repos that aren't mirrors have the same state, due date and progress as on GitHub")
+
+	flag.StringVar(&config.StateFile, "state-file", os.Getenv("STATE_FILE"), "Path to the state file recording mirror state between runs (optional)")
+	flag.StringVar(&config.LockFile, "lock-file", os.Getenv("LOCK_FILE"), "Lock file that keeps overlapping runs apart (default: the state file with .lock appended)")
+	flag.DurationVar(&config.LockWait, "lock-wait", 0, "How long to wait for another run to release the lock before giving up")
+	flag.StringVar(&config.PlanFile, "plan-file", "", "Plan file the plan command writes and apply executes (default plan.json; with --dry-run, also write the plan there)")
+	flag.DurationVar(&config.MaxWait, "max-wait", 0, "Longest pause for the GitHub rate limit to reset before failing instead (default 0, wait as long as needed)")
+	flag.BoolVar(&config.SkipUnchanged, "skip-unchanged", os.Getenv("SKIP_UNCHANGED") == "true", "Skip repositories whose GitHub metadata hasn't changed since the last successful run (requires --state-file)")
+	flag.BoolVar(&config.SinceLastRun, "since-last-run", os.Getenv("SINCE_LAST_RUN") == "true", "Only trigger a sync of existing mirrors pushed to on GitHub since the last run synced them (requires --state-file)")
+	flag.BoolVar(&config.DetectForcePush, "detect-force-push", os.Getenv("DETECT_FORCE_PUSH") == "true", "Report branches force-pushed on GitHub since the last run (requires --state-file)")
+
+	flag.StringVar(&config.EmptyRepos, "empty-repos", envOrDefault("EMPTY_REPOS", "skip"), "How to handle GitHub repos without commits: 'skip' or 'create' (an empty, non-mirror repo)")
+
+	flag.BoolVar(&config.SyncIssues, "sync-issues", os.Getenv("SYNC_ISSUES") == "true", "Copy new and edited GitHub issues and comments to repositories that aren't mirrors (requires --state-file)")
+	flag.BoolVar(&config.ReflectIssues, "reflect-issues", os.Getenv("REFLECT_ISSUES") == "true", "Reflect new GitHub issue activity onto the issue trackers of mirrors (requires --state-file)")
+	flag.BoolVar(&config.ImportDiscussions, "import-discussions", os.Getenv("IMPORT_DISCUSSIONS") == "true", "Import GitHub Discussions and their comments as issues labeled 'discussion' (requires --state-file)")
+	flag.BoolVar(&config.CopyReactions, "copy-reactions", os.Getenv("COPY_REACTIONS") == "true", "Copy reactions on the issues, discussions and comments --sync-issues and --import-discussions copy")
+	flag.BoolVar(&config.PinIssues, "pin-issues", os.Getenv("PIN_ISSUES") == "true", "Pin the Forgejo issues of the issues pinned on GitHub, for repos that aren't mirrors")
+	flag.BoolVar(&config.RewriteReferences, "rewrite-references", os.Getenv("REWRITE_REFERENCES") == "true", "Point links to GitHub issues, pull requests and discussions in migrated and synced issues at their Forgejo copies (requires --state-file)")
+	flag.BoolVar(&config.CopyAttachments, "copy-attachments", os.Getenv("COPY_ATTACHMENTS") == "true", "Upload files attached on GitHub to the issues, discussions and comments --sync-issues and --import-discussions copy, and link to the copies")
+	flag.BoolVar(&config.SyncLabels, "sync-labels", os.Getenv("SYNC_LABELS") == "true", "Keep the labels and milestones of repositories that aren't mirrors in line with GitHub")
+	flag.BoolVar(&config.PruneLabels, "prune-labels", os.Getenv("PRUNE_LABELS") == "true", "With --sync-labels, delete labels and milestones that no longer exist on GitHub")
+	flag.BoolVar(&config.SyncReleases, "sync-releases", os.Getenv("SYNC_RELEASES") == "true", "Copy new and edited GitHub releases and their assets to repositories that aren't mirrors")
+	flag.BoolVar(&config.SyncWikis, "sync-wikis", os.Getenv("SYNC_WIKIS") == "true", "Bring every wiki up to date on each run: pushed with git to repos that aren't mirrors (requires git), a mirror sync for existing mirrors")
+	flag.BoolVar(&config.LFS, "lfs", os.Getenv("MIRROR_LFS") == "true", "Mirror Git LFS objects (requires LFS to be enabled on Forgejo)")
+	flag.BoolVar(&config.MirrorNotes, "mirror-notes", os.Getenv("MIRROR_NOTES") == "true", "Push git notes (refs/notes/*) to repos that aren't mirrors and report other refs outside branches and tags that aren't copied (requires git)")
+	flag.BoolVar(&config.WikiFallback, "wiki-fallback", os.Getenv("WIKI_FALLBACK") == "true", "Push wikis with local git when Forgejo's wiki migration leaves them empty (requires git)")
+	flag.BoolVar(&config.CopyAvatars, "copy-avatars", os.Getenv("COPY_AVATARS") == "true", "Upload each repo's custom GitHub social preview image as the Forgejo repo avatar")
+	flag.BoolVar(&config.CopyWebhooks, "copy-webhooks", os.Getenv("COPY_WEBHOOKS") == "true", "Recreate the webhooks of each GitHub repo on Forgejo (needs admin access to the GitHub repos)")
+	flag.BoolVar(&config.CopyDeployKeys, "copy-deploy-keys", os.Getenv("COPY_DEPLOY_KEYS") == "true", "Add the read-only deploy keys of each GitHub repo to its Forgejo repo (needs admin access to the GitHub repos)")
+	flag.BoolVar(&config.CopyTagProtection, "copy-tag-protection", os.Getenv("COPY_TAG_PROTECTION") == "true", "Protect the tags on Forgejo that are protected on GitHub by tag protection rules or rulesets")
+	flag.BoolVar(&config.CopyRulesets, "copy-rulesets", os.Getenv("COPY_RULESETS") == "true", "Create Forgejo branch protection rules for the branch rulesets of each GitHub repo and its organization")
+	var statusBranches string
+	flag.BoolVar(&config.ExportDeployments, "export-deployments", os.Getenv("EXPORT_DEPLOYMENTS") == "true", "Commit GitHub environments, their protection rules and recent deployments to the github-metadata branch of repositories that aren't mirrors (requires git)")
+	flag.StringVar(&statusBranches, "copy-statuses", os.Getenv("COPY_STATUSES"), "Comma-separated branch patterns, e.g. 'main,release/*', whose tip commits get the commit statuses and check results of GitHub on Forgejo")
+	flag.BoolVar(&config.CopyPackages, "copy-packages", os.Getenv("COPY_PACKAGES") == "true", "Copy the tagged ghcr.io container images connected to each GitHub repo to the Forgejo container registry")
+	flag.BoolVar(&config.ScaffoldActions, "scaffold-actions", os.Getenv("SCAFFOLD_ACTIONS") == "true", "Create the GitHub Actions secrets and variables of each repo as Forgejo Actions secrets and variables")
+	flag.BoolVar(&config.CheckActions, "check-actions", os.Getenv("CHECK_ACTIONS") == "true", "Report GitHub Actions features in the workflows of each repo that Forgejo Actions doesn't support")
+	flag.BoolVar(&config.ActionsIssue, "actions-issue", os.Getenv("ACTIONS_ISSUE") == "true", "Open an issue on the Forgejo repo listing what --check-actions found")
+	flag.StringVar(&config.WebhookURLTemplate, "webhook-url-template", os.Getenv("WEBHOOK_URL_TEMPLATE"), "Rewrite copied webhook URLs, e.g. 'https://ci.internal{path}'; placeholders: {url}, {host}, {path}, {full_name}, {owner}, {name}")
+	flag.StringVar(&config.WebhookSecret, "webhook-secret", os.Getenv("WEBHOOK_SECRET"), "Secret of copied webhooks, since GitHub doesn't reveal the original ones")
+
+	flag.StringVar(&config.SelftestRepo, "selftest-repo", os.Getenv("SELFTEST_REPO"), "Existing GitHub repo (owner/name) for the selftest command instead of a throwaway repo")
+
+	var targetsFile string
+	flag.StringVar(&targetsFile, "targets", os.Getenv("FORGEJO_TARGETS"), "JSON file listing additional Forgejo instances to mirror every repo to")
+
+	flag.StringVar(&config.DescriptionSuffix, "description-suffix", os.Getenv("DESCRIPTION_SUFFIX"), "Template appended to each Forgejo repo description, e.g. '(mirror of {url})'; placeholders: {url}, {full_name}, {owner}, {name}")
+
+	flag.StringVar(&config.SanitizeName, "sanitize-name", envOrDefault("SANITIZE_NAME", "{name}"), "Forgejo name for repos whose GitHub name Forgejo rejects; {name} is the name with invalid characters replaced, {owner} the GitHub owner")
+	flag.StringVar(&config.CollisionName, "collision-name", envOrDefault("COLLISION_NAME", "{owner}-{name}"), "Forgejo name for repos whose name is already taken by a repo of another GitHub owner; placeholders: {owner}, {name}")
+
+	flag.BoolVar(&config.Star, "star", os.Getenv("STAR_MIRRORS") == "true", "Star each newly created mirror")
+	flag.BoolVar(&config.Watch, "watch", os.Getenv("WATCH_MIRRORS") == "true", "Watch each newly created mirror")
+	var starWatchUsers string
+	flag.StringVar(&starWatchUsers, "star-watch-users", os.Getenv("STAR_WATCH_USERS"), "Comma-separated Forgejo accounts that also star/watch new mirrors (requires --forgejo-admin)")
+
+	var accessMapFile, actionsValuesFile string
+	flag.StringVar(&actionsValuesFile, "actions-values", os.Getenv("ACTIONS_VALUES"), "File with values of Actions secrets and variables for --scaffold-actions, one 'acme/* -> NAME = value' rule per line")
+	flag.StringVar(&accessMapFile, "access-map", os.Getenv("ACCESS_MAP"), "File granting Forgejo users and teams access to mirrors, one 'acme/* -> user: alice = write' or 'acme/* -> team: platform' rule per line")
+
+	var ownerMapFile, userMapFile string
+	flag.BoolVar(&config.CopyCollaborators, "copy-collaborators", os.Getenv("COPY_COLLABORATORS") == "true", "Add the Forgejo accounts of GitHub collaborators in --user-map to repos that aren't mirrors, with equivalent permissions")
+	flag.StringVar(&userMapFile, "user-map", os.Getenv("USER_MAP"), "File mapping GitHub users to Forgejo accounts that synced issues, comments and reactions are posted as, one 'octocat -> alice' rule per line (requires --forgejo-admin)")
+	flag.StringVar(&ownerMapFile, "owner-map", os.Getenv("OWNER_MAP"), "File mapping GitHub repos to Forgejo owners, one 'acme/infra-* -> forgejo-org: platform' rule per line")
+
+	var mirrorIntervals string
+	flag.StringVar(&mirrorIntervals, "mirror-intervals", os.Getenv("MIRROR_INTERVALS"), "Per-repo mirror sync intervals overriding --mirror-interval (e.g. 'big-repo=24h,hot-repo=10m')")
+
+	var freezeTime string
+	flag.StringVar(&freezeTime, "freeze-time", os.Getenv("FREEZE_TIME"), "Use this fixed RFC 3339 time as 'now' for reproducible reports")
+
+	var appID, appKeyFile, appInstallationID string
+	flag.StringVar(&appID, "github-app-id", os.Getenv("GITHUB_APP_ID"), "GitHub App ID (authenticate with installation tokens instead of a PAT)")
+	flag.StringVar(&appKeyFile, "github-app-key", os.Getenv("GITHUB_APP_KEY"), "Path to the GitHub App private key (PEM)")
+	flag.StringVar(&appInstallationID, "github-app-installation", os.Getenv("GITHUB_APP_INSTALLATION_ID"), "Only use this GitHub App installation (default: all installations)")
 
 	var onlyRepos, excludeRepos string
 	flag.StringVar(&onlyRepos, "only", os.Getenv("ONLY_REPOS"), "Comma-separated list of repos to migrate (migrate only these)")
@@ -502,7 +1106,13 @@ func loadConfig() *Config {
 	var showVersion bool
 	flag.BoolVar(&showVersion, "version", false, "Show version and exit")
 
-	flag.Parse()
+	flag.CommandLine.Parse(args)
+	if config.ConcurrentMigrate <= 0 {
+		config.ConcurrentMigrate = config.Concurrent
+	}
+	if config.ConcurrentSync <= 0 {
+		config.ConcurrentSync = config.Concurrent
+	}
 
 	if showVersion {
 		fmt.Printf("github-forgejo-mirror version %s\n", version)
@@ -512,11 +1122,90 @@ func loadConfig() *Config {
 	config.OnlyRepos = parseStringSlice(onlyRepos)
 	config.ExcludeRepos = parseStringSlice(excludeRepos)
 
+	var err error
+	if targetsFile != "" {
+		if config.ExtraTargets, err = loadTargets(targetsFile); err != nil {
+			log.Fatalf("Invalid targets: %v", err)
+		}
+		names := map[string]bool{targetName(config.ForgejoURL): true}
+		for _, t := range config.ExtraTargets {
+			if names[t.Name] {
+				log.Fatalf("Duplicate target name %q; set a unique name in the targets file", t.Name)
+			}
+			names[t.Name] = true
+		}
+	}
+	if ownerMapFile != "" {
+		if config.OwnerMap, err = loadMappingFile(ownerMapFile); err != nil {
+			log.Fatalf("Invalid owner map: %v", err)
+		}
+	}
+	if userMapFile != "" {
+		if config.UserMap, err = loadMappingFile(userMapFile); err != nil {
+			log.Fatalf("Invalid user map: %v", err)
+		}
+	}
+	if accessMapFile != "" {
+		rules, err := loadMappingFile(accessMapFile)
+		if err == nil {
+			config.AccessMap, err = parseAccessMap(rules)
+		}
+		if err != nil {
+			log.Fatalf("Invalid access map: %v", err)
+		}
+	}
+	if actionsValuesFile != "" {
+		rules, err := loadMappingFile(actionsValuesFile)
+		if err == nil {
+			config.ActionsValues, err = parseActionsValues(rules)
+		}
+		if err != nil {
+			log.Fatalf("Invalid Actions values: %v", err)
+		}
+	}
+	if config.MirrorIntervals, err = parseKeyValues(mirrorIntervals); err != nil {
+		log.Fatalf("Invalid mirror intervals: %v", err)
+	}
+	intervals := []string{config.MirrorInterval}
+	for _, interval := range config.MirrorIntervals {
+		intervals = append(intervals, interval)
+	}
+	for _, interval := range intervals {
+		if _, err := time.ParseDuration(interval); interval != "" && err != nil {
+			log.Fatalf("Invalid mirror interval %q (e.g. '10m', '8h')", interval)
+		}
+	}
+
+	if freezeTime != "" {
+		t, err := time.Parse(time.RFC3339, freezeTime)
+		if err != nil {
+			log.Fatalf("Invalid freeze time %q (expected RFC 3339, e.g. 2025-01-01T00:00:00Z)", freezeTime)
+		}
+		config.FreezeTime = t
+	}
+
+	if appID != "" {
+		if config.GitHubAppID, err = strconv.ParseInt(appID, 10, 64); err != nil {
+			log.Fatalf("Invalid GitHub App ID %q", appID)
+		}
+		if appKeyFile == "" {
+			log.Fatal("GitHub App private key is required (--github-app-key or GITHUB_APP_KEY)")
+		}
+		if config.GitHubAppKey, err = loadAppKey(appKeyFile); err != nil {
+			log.Fatalf("Failed to load GitHub App private key: %v", err)
+		}
+		if appInstallationID != "" {
+			if config.GitHubAppInstallationID, err = strconv.ParseInt(appInstallationID, 10, 64); err != nil {
+				log.Fatalf("Invalid GitHub App installation ID %q", appInstallationID)
+			}
+		}
+	}
+
 	// Validation
-	if config.GitHubToken == "" {
+	if config.GitHubToken == "" && config.GitHubAppID == 0 {
 		log.Fatal("GitHub token is required (--github-token or GITHUB_TOKEN)")
 	}
-	if config.GitHubUser == "" {
+	if config.GitHubUser == "" && config.GitHubAppID == 0 {
 		log.Fatal("GitHub username is required (--github-user or GITHUB_USER)")
 	}
 	if config.ForgejoURL == "" {
@@ -528,6 +1217,129 @@ func loadConfig() *Config {
 	if config.ForgejoUser == "" && config.Organization == "" {
 		log.Fatal("Either Forgejo user or organization is required")
 	}
+	if config.ActionsIssue && !config.CheckActions {
+		log.Fatal("--actions-issue requires --check-actions")
+	}
+	if config.DetectForcePush && config.StateFile == "" {
+		log.Fatal("Force-push detection requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.LockFile == "" && config.StateFile != "" {
+		config.LockFile = config.StateFile + ".lock"
+	}
+	if config.Recreate {
+		config.OnExists = "recreate"
+	}
+	switch config.OnExists {
+	case "skip", "sync", "update-settings":
+	case "recreate":
+		config.Recreate = true
+	default:
+		log.Fatalf("Invalid on-exists policy %q (must be 'skip', 'sync', 'update-settings' or 'recreate')", config.OnExists)
+	}
+	switch config.OnDuplicate {
+	case "report", "skip", "rename", "replace":
+	case "convert":
+		log.Printf("Warning: --on-duplicate=convert is now called 'replace'")
+		config.OnDuplicate = "replace"
+	default:
+		log.Fatalf("Invalid on-duplicate policy %q (must be 'report', 'skip', 'rename' or 'replace')", config.OnDuplicate)
+	}
+	if config.Resume && config.StateFile == "" {
+		log.Fatal("Resuming a run requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.SkipUnchanged && config.StateFile == "" {
+		log.Fatal("Skipping unchanged repositories requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.ImportPulls && config.StateFile == "" {
+		log.Fatal("Importing pull requests requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.ImportPulls && config.ConvertWithHistory {
+		log.Fatal("--import-pulls converts mirrors in place; --convert-with-history already migrates the pull requests")
+	}
+	if config.SyncIssues && config.StateFile == "" {
+		log.Fatal("Syncing issues requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.ReflectIssues && config.StateFile == "" {
+		log.Fatal("Reflecting issues requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.ImportDiscussions && config.StateFile == "" {
+		log.Fatal("Importing discussions requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.RewriteReferences && config.StateFile == "" {
+		log.Fatal("Rewriting references requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.SinceLastRun && config.StateFile == "" {
+		log.Fatal("Syncing only repositories pushed to since the last run requires a state file (--state-file or STATE_FILE)")
+	}
+	if config.Mode != "mirror" && config.Mode != "migrate" {
+		log.Fatalf("Invalid mode %q (must be 'mirror' or 'migrate')", config.Mode)
+	}
+	if config.Mode == "migrate" && config.CloneProtocol == "ssh" {
+		log.Printf("Warning: Issues, pull requests and releases are not migrated over SSH; only git data will be copied")
+	}
+	if config.CleanupOrphans && config.GitHubSearch != "" && len(searchOwners(config.GitHubSearch)) == 0 {
+		log.Fatal("Cleanup with --github-search needs user: or org: qualifiers in the query to know which mirrors may be orphans")
+	}
+	if config.CleanupPolicy != "archive" && config.CleanupPolicy != "delete" && config.CleanupPolicy != "report" {
+		log.Fatalf("Invalid cleanup policy %q (must be 'archive', 'delete' or 'report')", config.CleanupPolicy)
+	}
+	config.StarWatchUsers = parseStringSlice(starWatchUsers)
+	config.StatusBranches = parseStringSlice(statusBranches)
+	for _, pattern := range config.StatusBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Fatalf("Invalid branch pattern %q in --copy-statuses: %v", pattern, err)
+		}
+	}
+	if len(config.StarWatchUsers) > 0 && !config.ForgejoAdmin {
+		log.Fatal("Starring and watching on behalf of other accounts requires --forgejo-admin")
+	}
+	if len(config.UserMap) > 0 && !config.ForgejoAdmin {
+		log.Fatal("Posting on behalf of the accounts in --user-map requires --forgejo-admin")
+	}
+	if config.CopyCollaborators && len(config.UserMap) == 0 {
+		log.Fatal("Copying collaborators requires a user map (--user-map or USER_MAP)")
+	}
+	if !strings.Contains(config.SanitizeName, "{name}") {
+		log.Fatalf("Invalid sanitize name %q (must contain {name})", config.SanitizeName)
+	}
+	if !strings.Contains(config.CollisionName, "{owner}") {
+		log.Fatalf("Invalid collision name %q (must contain {owner})", config.CollisionName)
+	}
+	if config.RetryFailed != "" && config.CleanupOrphans {
+		// Every mirror outside the report would look orphaned
+		log.Fatal("--retry-failed can't be combined with --cleanup")
+	}
+	if config.RetryAttempts < 1 {
+		log.Fatalf("Invalid retry attempts %d (must be at least 1)", config.RetryAttempts)
+	}
+	if config.HealthFactor < 1 {
+		log.Fatalf("Invalid health factor %d (must be at least 1)", config.HealthFactor)
+	}
+	if config.RepoTimeout < 0 {
+		log.Fatalf("Invalid repo timeout %v (must not be negative)", config.RepoTimeout)
+	}
+	if config.MigrationWait < 0 {
+		log.Fatalf("Invalid migration wait %v (must not be negative)", config.MigrationWait)
+	}
+	if config.VerifyRefs != "" && config.VerifyRefs != "counts" && config.VerifyRefs != "names" && config.VerifyRefs != "deep" {
+		log.Fatalf("Invalid ref verification %q (must be 'counts', 'names' or 'deep')", config.VerifyRefs)
+	}
+	if config.TargetType != "forgejo" && config.TargetType != "gitea" {
+		log.Fatalf("Invalid target type %q (must be 'forgejo' or 'gitea')", config.TargetType)
+	}
+	if config.EmptyRepos != "skip" && config.EmptyRepos != "create" {
+		log.Fatalf("Invalid empty repos policy %q (must be 'skip' or 'create')", config.EmptyRepos)
+	}
+	if config.CloneProtocol != "https" && config.CloneProtocol != "ssh" {
+		log.Fatalf("Invalid clone protocol %q (must be 'https' or 'ssh')", config.CloneProtocol)
+	}
+	if config.SSHDeployKey != "" {
+		key, err := os.ReadFile(config.SSHDeployKey)
+		if err != nil {
+			log.Fatalf("Failed to read SSH deploy key: %v", err)
+		}
+		config.SSHPublicKey = strings.TrimSpace(string(key))
+	}
 
 	// Clean up Forgejo URL
 	config.ForgejoURL = strings.TrimSuffix(config.ForgejoURL, "/")
@@ -535,35 +1347,208 @@ func loadConfig() *Config {
 	return config
 }
 
+// targetStats counts the outcomes of a run for one Forgejo target
+type targetStats struct {
+	created, alreadyExists, synced, skipped, failed, timedOut, conflicts, deleted int
+	// Breakdown of skipped repositories, and archived orphans
+	overQuota, unchanged, migrating, notProcessed, archived int
+}
+
+// add counts one repository result
+func (s *targetStats) add(result Result) {
+	switch result {
+	case ResultCreated:
+		s.created++
+	case ResultAlreadyExists:
+		s.alreadyExists++
+	case ResultSynced:
+		s.synced++
+	case ResultSkipped:
+		s.skipped++
+	case ResultFailed:
+		s.failed++
+	case ResultTimedOut:
+		s.timedOut++
+	case ResultConflict:
+		s.conflicts++
+	case ResultDeleted:
+		s.deleted++
+	}
+}
+
+// repoResult is the outcome of processing one repository for one target.
+// Detail is the skip reason or error message.
+type repoResult struct {
+	repo   *GitHubRepo
+	target string
+	mirror string // Forgejo full name
+	result Result
+	detail string
+}
+
 // printStats prints migration statistics
-func printStats(total, migrated, skipped, failed int, duration time.Duration) {
-	fmt.Printf("\n📊 Migration Summary:\n")
+func printStats(title string, total int, stats *targetStats, duration time.Duration) {
+	fmt.Printf("\n📊 %s:\n", title)
 	fmt.Printf("   Total repos: %d\n", total)
-	fmt.Printf("   Migrated: %d\n", migrated)
-	fmt.Printf("   Skipped: %d\n", skipped)
-	fmt.Printf("   Failed: %d\n", failed)
+	fmt.Printf("   Created: %d\n", stats.created)
+	fmt.Printf("   Already existed: %d\n", stats.alreadyExists)
+	fmt.Printf("   Synced: %d\n", stats.synced)
+	fmt.Printf("   Skipped: %d\n", stats.skipped)
+	if stats.notProcessed > 0 {
+		fmt.Printf("     Not processed: %d\n", stats.notProcessed)
+	}
+	if stats.overQuota > 0 {
+		fmt.Printf("     Over quota: %d\n", stats.overQuota)
+	}
+	if stats.unchanged > 0 {
+		fmt.Printf("     Unchanged: %d\n", stats.unchanged)
+	}
+	if stats.migrating > 0 {
+		fmt.Printf("     Migration in progress: %d\n", stats.migrating)
+	}
+	fmt.Printf("   Failed: %d\n", stats.failed)
+	if stats.timedOut > 0 {
+		fmt.Printf("   Timed out: %d\n", stats.timedOut)
+	}
+	if stats.conflicts > 0 {
+		fmt.Printf("   Conflicts: %d\n", stats.conflicts)
+	}
+	if stats.deleted > 0 {
+		fmt.Printf("   Deleted: %d\n", stats.deleted)
+	}
+	if stats.archived > 0 {
+		fmt.Printf("   Archived: %d\n", stats.archived)
+	}
 	fmt.Printf("   Duration: %v\n", duration.Round(time.Second))
 }
 
+// parseCommand splits an optional leading subcommand from the flag arguments
+func parseCommand(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "mirror", args
+}
+
 func main() {
-	config := loadConfig()
+	command, args := parseCommand(os.Args[1:])
+	config := loadConfig(args)
+	switch command {
+	case "plan":
+		config.DryRun = true
+		if config.PlanFile == "" {
+			config.PlanFile = "plan.json"
+		}
+	case "apply":
+		if flag.NArg() > 0 {
+			config.PlanFile = flag.Arg(0)
+		}
+		if config.PlanFile == "" {
+			config.PlanFile = "plan.json"
+		}
+	case "convert":
+		// The mirrors are replaced by one-time migrations
+		if config.ConvertWithHistory {
+			config.Mode = "migrate"
+		}
+	case "refresh-credentials":
+		// Only private repositories need credentials, and every mirror found
+		// is deleted first, so none may be skipped as unchanged
+		config.IncludePrivate = true
+		config.SkipUnchanged = false
+	}
 	client := NewClient(config)
 
-	ctx := context.Background()
-	startTime := time.Now()
+	// Commands that change Forgejo or the state file don't run concurrently
+	switch command {
+	case "mirror", "apply", "convert", "push-mirror", "repair", "refresh-credentials", "rollback":
+		if config.LockFile != "" {
+			if err := acquireLock(config.LockFile, config.LockWait); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if config.StateFile != "" {
+		state, err := loadState(config.StateFile)
+		if err != nil {
+			log.Fatalf("Failed to load state: %v", err)
+		}
+		client.state = state
+	}
+
+	stopping, ctx := shutdownContexts()
+
+	if config.ForgejoAdmin {
+		for _, target := range client.targets() {
+			if err := target.requireAdmin(ctx); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	switch command {
+	case "mirror", "plan":
+		runMirror(ctx, stopping, config, client)
+	case "apply":
+		os.Exit(runApply(stopping, config, client))
+	case "verify":
+		os.Exit(runVerify(stopping, config, client))
+	case "selftest":
+		os.Exit(runSelftest(ctx, config, client))
+	case "convert":
+		os.Exit(runConvert(stopping, config, client))
+	case "push-mirror":
+		os.Exit(runPushMirror(stopping, config, client))
+	case "repair":
+		os.Exit(runRepair(stopping, config, client))
+	case "refresh-credentials":
+		os.Exit(runRefreshCredentials(stopping, config, client))
+	case "rollback":
+		os.Exit(runRollback(stopping, config, client, flag.Arg(0)))
+	case "status":
+		os.Exit(runStatus(config, client))
+	case "health":
+		os.Exit(runHealth(stopping, config, client))
+	default:
+		log.Fatalf("Unknown command %q (available: mirror, plan, apply, verify, selftest, convert, push-mirror, repair, refresh-credentials, rollback, status, health)", command)
+	}
+}
+
+// runMirror migrates GitHub repositories to Forgejo and prints a summary
+func runMirror(ctx, stopping context.Context, config *Config, client *Client) {
+	startTime := client.clock.Now()
 
 	fmt.Printf("🚀 GitHub to Forgejo Mirror Tool v%s\n", version)
-	if config.GitHubOrg != "" {
+	if config.GitHubSearch != "" {
+		fmt.Printf("   Source: GitHub search %q\n", config.GitHubSearch)
+	} else if config.GitHubAppID != 0 {
+		fmt.Printf("   Source: GitHub App %d installations\n", config.GitHubAppID)
+	} else if config.GitHubOrg != "" {
 		fmt.Printf("   Source: %s (org) @github.com\n", config.GitHubOrg)
 	} else {
 		fmt.Printf("   Source: %s@github.com\n", config.GitHubUser)
 	}
-	fmt.Printf("   Target: %s\n", config.ForgejoURL)
+	targets := client.targets()
+	for _, target := range targets {
+		fmt.Printf("   Target: %s\n", target.config.ForgejoURL)
+	}
 	if config.DryRun {
-		fmt.Printf("   Mode: DRY RUN\n")
+		fmt.Printf("   Mode: DRY RUN (as of %s)\n", startTime.Format(time.RFC3339))
 	}
 	if config.Recreate {
 		fmt.Printf("   Mode: RECREATE (will delete existing repos)\n")
+	} else if config.OnExists != "sync" {
+		fmt.Printf("   Existing repos: %s\n", config.OnExists)
+	}
+	if config.Mode == "migrate" {
+		fmt.Printf("   Mode: MIGRATE (regular repos, no mirroring)\n")
+	}
+	if config.MapOrgs {
+		fmt.Printf("   Owners: mapped from GitHub owners\n")
+	}
+	if config.ForgejoAdmin {
+		fmt.Printf("   Mode: ADMIN (mirroring on behalf of other users)\n")
 	}
 	fmt.Println()
 
@@ -574,82 +1559,511 @@ func main() {
 		log.Fatalf("Failed to fetch GitHub repositories: %v", err)
 	}
 	fmt.Printf("   Found %d repositories on GitHub\n", len(githubRepos))
+	for _, issue := range client.listingIssues {
+		fmt.Printf("⚠️  The GitHub listing may be incomplete: %s\n", issue)
+	}
 
-	// Optionally fetch existing Forgejo repos for cleanup
-	var forgejoRepos []*ForgejoRepo
-	if config.CleanupOrphans {
-		fmt.Println("📡 Fetching Forgejo repositories for cleanup...")
-		forgejoRepos, err = client.GetForgejoRepos(ctx)
+	if config.RetryFailed != "" {
+		failed, err := loadFailedRepos(config.RetryFailed)
 		if err != nil {
-			log.Printf("Warning: Failed to fetch Forgejo repos for cleanup: %v", err)
+			log.Fatalf("Failed to load report: %v", err)
+		}
+		var retry []*GitHubRepo
+		for _, repo := range githubRepos {
+			if failed[repo.FullName] {
+				retry = append(retry, repo)
+			}
+		}
+		githubRepos = retry
+		fmt.Printf("   Retrying %d repositories that failed in %s\n", len(githubRepos), config.RetryFailed)
+	}
+
+	// Cleanup still needs to know about repositories a resumed run skips
+	pending := githubRepos
+	if client.state != nil {
+		since := client.state.StartRun(client.clock.Now(), config.Resume)
+		if !since.IsZero() {
+			pending = nil
+			for _, repo := range githubRepos {
+				if !client.state.Handled(repo.FullName, targets, since) {
+					pending = append(pending, repo)
+				}
+			}
+			fmt.Printf("   Resuming the run started %s ago: %d repositories already handled, %d left\n", client.clock.Since(since).Round(time.Second), len(githubRepos)-len(pending), len(pending))
+		} else if config.Resume {
+			fmt.Println("   The last run finished, nothing to resume: processing all repositories")
+		}
+		if !config.DryRun {
+			fmt.Printf("   Run ID: %s\n", client.state.RunID())
+		}
+	}
+
+	// Fetch existing Forgejo repos to recognise renamed repositories and for cleanup
+	forgejoRepos := make(map[string][]*ForgejoRepo)
+	for _, target := range targets {
+		fmt.Printf("📡 Fetching Forgejo repositories from %s...\n", target.name)
+		repos, err := target.GetForgejoRepos(ctx)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch Forgejo repos: %v", err)
 		} else {
-			fmt.Printf("   Found %d repositories on Forgejo\n", len(forgejoRepos))
+			forgejoRepos[target.name] = repos
+			fmt.Printf("   Found %d repositories on Forgejo\n", len(repos))
+		}
+		target.existing = make(map[string]*ForgejoRepo)
+		for _, repo := range forgejoRepos[target.name] {
+			target.existing[strings.ToLower(repo.FullName)] = repo
+		}
+		target.renames = target.FindRenames(ctx, githubRepos, forgejoRepos[target.name])
+		if len(target.renames) > 0 {
+			fmt.Printf("   %d repositories were renamed on GitHub since they were mirrored\n", len(target.renames))
+		}
+		if !config.IncludePrivate {
+			target.HidePrivateMirrors(ctx, forgejoRepos[target.name])
 		}
 	}
 
-	// Create a semaphore for concurrent operations
-	semaphore := make(chan struct{}, config.Concurrent)
-	results := make(chan string, len(githubRepos))
+	// Workers wait for migration and sync slots, so the pool is sized for the larger limit
+	workers := max(config.Concurrent, config.ConcurrentMigrate, config.ConcurrentSync)
+	results := make(chan repoResult, workers*len(targets))
 
-	var migrated, skipped, failed int
+	stats := make(map[string]*targetStats)
+	for _, target := range targets {
+		stats[target.name] = &targetStats{}
+	}
+	var forcePushes []string
+	var forcePushMu sync.Mutex
+	actionsFindings := make(map[string][]ActionsFinding)
+	var actionsMu sync.Mutex
 
 	// Process each repository
 	fmt.Println("\n🔄 Starting migration...")
-	for _, repo := range githubRepos {
-		go func(r *GitHubRepo) {
-			semaphore <- struct{}{}        // Acquire
-			defer func() { <-semaphore }() // Release
+	process := func(_ int, r *GitHubRepo) {
+		// report records the same result for every target
+		report := func(result Result, detail string) {
+			for _, target := range targets {
+				client.state.RecordResult(r, target, result, detail, client.clock.Now())
+				results <- repoResult{repo: r, target: target.name, result: result, detail: detail}
+			}
+		}
 
-			if config.Verbose {
-				fmt.Printf("🔍 Processing: %s (⭐%d, %s)\n", r.Name, r.Stars, r.Language)
+		if config.Verbose {
+			fmt.Printf("🔍 Processing: %s (⭐%d, %s)\n", r.Name, r.Stars, r.Language)
+		}
+
+		empty, err := client.IsEmptyGitHubRepo(ctx, r)
+		if err != nil {
+			report(ResultFailed, fmt.Sprintf("❌ Failed to migrate %s: %v", r.Name, err))
+			return
+		}
+		if empty && config.EmptyRepos == "skip" {
+			fmt.Printf("📭 Skipping empty repository: %s\n", r.Name)
+			report(ResultSkipped, skipEmpty)
+			return
+		}
+
+		var tips map[string]string
+		if config.DetectForcePush && !empty {
+			var rewritten []string
+			var err error
+			tips, rewritten, err = client.DetectForcePushes(ctx, r, client.state.BranchTips(r.FullName))
+			if err != nil {
+				log.Printf("Warning: Force-push detection failed: %v", err)
+			} else if len(rewritten) > 0 {
+				fmt.Printf("🚨 FORCE-PUSH detected on %s: %s (the mirror will rewrite its history to match)\n", r.Name, strings.Join(rewritten, ", "))
+				forcePushMu.Lock()
+				forcePushes = append(forcePushes, fmt.Sprintf("%s: %s", r.FullName, strings.Join(rewritten, ", ")))
+				forcePushMu.Unlock()
 			}
+		}
 
-			if err := client.MigrateRepo(ctx, r); err != nil {
-				results <- fmt.Sprintf("❌ Failed to migrate %s: %v", r.Name, err)
-				return
+		var findings []ActionsFinding
+		if config.CheckActions && !empty {
+			var err error
+			if findings, err = client.CheckWorkflows(ctx, r); err != nil {
+				log.Printf("Warning: Checking the workflows of %s failed: %v", r.Name, err)
+			} else if len(findings) > 0 {
+				actionsMu.Lock()
+				actionsFindings[r.FullName] = findings
+				actionsMu.Unlock()
 			}
-			results <- "success"
-		}(repo)
+		}
+
+		succeeded := true
+		for _, target := range targets {
+			result, detail := target.mirrorRepo(ctx, r, empty)
+			if !result.Succeeded() {
+				succeeded = false
+			} else if config.ActionsIssue && len(findings) > 0 {
+				if err := target.TrackActionsFindings(ctx, r, findings); err != nil {
+					fmt.Printf("⚠️  Failed to open the Forgejo Actions issue of %s on %s: %v\n", r.Name, target.name, err)
+				}
+			}
+			client.state.RecordResult(r, target, result, detail, client.clock.Now())
+			results <- repoResult{repo: r, target: target.name, mirror: target.ownerFor(r) + "/" + r.Name, result: result, detail: detail}
+		}
+		if tips != nil && succeeded {
+			client.state.SetBranchTips(r.FullName, tips)
+		}
+	}
+	// Repositories not started before shutdown was requested
+	cancelled := func(_ int, r *GitHubRepo) {
+		for _, target := range targets {
+			results <- repoResult{repo: r, target: target.name, result: ResultSkipped, detail: skipInterrupted}
+		}
 	}
+	go func() {
+		forEach(stopping, workers, pending, process, cancelled)
+		close(results)
+	}()
 
 	// Collect results
-	for i := 0; i < len(githubRepos); i++ {
-		result := <-results
-		if result == "success" {
-			migrated++
-		} else if strings.Contains(result, "already exists") {
-			skipped++
-		} else {
-			failed++
+	report := &RunReport{}
+	notProcessed := make(map[string]bool)
+	var created []CreatedRepo
+	var unsaved int
+	lastSave := client.clock.Now()
+	for result := range results {
+		s := stats[result.target]
+		s.add(result.result)
+		if result.result == ResultCreated && !config.DryRun {
+			repo := CreatedRepo{Target: result.target, Mirror: result.mirror, Repo: result.repo.FullName}
+			created = append(created, repo)
+			client.state.RecordCreated(repo)
+		}
+		// Checkpoint progress so a crashed or killed run can be resumed. The
+		// final save below covers the results since the last checkpoint.
+		if unsaved++; client.state != nil && !config.DryRun && (unsaved >= checkpointEvery || client.clock.Since(lastSave) >= checkpointInterval) {
+			if err := client.state.Save(); err != nil {
+				log.Printf("Warning: Failed to save state: %v", err)
+			}
+			unsaved = 0
+			lastSave = client.clock.Now()
+		}
+		switch {
+		case result.detail == skipInterrupted:
+			s.notProcessed++
+			notProcessed[result.repo.FullName] = true
+			// Listed in the report so --retry-failed picks them up
+			report.Failed = append(report.Failed, FailedRepo{Repo: result.repo.FullName, Target: result.target, Error: result.detail})
+		case result.detail == skipQuota:
+			s.overQuota++
+		case result.detail == skipUnchanged:
+			s.unchanged++
+		case result.detail == skipMigrating:
+			s.migrating++
+		case result.result == ResultFailed || result.result == ResultTimedOut || result.result == ResultConflict:
+			report.Failed = append(report.Failed, FailedRepo{Repo: result.repo.FullName, Target: result.target, Error: result.detail})
 			if config.Verbose {
-				fmt.Println(result)
+				fmt.Println(result.detail)
 			}
 		}
 	}
 
-	// Cleanup orphaned mirrors
-	if config.CleanupOrphans && len(forgejoRepos) > 0 {
-		fmt.Println("\n🧹 Cleaning up orphaned mirrors...")
-		githubNames := make(map[string]bool)
-		for _, repo := range githubRepos {
-			githubNames[repo.Name] = true
+	// Cleanup orphaned mirrors, unless the run was interrupted
+	strictAbort := config.CleanupOrphans && config.Strict && len(client.listingIssues) > 0
+	if strictAbort {
+		fmt.Println("\n🛑 Skipping cleanup: the GitHub listing may be incomplete and --strict is set")
+	} else if config.CleanupOrphans && stopping.Err() == nil {
+		for _, target := range targets {
+			if repos := forgejoRepos[target.name]; len(repos) > 0 {
+				target.CleanupOrphans(ctx, githubRepos, repos, stats[target.name])
+			}
+		}
+	}
+
+	if len(forcePushes) > 0 {
+		sort.Strings(forcePushes)
+		fmt.Println("\n🚨 History rewritten on GitHub since the last run:")
+		for _, entry := range forcePushes {
+			fmt.Printf("   %s\n", entry)
+		}
+	}
+
+	if len(actionsFindings) > 0 {
+		names := make([]string, 0, len(actionsFindings))
+		for name := range actionsFindings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("\n🧪 GitHub Actions features Forgejo Actions doesn't support:")
+		for _, name := range names {
+			fmt.Printf("   %s\n", name)
+			for _, finding := range actionsFindings[name] {
+				fmt.Printf("      %s\n", finding)
+			}
+		}
+		report.Actions = actionsFindings
+	}
+
+	if client.state != nil && !config.DryRun {
+		if stopping.Err() == nil {
+			client.state.FinishRun(client.clock.Now())
+		}
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	if config.ReportFile != "" {
+		sort.Slice(report.Failed, func(i, j int) bool {
+			return report.Failed[i].Repo+report.Failed[i].Target < report.Failed[j].Repo+report.Failed[j].Target
+		})
+		report.FinishedAt = client.clock.Now()
+		if err := writeReport(config.ReportFile, report); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	duration := client.clock.Since(startTime)
+	var failed int
+	for _, target := range targets {
+		title := "Migration Summary"
+		if len(targets) > 1 {
+			title = fmt.Sprintf("Migration Summary for %s", target.name)
+		}
+		if config.DryRun {
+			target.plan.Print(strings.Replace(title, "Migration Summary", "Plan", 1))
+		}
+		printStats(title, len(pending), stats[target.name], duration)
+		if config.DryRun {
+			fmt.Printf("   Projected disk usage of new repositories: %.1f MB\n", float64(target.quota.projected)/1e6)
+		}
+		failed += stats[target.name].failed + stats[target.name].timedOut + stats[target.name].conflicts
+	}
+
+	if config.DryRun && config.PlanFile != "" && stopping.Err() == nil {
+		if err := writePlanFile(config.PlanFile, targets, client.clock.Now()); err != nil {
+			log.Printf("Warning: %v", err)
+		} else {
+			fmt.Printf("\n💾 Plan written to %s, review it and run 'apply %s' to execute it\n", config.PlanFile, config.PlanFile)
 		}
+	}
 
-		for _, forgejoRepo := range forgejoRepos {
-			if forgejoRepo.Mirror && !githubNames[forgejoRepo.Name] {
-				fmt.Printf("🗑️  Found orphaned mirror: %s\n", forgejoRepo.Name)
-				// Note: Deletion would require additional API call
+	if config.RollbackOnFailure && !config.DryRun && (stopping.Err() != nil || failed > 0) && len(created) > 0 {
+		fmt.Printf("\n⏪ Rolling back: deleting the %d repositories this run created\n", len(created))
+		var run *RunState
+		if client.state != nil {
+			run = client.state.FindRun(client.state.RunID())
+		}
+		if n := rollbackCreated(ctx, targets, client.state, run, created); n > 0 {
+			fmt.Printf("⚠️  %d repositories couldn't be deleted\n", n)
+		}
+		if client.state != nil {
+			if err := client.state.Save(); err != nil {
+				log.Printf("Warning: Failed to save state: %v", err)
 			}
 		}
 	}
 
-	duration := time.Since(startTime)
-	printStats(len(githubRepos), migrated, skipped, failed, duration)
+	if stopping.Err() != nil {
+		names := make([]string, 0, len(notProcessed))
+		for name := range notProcessed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("\n🛑 Interrupted: %d repositories were not processed:\n", len(names))
+		for _, name := range names {
+			fmt.Printf("   %s\n", name)
+		}
+		os.Exit(130)
+	}
 
 	if failed > 0 {
 		fmt.Printf("\n⚠️  %d repositories failed to migrate. Check logs for details.\n", failed)
 		os.Exit(1)
 	}
+	if strictAbort {
+		os.Exit(1)
+	}
 
 	fmt.Println("\n🎉 Migration completed successfully!")
 }
+
+// mirrorRepo migrates a single repository to this client's target within the
+// configured --repo-timeout and returns the result with the skip reason or
+// error message
+func (c *Client) mirrorRepo(ctx context.Context, r *GitHubRepo, empty bool) (Result, string) {
+	if c.config.RepoTimeout <= 0 {
+		return c.processRepo(ctx, r, empty)
+	}
+	repoCtx, cancel := context.WithTimeout(ctx, c.config.RepoTimeout)
+	defer cancel()
+
+	result, detail := c.processRepo(repoCtx, r, empty)
+	if !result.Succeeded() && errors.Is(repoCtx.Err(), context.DeadlineExceeded) {
+		return ResultTimedOut, fmt.Sprintf("⏱️  Timed out mirroring %s to %s after %v", r.Name, c.name, c.config.RepoTimeout)
+	}
+	return result, detail
+}
+
+// processRepo does the work of mirrorRepo without a deadline of its own
+func (c *Client) processRepo(ctx context.Context, r *GitHubRepo, empty bool) (Result, string) {
+	if c.unchanged(r) {
+		if c.config.Verbose {
+			fmt.Printf("⏭️  Unchanged since the last run: %s\n", r.Name)
+		}
+		return ResultSkipped, skipUnchanged
+	}
+
+	if old, ok := c.renames[r.FullName]; ok {
+		if err := c.RenameMirror(ctx, r, old); err != nil {
+			return ResultFailed, fmt.Sprintf("❌ Failed to rename the mirror of %s on %s: %v", r.Name, c.name, err)
+		}
+	}
+
+	if conflict := c.nameConflict(r); conflict != nil {
+		return ResultConflict, fmt.Sprintf("⚠️  Name conflict: %s would be mirrored as %s/%s on %s, but %s already exists and isn't its mirror", r.FullName, c.ownerFor(r), r.Name, c.name, conflict.FullName)
+	}
+
+	replaced := false
+	if dup := c.duplicate(r); dup != nil {
+		if c.placeholder(r, dup, empty) {
+			if !empty {
+				if err := c.replacePlaceholder(ctx, r, dup); err != nil {
+					return ResultFailed, fmt.Sprintf("❌ Failed to replace the empty repository %s on %s: %v", dup.FullName, c.name, err)
+				}
+				replaced = true
+			}
+		} else if result, detail, handled := c.handleDuplicate(ctx, r, dup); handled {
+			return result, detail
+		}
+	}
+
+	// Migrating again or syncing would start a second task for the same repository
+	if existing, ok := c.existing[strings.ToLower(c.ownerFor(r)+"/"+r.Name)]; ok && !replaced && migrationInProgress(r, existing, c.clock.Now()) {
+		fmt.Printf("⏳ Skipping %s on %s: Forgejo is still migrating it\n", r.Name, c.name)
+		return ResultSkipped, skipMigrating
+	}
+
+	if !c.reserveQuota(ctx, r) {
+		fmt.Printf("💾 Skipping %s on %s: it would exceed the storage quota of %s\n", r.Name, c.name, c.ownerFor(r))
+		return ResultSkipped, skipQuota
+	}
+
+	var result Result
+	if empty {
+		var err error
+		result, err = c.CreateEmptyRepo(ctx, r)
+		if err != nil {
+			return ResultFailed, fmt.Sprintf("❌ Failed to create %s on %s: %v", r.Name, c.name, err)
+		}
+		if result == ResultCreated && !c.config.DryRun {
+			c.state.SetEmptyRepo(r, c.name, true)
+		}
+	} else {
+		err := c.withRetry(ctx, "Migration of "+r.Name, func() error {
+			var err error
+			result, err = c.MigrateRepo(ctx, r)
+			return err
+		})
+		if errors.Is(err, errStillMigrating) {
+			// The next run finishes it as an existing repository
+			fmt.Printf("⏳ %s on %s wasn't cloned within %s: Forgejo is still migrating it\n", r.Name, c.name, c.config.MigrationWait)
+			return ResultSkipped, skipMigrating
+		}
+		if err != nil {
+			return ResultFailed, fmt.Sprintf("❌ Failed to migrate %s to %s: %v", r.Name, c.name, err)
+		}
+		if c.config.SyncWikis {
+			if err := c.SyncWiki(ctx, r, result); err != nil {
+				fmt.Printf("⚠️  Wiki sync failed for %s: %v\n", r.Name, err)
+			}
+		} else if c.config.WikiFallback {
+			if err := c.EnsureWiki(ctx, r); err != nil {
+				fmt.Printf("⚠️  Wiki fallback failed for %s: %v\n", r.Name, err)
+			}
+		}
+	}
+
+	if err := c.ApplyAccess(ctx, r); err != nil {
+		fmt.Printf("⚠️  Failed to grant access to %s: %v\n", r.Name, err)
+	}
+	if c.config.CopyCollaborators {
+		if err := c.CopyCollaborators(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy collaborators of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.MirrorNotes && !empty {
+		if err := c.MirrorNotes(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to mirror git notes of %s: %v\n", r.Name, err)
+		}
+	}
+	// Milestones first, so synced issues can be linked to them
+	if c.config.SyncLabels {
+		if err := c.SyncLabels(ctx, r, result); err != nil {
+			fmt.Printf("⚠️  Label sync failed for %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.SyncIssues || c.config.ReflectIssues {
+		if err := c.SyncIssues(ctx, r, result); err != nil {
+			fmt.Printf("⚠️  Issue sync failed for %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.PinIssues {
+		if err := c.PinIssues(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to pin issues of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.ImportDiscussions {
+		if err := c.ImportDiscussions(ctx, r); err != nil {
+			fmt.Printf("⚠️  Discussion import failed for %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.RewriteReferences {
+		if err := c.RewriteReferences(ctx, r, result); err != nil {
+			fmt.Printf("⚠️  Failed to rewrite GitHub links in %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.SyncReleases {
+		if err := c.SyncReleases(ctx, r); err != nil {
+			fmt.Printf("⚠️  Release sync failed for %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyAvatars {
+		if err := c.CopyRepoAvatar(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy avatar of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyWebhooks {
+		if err := c.CopyWebhooks(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy webhooks of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyDeployKeys {
+		if err := c.CopyDeployKeys(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy deploy keys of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyTagProtection {
+		if err := c.CopyTagProtection(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy tag protection of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyRulesets {
+		if err := c.CopyRulesets(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy rulesets of %s: %v\n", r.Name, err)
+		}
+	}
+	if len(c.config.StatusBranches) > 0 && !empty {
+		if err := c.CopyStatuses(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy commit statuses of %s: %v\n", r.Name, err)
+		}
+	}
+	// A first push to an empty repository would make the branch its default
+	if c.config.ExportDeployments && !empty {
+		if err := c.ExportDeployments(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to export deployments of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.CopyPackages {
+		if err := c.CopyPackages(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to copy container images of %s: %v\n", r.Name, err)
+		}
+	}
+	if c.config.ScaffoldActions {
+		if err := c.ScaffoldActions(ctx, r); err != nil {
+			fmt.Printf("⚠️  Failed to scaffold Actions of %s: %v\n", r.Name, err)
+		}
+	}
+	return result, ""
+}
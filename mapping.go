@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// mappingRule maps GitHub repositories or accounts matching a glob pattern to a value
+type mappingRule struct {
+	pattern string
+	value   string
+}
+
+// loadMappingFile reads "pattern -> value" rules, one per line. Blank lines
+// and lines starting with # are ignored.
+func loadMappingFile(filePath string) ([]mappingRule, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []mappingRule
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, value, ok := strings.Cut(line, "->")
+		pattern, value = strings.TrimSpace(pattern), strings.TrimSpace(value)
+		if !ok || pattern == "" || value == "" {
+			return nil, fmt.Errorf("%s:%d: expected 'pattern -> value'", filePath, lineNo)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q", filePath, lineNo, pattern)
+		}
+		rules = append(rules, mappingRule{pattern: pattern, value: value})
+	}
+	return rules, scanner.Err()
+}
+
+// matchRule returns the value of the first rule whose pattern matches name, ignoring case
+func matchRule(rules []mappingRule, name string) (string, bool) {
+	for _, rule := range rules {
+		if ok, _ := path.Match(strings.ToLower(rule.pattern), strings.ToLower(name)); ok {
+			return rule.value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxRepoNameLength is the longest repository name Forgejo accepts
+const maxRepoNameLength = 100
+
+var (
+	invalidNameChars = regexp.MustCompile(`[^-_.a-zA-Z0-9]+`)
+	dotRuns          = regexp.MustCompile(`\.{2,}`)
+	// Forgejo reserves these names, and names ending in these suffixes, for its own routes
+	reservedRepoNames    = []string{".", "..", "-"}
+	reservedRepoSuffixes = []string{".git", ".wiki", ".rss", ".atom"}
+)
+
+// invalidNameReason returns why Forgejo would reject a repository name, or an
+// empty string if it is valid
+func invalidNameReason(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case containsFold(reservedRepoNames, name):
+		return "reserved name"
+	case invalidNameChars.MatchString(name):
+		return "unsupported characters"
+	case dotRuns.MatchString(name):
+		return "consecutive dots"
+	case strings.HasPrefix(name, "."):
+		return "leading dot"
+	case len(name) > maxRepoNameLength:
+		return fmt.Sprintf("longer than %d characters", maxRepoNameLength)
+	}
+	for _, suffix := range reservedRepoSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return "reserved suffix " + suffix
+		}
+	}
+	return ""
+}
+
+// cleanRepoName turns a name Forgejo rejects into a valid one: unsupported
+// characters become dashes, dots that aren't allowed where they are become
+// dashes too, and overly long names are shortened
+func cleanRepoName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "-")
+	name = dotRuns.ReplaceAllString(name, "-")
+	if strings.HasPrefix(name, ".") {
+		name = strings.TrimLeft(name, ".")
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range reservedRepoSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			name = name[:len(name)-len(suffix)] + "-" + name[len(name)-len(suffix)+1:]
+			break
+		}
+	}
+	if len(name) > maxRepoNameLength {
+		name = name[:maxRepoNameLength]
+	}
+	if name == "" || containsFold(reservedRepoNames, name) {
+		name = "repo"
+	}
+	return name
+}
+
+// sanitizeNames renames repositories whose GitHub name Forgejo would reject
+// after the --sanitize-name template, instead of failing with an opaque 422
+func (c *Client) sanitizeNames(repos []*GitHubRepo) {
+	for _, repo := range repos {
+		reason := invalidNameReason(repo.Name)
+		if reason == "" {
+			continue
+		}
+		name := strings.NewReplacer("{owner}", repo.Owner, "{name}", cleanRepoName(repo.Name)).Replace(c.config.SanitizeName)
+		if invalidNameReason(name) != "" {
+			name = cleanRepoName(name)
+		}
+		fmt.Printf("🧹 %s is not a valid Forgejo name (%s), mirroring it as %s\n", repo.FullName, reason, name)
+		repo.Name = name
+	}
+}
+
+// githubName returns the name of a repository on GitHub, which differs from
+// Name when the mirror was renamed to avoid a collision
+func (r *GitHubRepo) githubName() string {
+	_, name, _ := strings.Cut(r.FullName, "/")
+	return name
+}
+
+// resolveNameCollisions renames repositories that would end up with the same
+// name, ignoring case, under the same Forgejo owner. The repository of the
+// organization listed first in --github-org keeps its name, otherwise the one
+// whose full name sorts first, so the same repository wins on every run
+// whatever order GitHub lists them in. The others are named after the
+// --collision-name template, with a number appended if that name is taken too.
+func (c *Client) resolveNameCollisions(repos []*GitHubRepo) {
+	orgs := parseStringSlice(c.config.GitHubOrg)
+	rank := func(repo *GitHubRepo) int {
+		if i := slices.IndexFunc(orgs, func(org string) bool { return strings.EqualFold(org, repo.Owner) }); i >= 0 {
+			return i
+		}
+		return len(orgs)
+	}
+	ordered := slices.Clone(repos)
+	slices.SortStableFunc(ordered, func(a, b *GitHubRepo) int {
+		return cmp.Or(cmp.Compare(rank(a), rank(b)), strings.Compare(strings.ToLower(a.FullName), strings.ToLower(b.FullName)))
+	})
+
+	key := func(repo *GitHubRepo, name string) string {
+		return strings.ToLower(c.ownerFor(repo) + "/" + name)
+	}
+	// Every name in use, so a renamed repository doesn't take the name of
+	// another one
+	names := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		names[key(repo, repo.Name)] = true
+	}
+	taken := make(map[string]string)
+	for _, repo := range ordered {
+		first, collides := taken[key(repo, repo.Name)]
+		if !collides {
+			taken[key(repo, repo.Name)] = repo.FullName
+			continue
+		}
+
+		base := strings.NewReplacer("{owner}", repo.Owner, "{name}", repo.Name).Replace(c.config.CollisionName)
+		name := base
+		for n := 2; names[key(repo, name)]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		names[key(repo, name)] = true
+		taken[key(repo, name)] = repo.FullName
+		fmt.Printf("🔀 %s has the same name as %s, mirroring it as %s\n", repo.FullName, first, name)
+		repo.Name = name
+	}
+}
+
+// nameConflict returns the existing Forgejo repository that has the name of a
+// repository in a different case and isn't its mirror. Forgejo treats such
+// names as the same, so migrating would update the wrong repository.
+func (c *Client) nameConflict(repo *GitHubRepo) *ForgejoRepo {
+	fullName := c.ownerFor(repo) + "/" + repo.Name
+	existing, ok := c.existing[strings.ToLower(fullName)]
+	if !ok || existing.FullName == fullName {
+		return nil
+	}
+	if sameRemote(existing.OriginalURL, repo.CloneURL) || sameRemote(existing.OriginalURL, repo.SSHURL) {
+		return nil
+	}
+	if old, renamed := c.renames[repo.FullName]; renamed && strings.EqualFold(old.owner+"/"+old.name, existing.FullName) {
+		return nil
+	}
+	return existing
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInvalidNameReason(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"api", ""},
+		{"my_repo.v2", ""},
+		{"-", "reserved name"},
+		{"..", "reserved name"},
+		{"my repo", "unsupported characters"},
+		{"café", "unsupported characters"},
+		{"a..b", "consecutive dots"},
+		{".env", "leading dot"},
+		{strings.Repeat("a", maxRepoNameLength+1), "longer than 100 characters"},
+		{"Site.GIT", "reserved suffix .git"},
+		{"docs.wiki", "reserved suffix .wiki"},
+	}
+	for _, tt := range tests {
+		if got := invalidNameReason(tt.name); got != tt.want {
+			t.Errorf("invalidNameReason(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCleanRepoName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"api", "api"},
+		{"my repo!", "my-repo-"},
+		{"a..b", "a-b"},
+		{".hidden", "hidden"},
+		{"site.git", "site-git"},
+		{"Feed.RSS", "Feed-RSS"},
+		{strings.Repeat("a", maxRepoNameLength+20), strings.Repeat("a", maxRepoNameLength)},
+		{"", "repo"},
+		{"...", "repo"},
+	}
+	for _, tt := range tests {
+		got := cleanRepoName(tt.name)
+		if got != tt.want {
+			t.Errorf("cleanRepoName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if reason := invalidNameReason(got); reason != "" {
+			t.Errorf("cleanRepoName(%q) = %q is still invalid: %s", tt.name, got, reason)
+		}
+	}
+}
+
+func TestResolveNameCollisions(t *testing.T) {
+	repo := func(fullName string) *GitHubRepo {
+		owner, name, _ := strings.Cut(fullName, "/")
+		return &GitHubRepo{FullName: fullName, Owner: owner, Name: name}
+	}
+
+	tests := []struct {
+		name  string
+		orgs  string
+		repos []string
+		want  []string
+	}{
+		{"no collision", "", []string{"acme/api", "labs/tools"}, []string{"api", "tools"}},
+		{"sorted by full name", "", []string{"labs/tools", "acme/tools"}, []string{"labs-tools", "tools"}},
+		{"ignores case", "", []string{"labs/Tools", "acme/tools"}, []string{"labs-Tools", "tools"}},
+		{"first organization wins", "labs,acme", []string{"acme/tools", "labs/tools"}, []string{"acme-tools", "tools"}},
+		{"renamed name taken", "", []string{"acme/tools", "labs/tools", "other/labs-tools"}, []string{"tools", "labs-tools-2", "labs-tools"}},
+	}
+	for _, tt := range tests {
+		c := &Client{config: &Config{Organization: "mirrors", GitHubOrg: tt.orgs, CollisionName: "{owner}-{name}"}}
+		var repos []*GitHubRepo
+		for _, fullName := range tt.repos {
+			repos = append(repos, repo(fullName))
+		}
+		c.resolveNameCollisions(repos)
+
+		var got []string
+		for _, r := range repos {
+			got = append(got, r.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: names = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
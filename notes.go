@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// hiddenNamespace returns the namespace of a ref outside branches and tags,
+// such as "refs/notes" or "refs/pull", or "" for branches, tags and HEAD
+func hiddenNamespace(ref string) string {
+	parts := strings.SplitN(ref, "/", 3)
+	if len(parts) < 3 || parts[0] != "refs" || parts[1] == "heads" || parts[1] == "tags" {
+		return ""
+	}
+	return "refs/" + parts[1]
+}
+
+// MirrorNotes pushes the git notes (refs/notes/*) of a GitHub repository to
+// its Forgejo copy if they are missing or outdated there, and reports the
+// other refs outside branches and tags that aren't on Forgejo, such as
+// GitHub's refs/pull/*. Mirrors can't be pushed to, so notes missing there
+// are only reported.
+func (c *Client) MirrorNotes(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Empty {
+		return err
+	}
+	source, target := repo.CloneURL, c.forgejoCloneURL(owner, repo.Name)
+	ghAuth, err := c.githubAuth(repo, source)
+	if err != nil {
+		return err
+	}
+	auth := []gitAuth{ghAuth, c.forgejoAuth(target)}
+
+	githubRefs, err := lsRemote(ctx, source, auth)
+	if err != nil {
+		return fmt.Errorf("failed to list refs on GitHub: %w", err)
+	}
+	forgejoRefs, err := lsRemote(ctx, target, auth)
+	if err != nil {
+		return fmt.Errorf("failed to list refs on Forgejo: %w", err)
+	}
+
+	var notes int
+	skipped := make(map[string]int)
+	for ref, sha := range githubRefs {
+		namespace := hiddenNamespace(ref)
+		if namespace == "" || forgejoRefs[ref] == sha {
+			continue
+		}
+		if namespace == "refs/notes" {
+			notes++
+		} else {
+			skipped[namespace]++
+		}
+	}
+	if len(skipped) > 0 {
+		var counts []string
+		for namespace, count := range skipped {
+			counts = append(counts, fmt.Sprintf("%s/* (%d)", namespace, count))
+		}
+		sort.Strings(counts)
+		fmt.Printf("ℹ️  Skipped refs of %s that aren't branches, tags or notes: %s\n", repo.Name, strings.Join(counts, ", "))
+	}
+	if notes == 0 {
+		return nil
+	}
+	if forgejoRepo.Mirror {
+		fmt.Printf("⚠️  %d notes refs of %s are missing on its mirror, which can't be pushed to\n", notes, repo.Name)
+		return nil
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would push %d notes refs of %s\n", notes, repo.Name)
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "notes-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if _, err := runGit(ctx, dir, auth, "init", "--bare", "--quiet"); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, dir, auth, "fetch", "--quiet", source, "+refs/notes/*:refs/notes/*"); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, dir, auth, "push", "--quiet", "--force", target, "refs/notes/*:refs/notes/*"); err != nil {
+		return err
+	}
+	fmt.Printf("📝 Pushed %d notes refs of %s\n", notes, repo.Name)
+	return nil
+}
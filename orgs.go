@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// orgCache remembers which Forgejo organizations are known to exist
+type orgCache struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+func newOrgCache() *orgCache {
+	return &orgCache{names: make(map[string]bool)}
+}
+
+// ownerFor returns the Forgejo user or organization a repository is mirrored to
+func (c *Client) ownerFor(repo *GitHubRepo) string {
+	owner, _ := c.resolveOwner(repo)
+	return owner
+}
+
+// resolveOwner returns the Forgejo owner of a repository and whether it is an
+// organization. Owner map rules take precedence over --map-orgs, which takes
+// precedence over the global user or organization.
+func (c *Client) resolveOwner(repo *GitHubRepo) (string, bool) {
+	if value, ok := matchRule(c.config.OwnerMap, repo.FullName); ok {
+		kind, name, found := strings.Cut(value, ":")
+		if !found {
+			return value, true
+		}
+		return strings.TrimSpace(name), strings.TrimSpace(kind) != "forgejo-user"
+	}
+	if c.config.MapOrgs && repo.Owner != "" {
+		return repo.Owner, true
+	}
+	return c.targetOwner(), c.config.Organization != ""
+}
+
+// ensureOwner creates the organization a repository is mirrored to, if it
+// was derived from the owner map or --map-orgs rather than configured directly
+func (c *Client) ensureOwner(ctx context.Context, repo *GitHubRepo) error {
+	owner, isOrg := c.resolveOwner(repo)
+	if !isOrg || strings.EqualFold(owner, c.config.Organization) {
+		return nil
+	}
+	return c.EnsureOrg(ctx, owner, repo)
+}
+
+// EnsureOrg creates a Forgejo organization unless it already exists. If the
+// organization mirrors the GitHub owner of source, its profile is copied.
+func (c *Client) EnsureOrg(ctx context.Context, name string, source *GitHubRepo) error {
+	if name == c.config.ForgejoUser {
+		return nil
+	}
+
+	// Hold the lock while creating so concurrent workers don't race
+	c.knownOrgs.mu.Lock()
+	defer c.knownOrgs.mu.Unlock()
+	if c.knownOrgs.names[strings.ToLower(name)] {
+		return nil
+	}
+
+	status, body, err := c.forgejoRequest(ctx, "GET", "/orgs/"+url.PathEscape(name), nil)
+	if err != nil {
+		return fmt.Errorf("failed to check organization %s: %w", name, err)
+	}
+	switch status {
+	case http.StatusOK:
+		c.knownOrgs.names[strings.ToLower(name)] = true
+		return nil
+	case http.StatusNotFound:
+	default:
+		return forgejoError("checking organization "+name, status, body)
+	}
+
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would create organization: %s\n", name)
+		c.knownOrgs.names[strings.ToLower(name)] = true
+		return nil
+	}
+
+	payload := map[string]string{"username": name}
+	var profile *github.User
+	if source != nil && strings.EqualFold(source.Owner, name) {
+		if profile, err = c.getGitHubProfile(ctx, source); err != nil {
+			log.Printf("Warning: Failed to fetch GitHub profile of %s: %v", source.Owner, err)
+		} else {
+			payload["full_name"] = profile.GetName()
+			payload["description"] = profile.GetBio()
+			payload["website"] = profile.GetBlog()
+			payload["location"] = profile.GetLocation()
+		}
+	}
+
+	status, body, err = c.forgejoRequest(ctx, "POST", "/orgs", payload)
+	if err != nil {
+		return fmt.Errorf("failed to create organization %s: %w", name, err)
+	}
+	switch status {
+	case http.StatusCreated:
+		fmt.Printf("🏢 Created organization: %s\n", name)
+	default:
+		return forgejoError("creating organization "+name, status, body)
+	}
+	c.knownOrgs.names[strings.ToLower(name)] = true
+
+	if profile != nil && profile.GetAvatarURL() != "" {
+		if err := c.copyOrgAvatar(ctx, name, profile.GetAvatarURL()); err != nil {
+			log.Printf("Warning: Failed to copy avatar of %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// getGitHubProfile fetches the profile of the GitHub account that owns the
+// repository. Organization descriptions are returned as the bio.
+func (c *Client) getGitHubProfile(ctx context.Context, source *GitHubRepo) (*github.User, error) {
+	gh := c.githubFor(source)
+	org, _, err := gh.Organizations.Get(ctx, source.Owner)
+	if err == nil {
+		return &github.User{
+			Name:      org.Name,
+			Bio:       org.Description,
+			Blog:      org.Blog,
+			Location:  org.Location,
+			AvatarURL: org.AvatarURL,
+		}, nil
+	}
+
+	// The owner may be a user account
+	user, _, userErr := gh.Users.Get(ctx, source.Owner)
+	if userErr != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// copyOrgAvatar downloads an image and uploads it as the avatar of a Forgejo organization
+func (c *Client) copyOrgAvatar(ctx context.Context, org, avatarURL string) error {
+	image, err := c.download(ctx, avatarURL)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]string{"image": base64.StdEncoding.EncodeToString(image)}
+	status, body, err := c.forgejoRequest(ctx, "POST", "/orgs/"+url.PathEscape(org)+"/avatar", payload)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent && status != http.StatusOK {
+		return fmt.Errorf("avatar upload failed with status %d: %s", status, string(body))
+	}
+	return nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// githubRegistry is the GitHub container registry
+const githubRegistry = "https://ghcr.io"
+
+// manifestMediaTypes are the image manifest formats read from and written to
+// the registries, multi-platform indexes first
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// challengePattern matches the parameters of a WWW-Authenticate header
+var challengePattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// imageManifest is the part of an image manifest or index that references
+// other content
+type imageManifest struct {
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+}
+
+// registry is a container registry speaking the OCI distribution API, logged
+// in to one image at a time
+type registry struct {
+	name     string // for error messages
+	client   *http.Client
+	base     string
+	user     string
+	password string
+	token    string
+}
+
+// CopyPackages copies the tagged container images GitHub stores for a
+// repository, those published to ghcr.io and connected to it, to the
+// container registry of the Forgejo owner of its mirror. Tags that already
+// point at the same image on Forgejo are skipped.
+func (c *Client) CopyPackages(ctx context.Context, repo *GitHubRepo) error {
+	packages, err := c.containerPackages(ctx, repo)
+	if err != nil || len(packages) == 0 {
+		return err
+	}
+	user, token, err := c.migrationCredentials(repo)
+	if err != nil {
+		return fmt.Errorf("failed to get GitHub credentials: %w", err)
+	}
+	forgejoURL, err := url.Parse(c.config.ForgejoURL)
+	if err != nil {
+		return fmt.Errorf("invalid Forgejo URL: %w", err)
+	}
+
+	// Layers take longer than the client's usual timeout, the context still
+	// bounds the transfer
+	transfer := &http.Client{Transport: c.httpClient.Transport}
+	source := &registry{name: "ghcr.io", client: transfer, base: githubRegistry, user: user, password: token}
+	target := &registry{name: "Forgejo registry", client: transfer, base: forgejoURL.Scheme + "://" + forgejoURL.Host, user: c.config.ForgejoUser, password: c.config.ForgejoToken}
+	owner := c.ownerFor(repo)
+
+	for _, pkg := range packages {
+		tags, err := c.containerTags(ctx, repo, pkg.GetName())
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would copy %d tags of container image %s to Forgejo\n", len(tags), pkg.GetName())
+			continue
+		}
+
+		sourceName := strings.ToLower(repo.Owner + "/" + pkg.GetName())
+		targetName := strings.ToLower(owner + "/" + pkg.GetName())
+		if err := source.login(ctx, "repository:"+sourceName+":pull"); err != nil {
+			return fmt.Errorf("failed to log in to ghcr.io: %w", err)
+		}
+		if err := target.login(ctx, "repository:"+targetName+":pull,push"); err != nil {
+			return fmt.Errorf("failed to log in to the Forgejo container registry: %w", err)
+		}
+		copied := 0
+		for _, tag := range tags {
+			ok, err := copyImage(ctx, source, target, sourceName, targetName, tag)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s:%s: %w", pkg.GetName(), tag, err)
+			}
+			if ok {
+				copied++
+			}
+		}
+		if c.config.Verbose && copied > 0 {
+			fmt.Printf("🐳 Copied %d tags of container image %s to Forgejo\n", copied, pkg.GetName())
+		}
+	}
+	return nil
+}
+
+// containerPackages lists the container images of the GitHub owner of a
+// repository that are connected to it
+func (c *Client) containerPackages(ctx context.Context, repo *GitHubRepo) ([]*github.Package, error) {
+	gh := c.githubFor(repo)
+	opts := &github.PackageListOptions{PackageType: github.String("container"), ListOptions: github.ListOptions{PerPage: 100}}
+	isOrg := true
+	var packages []*github.Package
+	for {
+		var list []*github.Package
+		var resp *github.Response
+		var err error
+		if isOrg {
+			list, resp, err = gh.Organizations.ListPackages(ctx, repo.Owner, opts)
+		} else {
+			list, resp, err = gh.Users.ListPackages(ctx, repo.Owner, opts)
+		}
+		if err != nil {
+			err = githubError("listing container packages of "+repo.Owner, err)
+			// Users aren't organizations
+			var notFound *NotFoundError
+			if isOrg && errors.As(err, &notFound) {
+				isOrg = false
+				continue
+			}
+			return nil, err
+		}
+		for _, pkg := range list {
+			if strings.EqualFold(pkg.GetRepository().GetName(), repo.githubName()) {
+				packages = append(packages, pkg)
+			}
+		}
+		if resp.NextPage == 0 {
+			return packages, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// containerTags lists the tags of a container image on GitHub. Untagged
+// versions are only reachable by digest and aren't copied.
+func (c *Client) containerTags(ctx context.Context, repo *GitHubRepo, name string) ([]string, error) {
+	gh := c.githubFor(repo)
+	opts := &github.PackageListOptions{State: github.String("active"), ListOptions: github.ListOptions{PerPage: 100}}
+	isOrg := true
+	var tags []string
+	for {
+		var versions []*github.PackageVersion
+		var resp *github.Response
+		var err error
+		if isOrg {
+			versions, resp, err = gh.Organizations.PackageGetAllVersions(ctx, repo.Owner, "container", url.PathEscape(name), opts)
+		} else {
+			versions, resp, err = gh.Users.PackageGetAllVersions(ctx, repo.Owner, "container", url.PathEscape(name), opts)
+		}
+		if err != nil {
+			err = githubError("listing versions of container image "+name, err)
+			var notFound *NotFoundError
+			if isOrg && errors.As(err, &notFound) {
+				isOrg = false
+				continue
+			}
+			return nil, err
+		}
+		for _, version := range versions {
+			if metadata := version.GetMetadata(); metadata != nil && metadata.Container != nil {
+				tags = append(tags, metadata.Container.Tags...)
+			}
+		}
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// copyImage copies a tagged image with everything it references. It reports
+// false if the tag already points at the same image on the target.
+func copyImage(ctx context.Context, source, target *registry, sourceName, targetName, tag string) (bool, error) {
+	manifest, mediaType, digest, err := source.manifest(ctx, sourceName, tag)
+	if err != nil {
+		return false, err
+	}
+	resp, err := target.do(ctx, "HEAD", "/v2/"+targetName+"/manifests/"+tag, nil, -1, manifestMediaTypes...)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("Docker-Content-Digest") == digest {
+		return false, nil
+	}
+	return true, copyManifest(ctx, source, target, sourceName, targetName, tag, manifest, mediaType)
+}
+
+// copyManifest copies the images of an index or the blobs of an image, then
+// the manifest itself
+func copyManifest(ctx context.Context, source, target *registry, sourceName, targetName, reference string, manifest []byte, mediaType string) error {
+	var parsed imageManifest
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return fmt.Errorf("failed to decode manifest %s: %w", reference, err)
+	}
+	for _, child := range parsed.Manifests {
+		content, childType, _, err := source.manifest(ctx, sourceName, child.Digest)
+		if err != nil {
+			return err
+		}
+		if err := copyManifest(ctx, source, target, sourceName, targetName, child.Digest, content, childType); err != nil {
+			return err
+		}
+	}
+	var blobs []string
+	if parsed.Config != nil {
+		blobs = append(blobs, parsed.Config.Digest)
+	}
+	for _, layer := range parsed.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+	for _, digest := range blobs {
+		if err := copyBlob(ctx, source, target, sourceName, targetName, digest); err != nil {
+			return err
+		}
+	}
+
+	resp, err := target.do(ctx, "PUT", "/v2/"+targetName+"/manifests/"+reference, bytes.NewReader(manifest), int64(len(manifest)), mediaType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return target.error("uploading manifest "+reference, resp)
+	}
+	return nil
+}
+
+// copyBlob streams a layer or image configuration to the target unless it
+// already has it
+func copyBlob(ctx context.Context, source, target *registry, sourceName, targetName, digest string) error {
+	resp, err := target.do(ctx, "HEAD", "/v2/"+targetName+"/blobs/"+digest, nil, -1)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	blob, err := source.do(ctx, "GET", "/v2/"+sourceName+"/blobs/"+digest, nil, -1)
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+	if blob.StatusCode != http.StatusOK {
+		return source.error("downloading blob "+digest, blob)
+	}
+
+	upload, err := target.do(ctx, "POST", "/v2/"+targetName+"/blobs/uploads/", nil, 0)
+	if err != nil {
+		return err
+	}
+	upload.Body.Close()
+	if upload.StatusCode != http.StatusAccepted {
+		return target.error("starting upload of blob "+digest, upload)
+	}
+	location, err := upload.Location()
+	if err != nil {
+		return fmt.Errorf("upload of blob %s has no location: %w", digest, err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = target.do(ctx, "PUT", location.String(), blob.Body, blob.ContentLength, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return target.error("uploading blob "+digest, resp)
+	}
+	return nil
+}
+
+// login fetches a bearer token for scope, if the registry asks for one
+func (r *registry) login(ctx context.Context, scope string) error {
+	r.token = ""
+	resp, err := r.do(ctx, "GET", "/v2/", nil, -1)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, m := range challengePattern.FindAllStringSubmatch(resp.Header.Get("WWW-Authenticate"), -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("registry asks for unsupported authentication %q", resp.Header.Get("WWW-Authenticate"))
+	}
+	query := url.Values{"scope": {scope}}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.user, r.password)
+	req.Header.Set("User-Agent", userAgent)
+	resp, err = r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return r.error("logging in", resp)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	return nil
+}
+
+// manifest fetches a manifest by tag or digest and returns it with its media
+// type and digest
+func (r *registry) manifest(ctx context.Context, name, reference string) ([]byte, string, string, error) {
+	resp, err := r.do(ctx, "GET", "/v2/"+name+"/manifests/"+reference, nil, -1, manifestMediaTypes...)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", r.error("fetching manifest "+reference, resp)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read manifest %s: %w", reference, err)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(content)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return content, resp.Header.Get("Content-Type"), digest, nil
+}
+
+// do sends a request to the registry. path is relative to its base URL unless
+// it's absolute, like the upload locations registries hand out. size is the
+// length of body, -1 if unknown. mediaTypes are the accepted types of the
+// response, or for PUT requests the type of body.
+func (r *registry) do(ctx context.Context, method, path string, body io.Reader, size int64, mediaTypes ...string) (*http.Response, error) {
+	target := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		target = r.base + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	switch {
+	case method == "PUT" && len(mediaTypes) > 0:
+		req.Header.Set("Content-Type", mediaTypes[0])
+	case len(mediaTypes) > 0:
+		req.Header.Set("Accept", strings.Join(mediaTypes, ", "))
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return r.client.Do(req)
+}
+
+// error describes a failed registry request
+func (r *registry) error(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &APIError{Service: r.name, Op: op, Status: resp.StatusCode, Excerpt: excerptOf(body)}
+}
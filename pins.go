@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// pinnedIssuesQuery lists the issues pinned to a repository. The REST API
+// doesn't expose them, and GitHub allows at most three.
+const pinnedIssuesQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    pinnedIssues(first: 3) {
+      nodes { issue { number } }
+    }
+  }
+}`
+
+// PinIssues pins the Forgejo issues of the issues pinned on GitHub, in the
+// same order. Issues pinned on Forgejo stay pinned, so pins made there aren't
+// lost. Mirrors have no issues and are left alone.
+func (c *Client) PinIssues(ctx context.Context, repo *GitHubRepo) error {
+	if !repo.HasIssues {
+		return nil
+	}
+	var data struct {
+		Repository struct {
+			PinnedIssues struct {
+				Nodes []struct {
+					Issue struct {
+						Number int `json:"number"`
+					} `json:"issue"`
+				} `json:"nodes"`
+			} `json:"pinnedIssues"`
+		} `json:"repository"`
+	}
+	variables := map[string]interface{}{"owner": repo.Owner, "name": repo.githubName()}
+	if err := c.githubGraphQL(ctx, repo, "listing pinned issues of "+repo.FullName, pinnedIssuesQuery, variables, &data); err != nil {
+		return err
+	}
+	pins := data.Repository.PinnedIssues.Nodes
+	if len(pins) == 0 {
+		return nil
+	}
+
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Mirror {
+		return err
+	}
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, repo.Name, "issues", "pinned"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list pinned issues: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("listing pinned issues of "+repo.Name, status, body)
+	}
+	var pinned []forgejoIssue
+	if err := json.Unmarshal(body, &pinned); err != nil {
+		return fmt.Errorf("failed to decode pinned issues: %w", err)
+	}
+	isPinned := make(map[int64]bool)
+	for _, issue := range pinned {
+		isPinned[issue.Index] = true
+	}
+
+	var synced *IssueSyncState
+	if c.state != nil {
+		synced = c.state.IssueSync(repo.FullName, c.name)
+	}
+	for _, pin := range pins {
+		index := synced.index(pin.Issue.Number)
+		if isPinned[index] {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would pin issue #%d of %s\n", index, repo.Name)
+			continue
+		}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "issues", strconv.FormatInt(index, 10), "pin"), nil)
+		if err != nil {
+			return fmt.Errorf("failed to pin issue #%d: %w", index, err)
+		}
+		if status != http.StatusNoContent {
+			return forgejoError(fmt.Sprintf("pinning issue #%d of %s", index, repo.Name), status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("📌 Pinned issue #%d of %s\n", index, repo.Name)
+		}
+	}
+	return nil
+}
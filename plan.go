@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlanAction is a change a run would make on a Forgejo target
+type PlanAction struct {
+	Action string `json:"action"` // create, recreate, update, sync, rename, archive or delete
+	Target string `json:"target"`
+	Repo   string `json:"repo,omitempty"` // GitHub full name, empty for orphans and duplicates
+	Mirror string `json:"mirror"`         // Forgejo full name
+	// Former GitHub full name of a repository renamed since it was mirrored
+	Previous string          `json:"previous,omitempty"`
+	Note     string          `json:"note,omitempty"`
+	Changes  []SettingChange `json:"changes,omitempty"`
+}
+
+// SettingChange is a repository setting that differs between Forgejo and
+// what the run would set
+type SettingChange struct {
+	Setting string `json:"setting"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// planOrder lists the actions in the order the plan summary counts them
+var planOrder = []string{"create", "recreate", "update", "sync", "rename", "archive", "delete"}
+
+// Plan collects the actions of a dry run on one target
+type Plan struct {
+	mu      sync.Mutex
+	Actions []PlanAction `json:"actions"`
+}
+
+func newPlan() *Plan {
+	return &Plan{}
+}
+
+func (p *Plan) add(action PlanAction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Actions = append(p.Actions, action)
+}
+
+// Print shows the planned actions sorted by mirror, followed by a count per action
+func (p *Plan) Print(title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fmt.Printf("\n📋 %s:\n", title)
+	if len(p.Actions) == 0 {
+		fmt.Println("   No changes, the mirrors match GitHub")
+		return
+	}
+	// The recorded order is kept for apply: moving a duplicate aside or renaming
+	// a mirror has to happen before the changes planned for its new name
+	actions := append([]PlanAction(nil), p.Actions...)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return strings.ToLower(actions[i].Mirror) < strings.ToLower(actions[j].Mirror)
+	})
+
+	counts := make(map[string]int)
+	for _, action := range actions {
+		counts[action.Action]++
+		subject := action.Mirror
+		if action.Repo != "" {
+			subject = action.Repo + " → " + action.Mirror
+		}
+		if action.Note != "" {
+			subject += " (" + action.Note + ")"
+		}
+		fmt.Printf("   %s %-8s %s\n", planSymbol(action.Action), action.Action, subject)
+		for _, change := range action.Changes {
+			fmt.Printf("        %s: %s → %s\n", change.Setting, change.From, change.To)
+		}
+	}
+
+	var totals []string
+	for _, action := range planOrder {
+		if counts[action] > 0 {
+			totals = append(totals, fmt.Sprintf("%d to %s", counts[action], action))
+		}
+	}
+	fmt.Printf("\n   Plan: %s\n", strings.Join(totals, ", "))
+}
+
+// planSymbol returns the terraform-style marker of an action
+func planSymbol(action string) string {
+	switch action {
+	case "create":
+		return "+"
+	case "recreate":
+		return "±"
+	case "delete":
+		return "-"
+	case "sync":
+		return "↻"
+	}
+	return "~"
+}
+
+// planRepo records what migrating a repository would do on this target and
+// returns the result the run would have. It stands in for MigrateRepo in a
+// dry run.
+func (c *Client) planRepo(ctx context.Context, repo *GitHubRepo) (Result, error) {
+	action := PlanAction{Target: c.name, Repo: repo.FullName, Mirror: c.ownerFor(repo) + "/" + repo.Name}
+
+	// Planned renames haven't happened, so the mirror is still at its old
+	// place, and a duplicate that got this far would be moved out of the way
+	owner, name := c.ownerFor(repo), repo.Name
+	if old, ok := c.renames[repo.FullName]; ok {
+		owner, name = old.owner, old.name
+	}
+	var current map[string]interface{}
+	if c.duplicate(repo) == nil {
+		var err error
+		if current, err = c.getRepoFields(ctx, owner, name); err != nil {
+			return ResultFailed, err
+		}
+	}
+
+	switch {
+	case current == nil:
+		action.Action = "create"
+		action.Note = c.createNote(repo)
+		c.plan.add(action)
+		return ResultCreated, nil
+	case c.config.Recreate:
+		action.Action = "recreate"
+		action.Note = c.createNote(repo)
+		c.plan.add(action)
+		return ResultCreated, nil
+	case c.config.OnExists == "skip":
+		return ResultAlreadyExists, nil
+	}
+
+	archived, _ := current["archived"].(bool)
+	if mirror, _ := current["mirror"].(bool); mirror && !archived && c.config.FixRemotes {
+		remote, _ := current["original_url"].(string)
+		if reason := c.remoteMismatch(repo, remote); reason != "" {
+			action.Action = "recreate"
+			action.Note = reason
+			c.plan.add(action)
+			return ResultCreated, nil
+		}
+	}
+
+	desired := c.desiredSettings(ctx, repo, c.config.OnExists == "update-settings")
+	if archived {
+		if repo.Archived || !c.config.Unarchive {
+			// Archived repositories are left as they are
+			return ResultAlreadyExists, nil
+		}
+		unarchived := false
+		desired.Archived = &unarchived
+	} else if repo.Archived {
+		desired.Archived = &repo.Archived
+	}
+	action.Changes = settingChanges(desired, current)
+
+	result := ResultAlreadyExists
+	if mirror, _ := current["mirror"].(bool); mirror && c.config.OnExists == "sync" && c.pushedSinceSync(repo) {
+		action.Action = "sync"
+		result = ResultSynced
+	} else if len(action.Changes) > 0 {
+		action.Action = "update"
+	} else {
+		return result, nil
+	}
+	c.plan.add(action)
+	return result, nil
+}
+
+// createNote describes the repository a migration would create
+func (c *Client) createNote(repo *GitHubRepo) string {
+	var details []string
+	if c.config.Mode == "mirror" {
+		interval := c.mirrorIntervalFor(repo)
+		if interval == "" {
+			interval = "8h" // Forgejo's default
+		}
+		details = append(details, "mirror, syncs every "+interval)
+	} else {
+		details = append(details, "one-time migration")
+	}
+	if repo.Private {
+		details = append(details, "private")
+	}
+	if repo.Archived {
+		details = append(details, "archived")
+	}
+	return strings.Join(details, ", ")
+}
+
+// getRepoFields returns the settings of a Forgejo repository as decoded JSON,
+// keyed like EditRepoOption, or nil if it does not exist
+func (c *Client) getRepoFields(ctx context.Context, owner, name string) (map[string]interface{}, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Forgejo repo %s: %w", name, err)
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, forgejoError("fetching repository "+name, status, body)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode Forgejo repo %s: %w", name, err)
+	}
+	return fields, nil
+}
+
+// settingChanges lists the settings in desired that differ from the current
+// settings of a Forgejo repository
+func settingChanges(desired *EditRepoOption, current map[string]interface{}) []SettingChange {
+	data, err := json.Marshal(desired)
+	if err != nil {
+		return nil
+	}
+	var want map[string]interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		return nil
+	}
+
+	var changes []SettingChange
+	for setting, value := range want {
+		if sameSetting(setting, current[setting], value) {
+			continue
+		}
+		changes = append(changes, SettingChange{Setting: setting, From: formatSetting(current[setting]), To: formatSetting(value)})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Setting < changes[j].Setting })
+	return changes
+}
+
+// sameSetting compares two decoded setting values. Forgejo reports mirror
+// intervals normalised, e.g. "8h0m0s" for "8h".
+func sameSetting(setting string, current, desired interface{}) bool {
+	if setting == "mirror_interval" {
+		have, err1 := time.ParseDuration(fmt.Sprint(current))
+		want, err2 := time.ParseDuration(fmt.Sprint(desired))
+		if err1 == nil && err2 == nil {
+			return have == want
+		}
+	}
+	return fmt.Sprint(current) == fmt.Sprint(desired)
+}
+
+func formatSetting(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "(unset)"
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprint(value)
+}
+
+// SavedPlan is the plan file written by the plan command and executed by apply
+type SavedPlan struct {
+	Version   string       `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Targets   []string     `json:"targets"`
+	Actions   []PlanAction `json:"actions"`
+}
+
+// writePlanFile saves the planned actions of all targets
+func writePlanFile(path string, targets []*Client, now time.Time) error {
+	saved := SavedPlan{Version: version, CreatedAt: now, Actions: []PlanAction{}}
+	for _, target := range targets {
+		saved.Targets = append(saved.Targets, target.name)
+		target.plan.mu.Lock()
+		saved.Actions = append(saved.Actions, target.plan.Actions...)
+		target.plan.mu.Unlock()
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// readPlanFile loads a plan written by writePlanFile
+func readPlanFile(path string) (*SavedPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var saved SavedPlan
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode plan: %w", err)
+	}
+	return &saved, nil
+}
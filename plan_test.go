@@ -0,0 +1,54 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSettingChanges(t *testing.T) {
+	str := func(s string) *string { return &s }
+	yes, no := true, false
+
+	tests := []struct {
+		name    string
+		desired *EditRepoOption
+		current map[string]interface{}
+		want    []SettingChange
+	}{
+		{
+			"unchanged",
+			&EditRepoOption{Description: str("API"), Private: &yes},
+			map[string]interface{}{"description": "API", "private": true, "stars_count": 3.0},
+			nil,
+		},
+		{
+			"changed, sorted by setting",
+			&EditRepoOption{Private: &no, Description: str("New"), HasWiki: &no},
+			map[string]interface{}{"description": "Old", "private": true, "has_wiki": false},
+			[]SettingChange{{"description", `"Old"`, `"New"`}, {"private", "true", "false"}},
+		},
+		{
+			"not reported by Forgejo",
+			&EditRepoOption{DefaultMergeStyle: str("squash")},
+			map[string]interface{}{},
+			[]SettingChange{{"default_merge_style", "(unset)", `"squash"`}},
+		},
+		{
+			"normalised mirror interval",
+			&EditRepoOption{MirrorInterval: str("8h")},
+			map[string]interface{}{"mirror_interval": "8h0m0s"},
+			nil,
+		},
+		{
+			"mirror interval",
+			&EditRepoOption{MirrorInterval: str("1h")},
+			map[string]interface{}{"mirror_interval": "8h0m0s"},
+			[]SettingChange{{"mirror_interval", `"8h0m0s"`, `"1h"`}},
+		},
+	}
+	for _, tt := range tests {
+		if got := settingChanges(tt.desired, tt.current); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: settingChanges = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// forEach calls fn for every item on a pool of n workers and returns once all
+// calls have finished. After ctx is cancelled no further items are started;
+// skipped, if set, is called for each of them once all workers are done.
+func forEach[T any](ctx context.Context, n int, items []T, fn func(int, T), skipped func(int, T)) {
+	type job struct {
+		index int
+		item  T
+	}
+	jobs := make(chan job)
+
+	var wg sync.WaitGroup
+	for range max(n, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				fn(j.index, j.item)
+			}
+		}()
+	}
+
+	next := 0
+	for ; next < len(items) && ctx.Err() == nil; next++ {
+		select {
+		case jobs <- job{index: next, item: items[next]}:
+		case <-ctx.Done():
+			next-- // Not handed to a worker
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if skipped != nil {
+		for i := next; i < len(items); i++ {
+			skipped(i, items[i])
+		}
+	}
+}
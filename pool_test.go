@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		workers     int
+		wantDone    int
+		wantSkipped int
+	}{
+		{"one worker", context.Background(), 1, 5, 0},
+		{"more workers than items", context.Background(), 8, 5, 0},
+		{"no workers configured", context.Background(), 0, 5, 0},
+		{"cancelled", cancelled, 2, 0, 5},
+	}
+	for _, tt := range tests {
+		var mu sync.Mutex
+		seen := make([]bool, len(items))
+		var done, skipped int
+		forEach(tt.ctx, tt.workers, items, func(i int, item string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if items[i] != item || seen[i] {
+				t.Errorf("%s: item %d handed out twice or as %q", tt.name, i, item)
+			}
+			seen[i] = true
+			done++
+		}, func(i int, item string) {
+			if seen[i] {
+				t.Errorf("%s: item %d skipped after it was processed", tt.name, i)
+			}
+			seen[i] = true
+			skipped++
+		})
+		if done != tt.wantDone || skipped != tt.wantSkipped || slices.Contains(seen, false) {
+			t.Errorf("%s: %d processed and %d skipped (all seen: %v), want %d and %d", tt.name, done, skipped, !slices.Contains(seen, false), tt.wantDone, tt.wantSkipped)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// pullRequestLabel marks the Forgejo issues --import-pulls creates for GitHub
+// pull requests
+var pullRequestLabel = forgejoLabel{Name: "pull request", Color: "#8250df", Description: "Pull request imported from GitHub"}
+
+// ImportPulls copies the issues and pull requests of a GitHub repository to
+// its Forgejo repository after converting it from a mirror in place. Forgejo
+// can't create merged or closed pull requests, so each pull request becomes an
+// issue labeled "pull request" that records where it was merged, with its
+// changes attached as a patch. Everything is created in the order of the
+// GitHub numbers, and numbers that are neither, such as discussions, get a
+// closed placeholder, so #123 means the same on both. The state file records
+// the numbers for --sync-issues and --rewrite-references.
+func (c *Client) ImportPulls(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	items, err := c.githubIssues(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if c.config.DryRun {
+		pulls := slices.DeleteFunc(slices.Clone(items), func(item *github.Issue) bool { return !item.IsPullRequest() })
+		fmt.Printf("[DRY RUN] Would import %d issues and %d pull requests of %s\n", len(items)-len(pulls), len(pulls), repo.Name)
+		return nil
+	}
+
+	// The numbers only line up on a repository without issues
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, repo.Name, "issues")+"?state=all&limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("failed to list issues: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("listing issues of "+repo.Name, status, body)
+	}
+	var existing []forgejoIssue
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return fmt.Errorf("failed to decode issues: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%s already has issues on Forgejo, so GitHub's numbers can't be kept", repo.Name)
+	}
+
+	labelID, err := c.ensureLabel(ctx, owner, repo, pullRequestLabel)
+	if err != nil {
+		return err
+	}
+	milestones, err := c.milestoneIDs(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	synced := (&IssueSyncState{}).clone()
+	next := 1
+	var pulls int
+	for _, item := range items {
+		for ; next < item.GetNumber(); next++ {
+			if err := c.importGap(ctx, owner, repo, next); err != nil {
+				return err
+			}
+		}
+		next = item.GetNumber() + 1
+
+		var index int64
+		if item.IsPullRequest() {
+			pulls++
+			index, err = c.importPull(ctx, owner, repo, item, labelID, synced)
+		} else {
+			index, _, err = c.syncIssue(ctx, owner, repo, item, synced, false)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.syncActivity(ctx, owner, repo, item, index, milestones, synced); err != nil {
+			return err
+		}
+		// Saved after every issue, so --sync-issues carries on with the rest
+		// if the import fails
+		c.state.SetIssueSync(repo, c.name, synced)
+	}
+	synced.Since = now
+	c.state.SetIssueSync(repo, c.name, synced)
+	fmt.Printf("📝 Imported %d issues and %d pull requests of %s\n", len(items)-pulls, pulls, repo.Name)
+	return nil
+}
+
+// githubIssues lists all issues and pull requests of a GitHub repository by
+// number. Transferred issues are numbered when they arrive, so the order of
+// creation differs.
+func (c *Client) githubIssues(ctx context.Context, repo *GitHubRepo) ([]*github.Issue, error) {
+	var issues []*github.Issue
+	opts := &github.IssueListByRepoOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := c.githubFor(repo).Issues.ListByRepo(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return nil, githubError("listing issues of "+repo.FullName, err)
+		}
+		issues = append(issues, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	slices.SortFunc(issues, func(a, b *github.Issue) int { return a.GetNumber() - b.GetNumber() })
+	return issues, nil
+}
+
+// importPull creates the Forgejo issue of a GitHub pull request and returns
+// its index
+func (c *Client) importPull(ctx context.Context, owner string, repo *GitHubRepo, item *github.Issue, labelID int64, synced *IssueSyncState) (int64, error) {
+	number := item.GetNumber()
+	pull, _, err := c.githubFor(repo).PullRequests.Get(ctx, repo.Owner, repo.githubName(), number)
+	if err != nil {
+		return 0, githubError(fmt.Sprintf("getting pull request %s#%d", repo.FullName, number), err)
+	}
+	// GitHub refuses patches of very large pull requests
+	patch, _, err := c.githubFor(repo).PullRequests.GetRaw(ctx, repo.Owner, repo.githubName(), number, github.RawOptions{Type: github.Patch})
+	if err != nil {
+		fmt.Printf("⚠️  Couldn't get the changes of %s#%d: %v\n", repo.FullName, number, githubError("getting patch", err))
+	}
+	name := fmt.Sprintf("pull-%d.patch", number)
+
+	author, body := c.authored(item.GetUser().GetLogin(), item.GetHTMLURL(), item.GetCreatedAt().Time, c.references(repo).rewrite(item.GetBody()))
+	body = strings.TrimRight(body, "\n") + "\n\n---\n" + pullSummary(pull)
+	if patch != "" {
+		body += fmt.Sprintf(" The changes are attached as %s.", name)
+	}
+	payload := map[string]interface{}{"title": item.GetTitle(), "body": body, "closed": item.GetState() == "closed", "labels": []int64{labelID}}
+	status, respBody, err := c.forgejoRequestAs(ctx, author, "POST", repoPath(owner, repo.Name, "issues"), payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create issue: %w", err)
+	}
+	if status != http.StatusCreated {
+		return 0, forgejoError(fmt.Sprintf("creating issue for %s#%d", repo.FullName, number), status, respBody)
+	}
+	var created forgejoIssue
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return 0, fmt.Errorf("failed to decode issue: %w", err)
+	}
+	synced.Issues[number] = created.Index
+	index := strconv.FormatInt(created.Index, 10)
+
+	if patch != "" {
+		status, respBody, err := c.forgejoUpload(ctx, repoPath(owner, repo.Name, "issues", index, "assets")+"?name="+url.QueryEscape(name), "attachment", name, strings.NewReader(patch))
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+		if status != http.StatusCreated {
+			return 0, forgejoError("uploading "+name+" of "+repo.Name, status, respBody)
+		}
+	}
+	return created.Index, c.copyAttachments(ctx, owner, repo, body, "issues", index)
+}
+
+// pullSummary describes the branches of a GitHub pull request and how it was
+// closed
+func pullSummary(pull *github.PullRequest) string {
+	branches := fmt.Sprintf("from `%s` into `%s`", pull.GetHead().GetLabel(), pull.GetBase().GetRef())
+	switch {
+	case pull.GetMerged():
+		sha := pull.GetMergeCommitSHA()
+		if len(sha) > 10 {
+			sha = sha[:10]
+		}
+		return fmt.Sprintf("Pull request %s, merged by @%s on %s as %s.", branches, pull.GetMergedBy().GetLogin(), pull.GetMergedAt().UTC().Format("2006-01-02 15:04 MST"), sha)
+	case pull.GetState() == "closed":
+		return fmt.Sprintf("Pull request %s, closed without merging on %s.", branches, pull.GetClosedAt().UTC().Format("2006-01-02 15:04 MST"))
+	}
+	return fmt.Sprintf("Pull request %s, still open on GitHub.", branches)
+}
+
+// importGap creates a closed placeholder issue for a GitHub number that isn't
+// an issue or pull request, so the ones after it keep their numbers
+func (c *Client) importGap(ctx context.Context, owner string, repo *GitHubRepo, number int) error {
+	payload := map[string]interface{}{
+		"title":  fmt.Sprintf("#%d on GitHub", number),
+		"body":   fmt.Sprintf("Keeps GitHub's numbering: [#%d](https://github.com/%s/issues/%d) is a discussion there, or an issue that was deleted or transferred.", number, repo.FullName, number),
+		"closed": true,
+	}
+	status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "issues"), payload)
+	if err != nil {
+		return fmt.Errorf("failed to create placeholder for #%d: %w", number, err)
+	}
+	if status != http.StatusCreated {
+		return forgejoError(fmt.Sprintf("creating a placeholder for %s#%d", repo.FullName, number), status, body)
+	}
+	return nil
+}
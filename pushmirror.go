@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// defaultPushInterval is Forgejo's own default for push mirrors. The API
+// rejects push mirrors without an interval, so it is sent explicitly when
+// --mirror-interval isn't set.
+const defaultPushInterval = "8h0m0s"
+
+// PushMirror is a Forgejo push mirror configuration
+type PushMirror struct {
+	RemoteName    string `json:"remote_name,omitempty"`
+	RemoteAddress string `json:"remote_address"`
+	RemoteUser    string `json:"remote_username,omitempty"`
+	RemotePass    string `json:"remote_password,omitempty"`
+	Interval      string `json:"interval"`
+	SyncOnCommit  bool   `json:"sync_on_commit"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// GetPushMirrors lists the push mirrors of a Forgejo repository
+func (c *Client) GetPushMirrors(ctx context.Context, owner, name string) ([]*PushMirror, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, name, "push_mirrors"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push mirrors: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var mirrors []*PushMirror
+	if err := json.Unmarshal(body, &mirrors); err != nil {
+		return nil, fmt.Errorf("failed to decode push mirrors: %w", err)
+	}
+	return mirrors, nil
+}
+
+// EnsurePushMirror configures a Forgejo push mirror that pushes the repository
+// back to GitHub, unless one pointing at the same address already exists
+func (c *Client) EnsurePushMirror(ctx context.Context, repo *GitHubRepo) (bool, error) {
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil {
+		return false, err
+	}
+	if forgejoRepo == nil {
+		return false, fmt.Errorf("repository does not exist on Forgejo")
+	}
+	if forgejoRepo.Mirror {
+		return false, fmt.Errorf("repository is a pull mirror of GitHub; convert it before pushing back")
+	}
+
+	mirrors, err := c.GetPushMirrors(ctx, owner, repo.Name)
+	if err != nil {
+		return false, err
+	}
+	for _, mirror := range mirrors {
+		if strings.EqualFold(strings.TrimSuffix(mirror.RemoteAddress, ".git"), strings.TrimSuffix(repo.CloneURL, ".git")) {
+			return false, nil
+		}
+	}
+
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would add push mirror: %s → %s\n", repo.Name, repo.CloneURL)
+		return true, nil
+	}
+
+	mirror := &PushMirror{
+		RemoteAddress: repo.CloneURL,
+		RemoteUser:    c.config.GitHubUser,
+		RemotePass:    c.config.GitHubToken,
+		Interval:      c.pushInterval(repo),
+		SyncOnCommit:  true,
+	}
+	status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "push_mirrors"), mirror)
+	if err != nil {
+		return false, fmt.Errorf("failed to add push mirror: %w", err)
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return false, forgejoError("adding push mirror", status, body)
+	}
+	fmt.Printf("⬆️  Added push mirror: %s → %s\n", repo.Name, repo.CloneURL)
+	return true, nil
+}
+
+// pushInterval returns the interval of the push mirror of a repository: its
+// mirror interval, or defaultPushInterval if none is configured
+func (c *Client) pushInterval(repo *GitHubRepo) string {
+	if interval := c.mirrorIntervalFor(repo); interval != "" {
+		return interval
+	}
+	return defaultPushInterval
+}
+
+// runPushMirror configures Forgejo push mirrors to GitHub for the selected
+// repositories and returns the exit code. Forgejo stores the GitHub
+// credentials with each push mirror, so they have to be a personal access
+// token: installation tokens of a GitHub App expire after an hour.
+func runPushMirror(ctx context.Context, config *Config, client *Client) int {
+	if config.GitHubAppID != 0 {
+		fmt.Println("❌ Push mirrors need a GitHub personal access token in GITHUB_TOKEN, they can't use the short-lived installation tokens of --github-app-id")
+		return 1
+	}
+	fmt.Printf("⬆️  Configuring push mirrors from %s to GitHub\n", config.ForgejoURL)
+	repos, err := client.GetGitHubRepos(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+	}
+	fmt.Printf("   Found %d repositories on GitHub\n\n", len(repos))
+
+	var added, existing, failed int
+	var mu sync.Mutex
+	forEach(ctx, config.Concurrent, repos, func(_ int, r *GitHubRepo) {
+		created, err := client.EnsurePushMirror(ctx, r)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			fmt.Printf("❌ %s: %v\n", r.Name, err)
+			failed++
+		case created:
+			added++
+		default:
+			existing++
+		}
+	}, func(_ int, r *GitHubRepo) {
+		fmt.Printf("❌ %s: not processed, the run was interrupted\n", r.Name)
+		failed++
+	})
+
+	fmt.Printf("\n📊 Push Mirror Summary:\n")
+	fmt.Printf("   Added: %d\n", added)
+	fmt.Printf("   Already configured: %d\n", existing)
+	fmt.Printf("   Failed: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestPushInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  string
+		intervals map[string]string
+		want      string
+	}{
+		{"nothing configured", "", nil, defaultPushInterval},
+		{"global interval", "1h", nil, "1h"},
+		{"per repository", "1h", map[string]string{"api": "10m"}, "10m"},
+		{"other repository", "", map[string]string{"web": "10m"}, defaultPushInterval},
+	}
+	for _, tt := range tests {
+		c := &Client{config: &Config{MirrorInterval: tt.interval, MirrorIntervals: tt.intervals}}
+		if got := c.pushInterval(&GitHubRepo{FullName: "acme/api", Name: "api"}); got != tt.want {
+			t.Errorf("%s: pushInterval = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
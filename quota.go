@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// quotaInfo is the storage usage and the quota rules of a Forgejo account
+type quotaInfo struct {
+	Used struct {
+		Size struct {
+			Repos struct {
+				Public  int64 `json:"public"`
+				Private int64 `json:"private"`
+			} `json:"repos"`
+			Git struct {
+				LFS int64 `json:"LFS"`
+			} `json:"git"`
+			Assets struct {
+				Attachments struct {
+					Issues   int64 `json:"issues"`
+					Releases int64 `json:"releases"`
+				} `json:"attachments"`
+				Artifacts int64 `json:"artifacts"`
+				Packages  struct {
+					All int64 `json:"all"`
+				} `json:"packages"`
+			} `json:"assets"`
+		} `json:"size"`
+	} `json:"used"`
+	Groups []struct {
+		Rules []struct {
+			Limit    int64    `json:"limit"`
+			Subjects []string `json:"subjects"`
+		} `json:"rules"`
+	} `json:"groups"`
+}
+
+// usage returns the bytes counted against a quota subject
+func (q *quotaInfo) usage(subject string) int64 {
+	size := q.Used.Size
+	repos := size.Repos.Public + size.Repos.Private
+	assets := size.Assets.Attachments.Issues + size.Assets.Attachments.Releases + size.Assets.Artifacts + size.Assets.Packages.All
+	switch subject {
+	case "size:all":
+		return repos + size.Git.LFS + assets
+	case "size:git:all":
+		return repos + size.Git.LFS
+	case "size:repos:all":
+		return repos
+	case "size:repos:public":
+		return size.Repos.Public
+	case "size:repos:private":
+		return size.Repos.Private
+	}
+	return 0
+}
+
+// remaining returns the bytes a new repository with the given visibility may
+// still use, or -1 if it is unlimited. An account may use the space of the
+// most generous group it belongs to.
+func (q *quotaInfo) remaining(private bool) int64 {
+	applies := map[string]bool{"size:all": true, "size:git:all": true, "size:repos:all": true}
+	if private {
+		applies["size:repos:private"] = true
+	} else {
+		applies["size:repos:public"] = true
+	}
+
+	best := int64(0)
+	for i, group := range q.Groups {
+		groupRemaining := int64(-1)
+		for _, rule := range group.Rules {
+			var used int64
+			relevant := false
+			for _, subject := range rule.Subjects {
+				used += q.usage(subject)
+				relevant = relevant || applies[subject]
+			}
+			if !relevant || rule.Limit < 0 {
+				continue
+			}
+			if left := max(rule.Limit-used, 0); groupRemaining < 0 || left < groupRemaining {
+				groupRemaining = left
+			}
+		}
+		if groupRemaining < 0 {
+			return -1
+		}
+		if i == 0 || groupRemaining > best {
+			best = groupRemaining
+		}
+	}
+	if len(q.Groups) == 0 {
+		return -1
+	}
+	return best
+}
+
+// quotaTracker keeps the quota of every Forgejo owner up to date while
+// repositories are migrated concurrently
+type quotaTracker struct {
+	mu        sync.Mutex
+	owners    map[string]*quotaInfo
+	projected int64
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{owners: make(map[string]*quotaInfo)}
+}
+
+// getQuota fetches the quota of a Forgejo user or organization. It returns nil
+// without an error if the instance has no quota API.
+func (c *Client) getQuota(ctx context.Context, owner string, isOrg bool) (*quotaInfo, error) {
+	path := "/user/quota"
+	switch {
+	case isOrg:
+		path = "/orgs/" + url.PathEscape(owner) + "/quota"
+	case !strings.EqualFold(owner, c.config.ForgejoUser) || c.config.ForgejoAdmin:
+		path = "/admin/users/" + url.PathEscape(owner) + "/quota"
+	}
+
+	status, body, err := c.forgejoRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var info quotaInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode quota: %w", err)
+	}
+	return &info, nil
+}
+
+// reserveQuota reports whether the mirror of a GitHub repository fits into the
+// storage quota of its Forgejo owner, and if so counts it against the quota.
+// Repositories that already exist on Forgejo always fit, and the usage Forgejo
+// reports already includes them.
+func (c *Client) reserveQuota(ctx context.Context, repo *GitHubRepo) bool {
+	owner, isOrg := c.resolveOwner(repo)
+	if _, ok := c.existing[strings.ToLower(c.ownerFor(repo)+"/"+repo.Name)]; ok {
+		return true
+	}
+	key := strings.ToLower(owner)
+
+	// Fetched without holding the lock, so other workers aren't held up
+	c.quota.mu.Lock()
+	_, loaded := c.quota.owners[key]
+	c.quota.mu.Unlock()
+	if !loaded {
+		info, err := c.getQuota(ctx, owner, isOrg)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch the storage quota of %s, not enforcing it: %v", owner, err)
+		}
+		c.quota.mu.Lock()
+		if _, loaded := c.quota.owners[key]; !loaded {
+			c.quota.owners[key] = info
+		}
+		c.quota.mu.Unlock()
+	}
+
+	if c.quota.reserve(key, repo) {
+		return true
+	}
+	// The Forgejo listing may predate a repository created since
+	existing, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	return err == nil && existing != nil
+}
+
+// reserve counts a new repository against the quota of its owner if it fits
+func (t *quotaTracker) reserve(owner string, repo *GitHubRepo) bool {
+	size := int64(repo.Size) * 1024 // GitHub reports kilobytes
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := t.owners[owner]
+	if info != nil {
+		if remaining := info.remaining(repo.Private); remaining >= 0 && size > remaining {
+			return false
+		}
+		if repo.Private {
+			info.Used.Size.Repos.Private += size
+		} else {
+			info.Used.Size.Repos.Public += size
+		}
+	}
+	t.projected += size
+	return true
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+// quotaRules are the rules of a quota group
+type quotaRules = []struct {
+	Limit    int64    `json:"limit"`
+	Subjects []string `json:"subjects"`
+}
+
+// quotaWith returns quota info with the given repository usage and one group
+// per list of rules
+func quotaWith(public, private int64, groups ...quotaRules) *quotaInfo {
+	info := &quotaInfo{}
+	info.Used.Size.Repos.Public = public
+	info.Used.Size.Repos.Private = private
+	for _, rules := range groups {
+		info.Groups = append(info.Groups, struct {
+			Rules quotaRules `json:"rules"`
+		}{Rules: rules})
+	}
+	return info
+}
+
+func TestQuotaRemaining(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    *quotaInfo
+		private bool
+		want    int64
+	}{
+		{"no groups", quotaWith(100, 0), false, -1},
+		{"all sizes", quotaWith(300, 200, quotaRules{{1000, []string{"size:all"}}}), false, 500},
+		{"used up", quotaWith(900, 200, quotaRules{{1000, []string{"size:all"}}}), false, 0},
+		{"private rule for a public repo", quotaWith(0, 200, quotaRules{{100, []string{"size:repos:private"}}}), false, -1},
+		{"private rule for a private repo", quotaWith(0, 50, quotaRules{{100, []string{"size:repos:private"}}}), true, 50},
+		{"unlimited rule", quotaWith(500, 0, quotaRules{{-1, []string{"size:all"}}}), false, -1},
+		{"tightest rule of a group", quotaWith(100, 100, quotaRules{{1000, []string{"size:all"}}, {300, []string{"size:repos:all"}}}), false, 100},
+		{"most generous group", quotaWith(100, 0, quotaRules{{200, []string{"size:all"}}}, quotaRules{{500, []string{"size:all"}}}), false, 400},
+		{"group without limits", quotaWith(100, 0, quotaRules{{200, []string{"size:all"}}}, quotaRules{}), false, -1},
+	}
+	for _, tt := range tests {
+		if got := tt.info.remaining(tt.private); got != tt.want {
+			t.Errorf("%s: remaining = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuotaReserve(t *testing.T) {
+	tracker := newQuotaTracker()
+	tracker.owners["acme"] = quotaWith(0, 0, quotaRules{{3 * 1024, []string{"size:all"}}})
+	tracker.owners["open"] = nil
+
+	steps := []struct {
+		owner string
+		size  int
+		want  bool
+	}{
+		{"acme", 2, true},
+		{"acme", 2, false}, // 2 of 3 KiB are reserved
+		{"acme", 1, true},
+		{"open", 1000, true}, // no quota API
+	}
+	for i, step := range steps {
+		if got := tracker.reserve(step.owner, &GitHubRepo{Size: step.size}); got != step.want {
+			t.Errorf("step %d: reserve(%s, %d KiB) = %v, want %v", i, step.owner, step.size, got, step.want)
+		}
+	}
+	if want := int64(1003 * 1024); tracker.projected != want {
+		t.Errorf("projected = %d, want %d", tracker.projected, want)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"golang.org/x/oauth2"
+)
+
+// rateLimitReserve is how many GitHub API requests are left unused before
+// pausing until the rate limit resets. go-github refuses to send requests
+// itself once a limit reports zero, and concurrent workers need some room.
+const rateLimitReserve = 10
+
+// newGitHubClient creates a GitHub client authenticated with ts that pauses
+// instead of failing when the rate limit of the token runs out
+func newGitHubClient(config *Config, ts oauth2.TokenSource) *github.Client {
+	httpClient := oauth2.NewClient(context.Background(), ts)
+	httpClient.Transport = &rateLimitTransport{
+		base:    httpClient.Transport,
+		maxWait: config.MaxWait,
+		windows: make(map[string]*rateWindow),
+	}
+	client := github.NewClient(httpClient)
+	client.UserAgent = userAgent
+	return client
+}
+
+// rateWindow is what GitHub last reported about one rate limit of a token
+type rateWindow struct {
+	remaining int
+	reset     time.Time
+	announced time.Time // reset time the pause was last announced for
+}
+
+// rateLimitTransport tracks the rate limit headers of GitHub responses. It
+// waits for the limit to reset when it is nearly used up and retries requests
+// rejected by GitHub's primary or secondary rate limits once.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	maxWait time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow // keyed by rate limit resource
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := rateResource(req.URL.Path)
+	if err := t.waitForReset(req.Context(), resource); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.update(resource, resp.Header)
+
+	wait, limited := rateLimited(resp)
+	if !limited || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	if err := t.pause(req.Context(), resource, time.Now().Add(wait), "GitHub rate limit exceeded"); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = t.base.RoundTrip(retry)
+	if err != nil {
+		return nil, err
+	}
+	t.update(resource, resp.Header)
+	return resp, nil
+}
+
+// rateResource returns the GitHub rate limit a request counts against
+func rateResource(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/search/"), strings.HasPrefix(path, "/api/v3/search/"):
+		return "search"
+	case strings.HasSuffix(path, "/graphql"):
+		return "graphql"
+	}
+	return "core"
+}
+
+// rateLimited reports whether GitHub rejected a response because of a rate
+// limit and how long it asks to wait
+func rateLimited(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// Secondary rate limits say how long to back off
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Minute, true
+	}
+	return time.Until(time.Unix(reset, 0)), true
+}
+
+// update records the rate limit headers of a response
+func (t *rateLimitTransport) update(resource string, header http.Header) {
+	remaining, err1 := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, err2 := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+	if name := header.Get("X-RateLimit-Resource"); name != "" {
+		resource = name
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	window, ok := t.windows[resource]
+	if !ok {
+		window = &rateWindow{}
+		t.windows[resource] = window
+	}
+	window.remaining = remaining
+	window.reset = time.Unix(reset, 0)
+}
+
+// waitForReset pauses until the rate limit resets if it is nearly used up
+func (t *rateLimitTransport) waitForReset(ctx context.Context, resource string) error {
+	t.mu.Lock()
+	window, ok := t.windows[resource]
+	if !ok || window.remaining > rateLimitReserve || time.Now().After(window.reset) {
+		t.mu.Unlock()
+		return nil
+	}
+	reset, remaining := window.reset, window.remaining
+	t.mu.Unlock()
+	return t.pause(ctx, resource, reset, fmt.Sprintf("GitHub %s rate limit almost used up (%d requests left)", resource, remaining))
+}
+
+// pause sleeps until the given time, announcing it once per rate limit
+// window. It fails if that is further away than --max-wait.
+func (t *rateLimitTransport) pause(ctx context.Context, resource string, until time.Time, why string) error {
+	wait := time.Until(until) + time.Second // GitHub's reset time has one second resolution
+	if t.maxWait > 0 && wait > t.maxWait {
+		return fmt.Errorf("%s, it resets in %s which is longer than --max-wait=%s", why, wait.Round(time.Second), t.maxWait)
+	}
+
+	t.mu.Lock()
+	window, ok := t.windows[resource]
+	if !ok {
+		window = &rateWindow{}
+		t.windows[resource] = window
+	}
+	if !window.announced.Equal(until) {
+		window.announced = until
+		fmt.Printf("⏳ %s, pausing for %s until %s\n", why, wait.Round(time.Second), until.Format("15:04:05"))
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// graphQLReactions maps the reaction contents of GitHub's GraphQL API to the
+// names Forgejo and GitHub's REST API use
+var graphQLReactions = map[string]string{
+	"THUMBS_UP":   "+1",
+	"THUMBS_DOWN": "-1",
+	"LAUGH":       "laugh",
+	"HOORAY":      "hooray",
+	"CONFUSED":    "confused",
+	"HEART":       "heart",
+	"ROCKET":      "rocket",
+	"EYES":        "eyes",
+}
+
+// reaction is a reaction on GitHub. user is the login of who reacted.
+type reaction struct {
+	user    string
+	content string
+}
+
+// graphQLReactionNodes are the reactions of a GraphQL query
+type graphQLReactionNodes struct {
+	Nodes []struct {
+		Content string `json:"content"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"nodes"`
+}
+
+func (r graphQLReactionNodes) reactions() []reaction {
+	var reactions []reaction
+	for _, node := range r.Nodes {
+		if content, ok := graphQLReactions[node.Content]; ok {
+			reactions = append(reactions, reaction{user: node.User.Login, content: content})
+		}
+	}
+	return reactions
+}
+
+// issueReactions lists the reactions on a GitHub issue
+func (c *Client) issueReactions(ctx context.Context, repo *GitHubRepo, number int) ([]reaction, error) {
+	var reactions []reaction
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Reactions.ListIssueReactions(ctx, repo.Owner, repo.githubName(), number, opts)
+		if err != nil {
+			return nil, githubError(fmt.Sprintf("listing reactions on %s#%d", repo.FullName, number), err)
+		}
+		for _, r := range list {
+			reactions = append(reactions, reaction{user: r.GetUser().GetLogin(), content: r.GetContent()})
+		}
+		if resp.NextPage == 0 {
+			return reactions, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// commentReactions lists the reactions on a comment on a GitHub issue
+func (c *Client) commentReactions(ctx context.Context, repo *GitHubRepo, id int64) ([]reaction, error) {
+	var reactions []reaction
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Reactions.ListIssueCommentReactions(ctx, repo.Owner, repo.githubName(), id, opts)
+		if err != nil {
+			return nil, githubError(fmt.Sprintf("listing reactions on comment %d of %s", id, repo.FullName), err)
+		}
+		for _, r := range list {
+			reactions = append(reactions, reaction{user: r.GetUser().GetLogin(), content: r.GetContent()})
+		}
+		if resp.NextPage == 0 {
+			return reactions, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// copyReactions adds reactions to a Forgejo issue or comment, given by the
+// path segments after the repository, e.g. "issues", "12". Reactions of users
+// in --user-map are added by their Forgejo account, all others by the account
+// of the token. Forgejo only counts one reaction of each kind per account.
+func (c *Client) copyReactions(ctx context.Context, owner string, repo *GitHubRepo, reactions []reaction, segments ...string) error {
+	added := make(map[reaction]bool)
+	for _, r := range reactions {
+		account, _ := matchRule(c.config.UserMap, r.user)
+		if r.user == "" {
+			account = ""
+		}
+		key := reaction{user: account, content: r.content}
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		status, body, err := c.forgejoRequestAs(ctx, account, "POST", repoPath(owner, repo.Name, append(segments, "reactions")...), map[string]string{"content": r.content})
+		if err != nil {
+			return fmt.Errorf("failed to add reaction: %w", err)
+		}
+		// 200 means the account already reacted this way
+		if status != http.StatusCreated && status != http.StatusOK {
+			return forgejoError("adding a "+r.content+" reaction on "+repo.Name, status, body)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// githubLinkPattern matches links to GitHub issues, pull requests and
+	// discussions, optionally to one of their comments
+	githubLinkPattern = regexp.MustCompile(`https://github\.com/([\w.-]+)/([\w.-]+)/(issues|pull|discussions)/(\d+)(?:#issuecomment-(\d+))?`)
+	// shortReferencePattern matches #123 references, but not HTML entities,
+	// URL fragments or words ending in a number sign
+	shortReferencePattern = regexp.MustCompile(`(^|[\s(\[])#(\d+)\b`)
+)
+
+// references rewrites links and references to the issues, pull requests and
+// discussions of a GitHub repository in migrated and synced text to their
+// Forgejo counterparts
+type references struct {
+	repo        *GitHubRepo
+	base        string // web URL of the Forgejo repository
+	issues      *IssueSyncState
+	discussions *IssueSyncState
+}
+
+// references returns the rewriter for the text of a repository, from the
+// numbers the state file recorded for synced issues and imported discussions.
+// It is nil unless --rewrite-references is set.
+func (c *Client) references(repo *GitHubRepo) *references {
+	if !c.config.RewriteReferences || c.state == nil {
+		return nil
+	}
+	return &references{
+		repo:        repo,
+		base:        strings.TrimSuffix(c.config.ForgejoURL, "/") + "/" + url.PathEscape(c.ownerFor(repo)) + "/" + url.PathEscape(repo.Name),
+		issues:      c.state.IssueSync(repo.FullName, c.name),
+		discussions: c.state.DiscussionSync(repo.FullName, c.name),
+	}
+}
+
+// rewrite points the links to the GitHub repository in text at Forgejo and
+// renumbers #123 references to issues that got another number there. Fenced
+// code blocks are left as they are. A nil rewriter returns text unchanged.
+func (r *references) rewrite(text string) string {
+	if r == nil {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		line = githubLinkPattern.ReplaceAllStringFunc(line, r.rewriteLink)
+		lines[i] = shortReferencePattern.ReplaceAllStringFunc(line, func(match string) string {
+			m := shortReferencePattern.FindStringSubmatch(match)
+			number, _ := strconv.Atoi(m[2])
+			index, ok := r.index(number)
+			if !ok {
+				return match
+			}
+			return m[1] + "#" + strconv.FormatInt(index, 10)
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rewriteLink rewrites one link matched by githubLinkPattern. Links to other
+// repositories and to discussions that weren't imported are kept.
+func (r *references) rewriteLink(link string) string {
+	m := githubLinkPattern.FindStringSubmatch(link)
+	if !strings.EqualFold(m[1]+"/"+m[2], r.repo.FullName) {
+		return link
+	}
+	number, _ := strconv.Atoi(m[4])
+	kind := "issues"
+	index := r.issues.index(number)
+	switch m[3] {
+	case "pull":
+		kind = "pulls"
+	case "discussions":
+		imported, ok := r.discussions.lookup(number)
+		if !ok {
+			return link
+		}
+		index = imported
+	}
+	rewritten := fmt.Sprintf("%s/%s/%d", r.base, kind, index)
+	// Comments that came with the migration can't be told apart on Forgejo
+	if m[5] != "" && r.issues != nil {
+		id, _ := strconv.ParseInt(m[5], 10, 64)
+		if forgejoID, ok := r.issues.Comments[id]; ok {
+			rewritten += "#issuecomment-" + strconv.FormatInt(forgejoID, 10)
+		}
+	}
+	return rewritten
+}
+
+// index returns the Forgejo index of a #number reference if it differs from
+// the number. GitHub numbers issues, pull requests and discussions together,
+// so a number is either a synced issue, an imported discussion or neither.
+func (r *references) index(number int) (int64, bool) {
+	if index, ok := r.issues.lookup(number); ok {
+		return index, index != int64(number)
+	}
+	if index, ok := r.discussions.lookup(number); ok {
+		return index, index != int64(number)
+	}
+	return 0, false
+}
+
+// RewriteReferences points the links to the GitHub repository in the issues,
+// pull requests and comments Forgejo migrated at their copies on Forgejo, once
+// per repository. Forgejo imports issues in the background after creating the
+// repository, so this happens on the run after the migration.
+func (c *Client) RewriteReferences(ctx context.Context, repo *GitHubRepo, result Result) error {
+	refs := c.references(repo)
+	if refs == nil {
+		return nil
+	}
+	if result == ResultCreated {
+		// Also after --on-exists=recreate
+		c.state.SetReferencesRewritten(repo, c.name, false)
+		return nil
+	}
+	if target, ok := c.state.Target(repo.FullName, c.name); ok && target.ReferencesRewritten {
+		return nil
+	}
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Mirror {
+		return err
+	}
+
+	issues, err := forgejoList[struct {
+		Index int64  `json:"number"`
+		Body  string `json:"body"`
+	}](ctx, c, repoPath(owner, repo.Name, "issues")+"?state=all", "issues of "+repo.Name)
+	if err != nil {
+		return err
+	}
+	comments, err := forgejoList[struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}](ctx, c, repoPath(owner, repo.Name, "issues", "comments"), "comments of "+repo.Name)
+	if err != nil {
+		return err
+	}
+
+	var edits int
+	for _, issue := range issues {
+		if body := refs.rewrite(issue.Body); body != issue.Body {
+			edits++
+			if err := c.rewriteText(ctx, repo, body, "issues", strconv.FormatInt(issue.Index, 10)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, comment := range comments {
+		if body := refs.rewrite(comment.Body); body != comment.Body {
+			edits++
+			if err := c.rewriteText(ctx, repo, body, "issues", "comments", strconv.FormatInt(comment.ID, 10)); err != nil {
+				return err
+			}
+		}
+	}
+	if c.config.DryRun {
+		if edits > 0 {
+			fmt.Printf("[DRY RUN] Would rewrite GitHub links in %d issues and comments of %s\n", edits, repo.Name)
+		}
+		return nil
+	}
+	c.state.SetReferencesRewritten(repo, c.name, true)
+	if c.config.Verbose && edits > 0 {
+		fmt.Printf("🔗 Rewrote GitHub links in %d issues and comments of %s\n", edits, repo.Name)
+	}
+	return nil
+}
+
+// rewriteText replaces the body of a Forgejo issue or comment, given by the
+// path segments after the repository
+func (c *Client) rewriteText(ctx context.Context, repo *GitHubRepo, body string, segments ...string) error {
+	if c.config.DryRun {
+		return nil
+	}
+	status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(c.ownerFor(repo), repo.Name, segments...), map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("failed to rewrite links: %w", err)
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return forgejoError("rewriting links in "+repo.Name, status, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// staleCredentials is a mirror that pulls with the GitHub credentials it was
+// created with
+type staleCredentials struct {
+	target *Client
+	repo   *GitHubRepo
+	owner  string
+}
+
+// runRefreshCredentials recreates the mirrors of private repositories so they
+// pull with the current GitHub token, and returns the exit code. Forgejo keeps
+// the credentials of a pull mirror in its remote address and its API can't
+// change them, so after a token rotation the mirrors have to be migrated again.
+func runRefreshCredentials(ctx context.Context, config *Config, client *Client) int {
+	fmt.Printf("🔑 Looking for mirrors that pull with GitHub credentials on %s\n", config.ForgejoURL)
+	repos, err := client.GetGitHubRepos(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+	}
+
+	var affected []staleCredentials
+	var failed int
+	for _, target := range client.targets() {
+		for _, repo := range repos {
+			if !repo.Private {
+				continue
+			}
+			owner := target.ownerFor(repo)
+			mirror, err := target.GetForgejoRepo(ctx, owner, repo.Name)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", repo.Name, err)
+				failed++
+				continue
+			}
+			// SSH mirrors authenticate with a deploy key instead of the token
+			if mirror == nil || !mirror.Mirror || mirror.Archived || remoteScheme(mirror.OriginalURL) == "ssh" {
+				continue
+			}
+			if migrationInProgress(repo, mirror, target.clock.Now()) {
+				fmt.Printf("   %s/%s: still being migrated, leaving it alone\n", owner, repo.Name)
+				continue
+			}
+			affected = append(affected, staleCredentials{target: target, repo: repo, owner: owner})
+			fmt.Printf("   %s/%s on %s\n", owner, repo.Name, target.name)
+		}
+	}
+	if len(affected) == 0 {
+		fmt.Println("   No mirrors of private repositories found")
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+	fmt.Println()
+
+	if !config.DryRun && !confirm(config, fmt.Sprintf("Recreate %d mirrors with the current GitHub token?", len(affected))) {
+		fmt.Println("   Credential refresh cancelled")
+		return 1
+	}
+
+	var refreshed int
+	for _, m := range affected {
+		if err := m.target.DeleteRepo(ctx, m.owner, m.repo.Name); err != nil {
+			fmt.Printf("❌ Failed to delete %s: %v\n", m.repo.Name, err)
+			failed++
+			continue
+		}
+		if config.DryRun {
+			fmt.Printf("[DRY RUN] Would migrate: %s\n", m.repo.Name)
+			refreshed++
+			continue
+		}
+		result, detail := m.target.mirrorRepo(ctx, m.repo, false)
+		m.target.state.RecordResult(m.repo, m.target, result, detail, m.target.clock.Now())
+		if !result.Succeeded() {
+			fmt.Println(detail)
+			failed++
+			continue
+		}
+		refreshed++
+	}
+	if client.state != nil && !config.DryRun {
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	fmt.Printf("\n📊 Credential Refresh Summary:\n")
+	fmt.Printf("   Mirrors: %d\n", len(affected))
+	fmt.Printf("   Refreshed: %d\n", refreshed)
+	fmt.Printf("   Failed: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// getGitHubTags returns the names of all tags of a GitHub repository
+func (c *Client) getGitHubTags(ctx context.Context, repo *GitHubRepo) ([]string, error) {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	var names []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		tags, resp, err := c.githubFor(repo).Repositories.ListTags(ctx, owner, name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", repo.FullName, err)
+		}
+		for _, tag := range tags {
+			names = append(names, tag.GetName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return names, nil
+}
+
+// getForgejoRefNames returns the names of all branches or tags of a Forgejo
+// repository; kind is "branches" or "tags"
+func (c *Client) getForgejoRefNames(ctx context.Context, owner, name, kind string) ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s?limit=50&page=%d", repoPath(owner, name, kind), page)
+		status, body, err := c.forgejoRequest(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("Forgejo API returned status %d listing %s: %s", status, kind, string(body))
+		}
+
+		var refs []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &refs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", kind, err)
+		}
+		if len(refs) == 0 {
+			break
+		}
+		for _, ref := range refs {
+			names = append(names, ref.Name)
+		}
+	}
+	return names, nil
+}
+
+// compareRefs describes the differences between the refs of one kind on
+// GitHub and Forgejo. Names are only compared in "names" mode.
+func compareRefs(kind string, source, mirror []string, mode string) []string {
+	if mode != "names" {
+		if len(mirror) < len(source) {
+			return []string{fmt.Sprintf("%d of %d %s are missing on Forgejo", len(source)-len(mirror), len(source), kind)}
+		}
+		return nil
+	}
+
+	present := make(map[string]bool, len(mirror))
+	for _, ref := range mirror {
+		present[ref] = true
+	}
+	var missing []string
+	for _, ref := range source {
+		if !present[ref] {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	list := strings.Join(missing, ", ")
+	if len(missing) > 5 {
+		list = strings.Join(missing[:5], ", ") + ", ..."
+	}
+	return []string{fmt.Sprintf("%d of %d %s are missing on Forgejo: %s", len(missing), len(source), kind, list)}
+}
+
+// verifyRefs checks that the mirror of a GitHub repository has all of its
+// branches and tags, a common symptom of partial migrations
+func (c *Client) verifyRefs(ctx context.Context, repo *GitHubRepo) []string {
+	if c.config.VerifyRefs == "deep" {
+		return c.deepVerifyRefs(ctx, repo)
+	}
+	tips, err := c.GetBranchTips(ctx, repo)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	branches := make([]string, 0, len(tips))
+	for branch := range tips {
+		branches = append(branches, branch)
+	}
+	tags, err := c.getGitHubTags(ctx, repo)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	owner := c.ownerFor(repo)
+	var problems []string
+	for _, refs := range []struct {
+		kind   string
+		github []string
+	}{{"branches", branches}, {"tags", tags}} {
+		forgejo, err := c.getForgejoRefNames(ctx, owner, repo.Name, refs.kind)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		problems = append(problems, compareRefs(refs.kind, refs.github, forgejo, c.config.VerifyRefs)...)
+	}
+	return problems
+}
+
+// forgejoCloneURL returns the HTTPS git URL of a Forgejo repository
+func (c *Client) forgejoCloneURL(owner, name string) string {
+	return fmt.Sprintf("%s/%s/%s.git", c.config.ForgejoURL, url.PathEscape(owner), url.PathEscape(name))
+}
+
+// lsRemote returns the SHA of every ref of a git remote, keyed by ref name,
+// or only of its branches and tags with the --heads and --tags options.
+// Peeled annotated tags are included with their ^{} suffix.
+func lsRemote(ctx context.Context, remote string, auth []gitAuth, options ...string) (map[string]string, error) {
+	out, err := runGit(ctx, "", auth, append(append([]string{"ls-remote"}, options...), remote)...)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if sha, ref, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			refs[ref] = sha
+		}
+	}
+	return refs, nil
+}
+
+// deepVerifyRefs lists the branches and tags of a repository on GitHub and
+// Forgejo with git ls-remote and reports every ref that is missing, points to
+// another commit or only exists on Forgejo
+func (c *Client) deepVerifyRefs(ctx context.Context, repo *GitHubRepo) []string {
+	source, mirror := repo.CloneURL, c.forgejoCloneURL(c.ownerFor(repo), repo.Name)
+	ghAuth, err := c.githubAuth(repo, source)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to get GitHub credentials: %v", err)}
+	}
+	auth := []gitAuth{ghAuth, c.forgejoAuth(mirror)}
+
+	githubRefs, err := lsRemote(ctx, source, auth, "--heads", "--tags")
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list refs on GitHub: %v", err)}
+	}
+	forgejoRefs, err := lsRemote(ctx, mirror, auth, "--heads", "--tags")
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list refs on Forgejo: %v", err)}
+	}
+
+	var missing, differ, extra []string
+	for ref, sha := range githubRefs {
+		switch got, ok := forgejoRefs[ref]; {
+		case !ok:
+			missing = append(missing, ref)
+		case got != sha:
+			differ = append(differ, fmt.Sprintf("%s (GitHub %.7s, Forgejo %.7s)", ref, sha, got))
+		}
+	}
+	for ref := range forgejoRefs {
+		if _, ok := githubRefs[ref]; !ok {
+			extra = append(extra, ref)
+		}
+	}
+
+	var problems []string
+	for _, diff := range []struct {
+		refs []string
+		what string
+	}{{missing, "missing on Forgejo"}, {differ, "at a different commit on Forgejo"}, {extra, "only on Forgejo"}} {
+		if len(diff.refs) == 0 {
+			continue
+		}
+		sort.Strings(diff.refs)
+		list := strings.Join(diff.refs, ", ")
+		if len(diff.refs) > 5 {
+			list = strings.Join(diff.refs[:5], ", ") + ", ..."
+		}
+		problems = append(problems, fmt.Sprintf("%d of %d refs are %s: %s", len(diff.refs), len(githubRefs), diff.what, list))
+	}
+	return problems
+}
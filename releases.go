@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// forgejoRelease is the part of a Forgejo release the sync compares
+type forgejoRelease struct {
+	ID         int64  `json:"id"`
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	} `json:"assets"`
+}
+
+// SyncReleases creates the published GitHub releases of a repository that are
+// missing on Forgejo, updates the title, notes and prerelease flag of the
+// others and uploads assets that are missing or differ in size. Forgejo
+// rebuilds the releases of pull mirrors from their tags whenever they sync, so
+// only repositories that aren't mirrors are synced.
+func (c *Client) SyncReleases(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil || forgejoRepo.Mirror {
+		return err
+	}
+
+	releases, err := c.githubReleases(ctx, repo)
+	if err != nil {
+		return err
+	}
+	existing, err := forgejoList[forgejoRelease](ctx, c, repoPath(owner, repo.Name, "releases"), "releases of "+repo.Name)
+	if err != nil {
+		return err
+	}
+	byTag := make(map[string]*forgejoRelease)
+	for i := range existing {
+		byTag[existing[i].TagName] = &existing[i]
+	}
+
+	// GitHub lists the newest first, creating the oldest first keeps their order on Forgejo
+	for i := len(releases) - 1; i >= 0; i-- {
+		release := releases[i]
+		if err := c.syncRelease(ctx, owner, repo, release, byTag[release.GetTagName()]); err != nil {
+			return fmt.Errorf("release %s: %w", release.GetTagName(), err)
+		}
+	}
+	return nil
+}
+
+// githubReleases lists the published releases of a GitHub repository
+func (c *Client) githubReleases(ctx context.Context, repo *GitHubRepo) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Repositories.ListReleases(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			return nil, githubError("listing releases of "+repo.FullName, err)
+		}
+		for _, release := range list {
+			if !release.GetDraft() {
+				releases = append(releases, release)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return releases, nil
+}
+
+// syncRelease creates or updates one release and its assets. current is the
+// Forgejo release with the same tag, or nil.
+func (c *Client) syncRelease(ctx context.Context, owner string, repo *GitHubRepo, release *github.RepositoryRelease, current *forgejoRelease) error {
+	payload := map[string]interface{}{
+		"tag_name":         release.GetTagName(),
+		"target_commitish": release.GetTargetCommitish(),
+		"name":             release.GetName(),
+		"body":             release.GetBody(),
+		"prerelease":       release.GetPrerelease(),
+	}
+
+	switch {
+	case current == nil && c.config.DryRun:
+		fmt.Printf("[DRY RUN] Would create release %s of %s with %d assets\n", release.GetTagName(), repo.Name, len(release.Assets))
+		return nil
+	case current == nil:
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "releases"), payload)
+		if err != nil {
+			return fmt.Errorf("failed to create release: %w", err)
+		}
+		if status != http.StatusCreated {
+			return forgejoError("creating release "+release.GetTagName()+" of "+repo.Name, status, body)
+		}
+		current = &forgejoRelease{}
+		if err := json.Unmarshal(body, current); err != nil {
+			return fmt.Errorf("failed to decode release: %w", err)
+		}
+		fmt.Printf("🏷️  Created release %s of %s\n", release.GetTagName(), repo.Name)
+	case current.Name != release.GetName() || current.Body != release.GetBody() || current.Prerelease != release.GetPrerelease():
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would update release %s of %s\n", release.GetTagName(), repo.Name)
+			break
+		}
+		delete(payload, "target_commitish")
+		status, body, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "releases", strconv.FormatInt(current.ID, 10)), payload)
+		if err != nil {
+			return fmt.Errorf("failed to update release: %w", err)
+		}
+		if status != http.StatusOK {
+			return forgejoError("updating release "+release.GetTagName()+" of "+repo.Name, status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("🏷️  Updated release %s of %s\n", release.GetTagName(), repo.Name)
+		}
+	}
+
+	for _, asset := range release.Assets {
+		if err := c.syncAsset(ctx, owner, repo, current, asset); err != nil {
+			return fmt.Errorf("asset %s: %w", asset.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// syncAsset uploads a GitHub release asset to a Forgejo release unless an
+// asset with the same name and size is already attached. One that differs in
+// size is replaced.
+func (c *Client) syncAsset(ctx context.Context, owner string, repo *GitHubRepo, release *forgejoRelease, asset *github.ReleaseAsset) error {
+	releasePath := repoPath(owner, repo.Name, "releases", strconv.FormatInt(release.ID, 10), "assets")
+	for _, existing := range release.Assets {
+		if existing.Name != asset.GetName() {
+			continue
+		}
+		if existing.Size == int64(asset.GetSize()) {
+			return nil
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would replace asset %s of release %s of %s\n", asset.GetName(), release.TagName, repo.Name)
+			return nil
+		}
+		status, body, err := c.forgejoRequest(ctx, "DELETE", releasePath+"/"+strconv.FormatInt(existing.ID, 10), nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete outdated asset: %w", err)
+		}
+		if status != http.StatusNoContent {
+			return forgejoError("deleting outdated asset "+asset.GetName(), status, body)
+		}
+	}
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would upload asset %s to release %s of %s\n", asset.GetName(), release.TagName, repo.Name)
+		return nil
+	}
+
+	// Private repositories only serve assets through the API, which redirects
+	// to a signed download URL
+	content, _, err := c.githubFor(repo).Repositories.DownloadReleaseAsset(ctx, repo.Owner, repo.githubName(), asset.GetID(), http.DefaultClient)
+	if err != nil {
+		return githubError("downloading asset "+asset.GetName(), err)
+	}
+	defer content.Close()
+
+	status, body, err := c.forgejoUpload(ctx, releasePath+"?name="+url.QueryEscape(asset.GetName()), "attachment", asset.GetName(), content)
+	if err != nil {
+		return fmt.Errorf("failed to upload asset: %w", err)
+	}
+	if status != http.StatusCreated {
+		return forgejoError("uploading asset "+asset.GetName(), status, body)
+	}
+	if c.config.Verbose {
+		fmt.Printf("📦 Uploaded %s to release %s of %s\n", asset.GetName(), release.TagName, repo.Name)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// expectedRemote returns the address a mirror of repo should pull from
+func (c *Client) expectedRemote(repo *GitHubRepo) string {
+	if c.config.CloneProtocol == "ssh" {
+		return repo.SSHURL
+	}
+	return repo.CloneURL
+}
+
+// remoteMismatch returns how the remote address a mirror pulls from differs
+// from the one a new mirror of repo would get, or an empty string if it
+// matches or Forgejo doesn't report it
+func (c *Client) remoteMismatch(repo *GitHubRepo, remote string) string {
+	expected := c.expectedRemote(repo)
+	if remote == "" || expected == "" || sameRemote(remote, expected) {
+		return ""
+	}
+	owner, name, ok := githubRepoFromURL(remote)
+	wantOwner, wantName, _ := githubRepoFromURL(expected)
+	switch {
+	case ok && !strings.EqualFold(owner+"/"+name, wantOwner+"/"+wantName):
+		return fmt.Sprintf("pulls from %s/%s, which is now %s", owner, name, repo.FullName)
+	case remoteUser(remote) != "":
+		return fmt.Sprintf("has the credentials of %s in its remote address", remoteUser(remote))
+	case remoteScheme(remote) != remoteScheme(expected):
+		return fmt.Sprintf("pulls over %s instead of %s", remoteScheme(remote), remoteScheme(expected))
+	}
+	return "pulls from " + remote
+}
+
+// remoteScheme returns the protocol of a git remote address, "ssh" for
+// scp-like addresses
+func remoteScheme(remote string) string {
+	if strings.HasPrefix(remote, "git@") {
+		return "ssh"
+	}
+	if u, err := url.Parse(remote); err == nil {
+		return strings.ToLower(u.Scheme)
+	}
+	return ""
+}
+
+// remoteUser returns the user embedded in an HTTP(S) remote address. SSH
+// addresses always name the git user, so they have none.
+func remoteUser(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.User.Username()
+}
+
+// recreateMirror deletes a mirror and migrates it again. Forgejo's API can't
+// change the remote address or credentials of a pull mirror, so this is the
+// only way to repoint one.
+func (c *Client) recreateMirror(ctx context.Context, repo *GitHubRepo, owner, why string) (Result, error) {
+	fmt.Printf("🔁 Recreating mirror %s/%s: it %s\n", owner, repo.Name, why)
+	if err := c.DeleteRepo(ctx, owner, repo.Name); err != nil {
+		return ResultFailed, err
+	}
+	return c.submitMigration(ctx, repo)
+}
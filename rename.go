@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// renamedMirror is the existing Forgejo mirror of a GitHub repository that
+// was renamed or transferred since it was mirrored
+type renamedMirror struct {
+	fullName string // former GitHub full name
+	owner    string
+	name     string
+}
+
+// FindRenames maps the full names of renamed GitHub repositories to their
+// existing mirrors on this target. Renames are recognised by the GitHub ID
+// recorded in the state file and, for mirrors the state file doesn't know,
+// by resolving the GitHub address the orphaned mirror pulls from.
+func (c *Client) FindRenames(ctx context.Context, githubRepos []*GitHubRepo, forgejoRepos []*ForgejoRepo) map[string]renamedMirror {
+	renames := make(map[string]renamedMirror)
+	byID := make(map[int64]*GitHubRepo)
+	for _, repo := range githubRepos {
+		byID[repo.ID] = repo
+	}
+
+	for id, fullName := range c.state.FullNamesByID() {
+		repo, ok := byID[id]
+		if !ok || repo.FullName == fullName {
+			continue
+		}
+		if target, ok := c.state.Target(fullName, c.name); ok {
+			renames[repo.FullName] = renamedMirror{fullName: fullName, owner: target.Owner, name: target.Name}
+		}
+	}
+
+	for _, orphan := range c.FindOrphans(githubRepos, forgejoRepos) {
+		owner, name, ok := githubRepoFromURL(orphan.OriginalURL)
+		if !ok {
+			continue
+		}
+		var current *github.Repository
+		var err error
+		for _, client := range c.githubClients(owner, githubRepos) {
+			if current, _, err = client.Repositories.Get(ctx, owner, name); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			if c.config.Verbose {
+				log.Printf("Warning: Failed to resolve the source of %s: %v", orphan.FullName, err)
+			}
+			continue
+		}
+		repo, ok := byID[current.GetID()]
+		if !ok || strings.EqualFold(repo.FullName, owner+"/"+name) {
+			continue
+		}
+		if _, known := renames[repo.FullName]; !known {
+			forgejoOwner, _, _ := strings.Cut(orphan.FullName, "/")
+			renames[repo.FullName] = renamedMirror{fullName: owner + "/" + name, owner: forgejoOwner, name: orphan.Name}
+		}
+	}
+	return renames
+}
+
+// githubRepoFromURL extracts the owner and name from a GitHub clone URL,
+// including scp-like SSH addresses used with --clone-protocol=ssh
+func githubRepoFromURL(rawURL string) (string, string, bool) {
+	if path, ok := strings.CutPrefix(rawURL, "git@github.com:"); ok {
+		rawURL = "ssh://git@github.com/" + path
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Hostname(), "github.com") {
+		return "", "", false
+	}
+	owner, name, ok := strings.Cut(strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/"), "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
+
+// RenameMirror moves the existing mirror of a renamed GitHub repository to the
+// owner and name the repository maps to now, so it is updated in place instead
+// of being mirrored again and the old copy treated as an orphan
+func (c *Client) RenameMirror(ctx context.Context, repo *GitHubRepo, old renamedMirror) error {
+	owner := c.ownerFor(repo)
+	if strings.EqualFold(old.owner, owner) && old.name == repo.Name {
+		c.state.RenameTarget(old.fullName, repo.FullName, c.name)
+		return nil
+	}
+
+	mirror, err := c.GetForgejoRepo(ctx, old.owner, old.name)
+	if err != nil {
+		return err
+	}
+	if mirror == nil {
+		return nil
+	}
+	existing, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil {
+		return err
+	}
+	// Forgejo looks names up case-insensitively, so a rename that only changes
+	// the case finds the mirror itself
+	if existing != nil && existing.ID != mirror.ID {
+		fmt.Printf("⚠️  %s was renamed to %s, but both %s/%s and %s/%s exist on Forgejo\n", old.fullName, repo.FullName, old.owner, old.name, owner, repo.Name)
+		return nil
+	}
+
+	if c.config.DryRun {
+		c.plan.add(PlanAction{
+			Action:   "rename",
+			Target:   c.name,
+			Repo:     repo.FullName,
+			Mirror:   old.owner + "/" + old.name,
+			Previous: old.fullName,
+			Note:     "renamed on GitHub from " + old.fullName,
+			Changes:  []SettingChange{{Setting: "full_name", From: old.owner + "/" + old.name, To: owner + "/" + repo.Name}},
+		})
+		return nil
+	}
+
+	if old.name != repo.Name {
+		if err := c.EditRepo(ctx, old.owner, old.name, &EditRepoOption{Name: &repo.Name}); err != nil {
+			return err
+		}
+	}
+	if !strings.EqualFold(old.owner, owner) {
+		if err := c.ensureOwner(ctx, repo); err != nil {
+			return err
+		}
+		if err := c.TransferRepo(ctx, old.owner, repo.Name, owner); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("✏️  Renamed mirror %s/%s to %s/%s (%s is now %s)\n", old.owner, old.name, owner, repo.Name, old.fullName, repo.FullName)
+	c.state.RenameTarget(old.fullName, repo.FullName, c.name)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// stuckMirror is a Forgejo mirror whose migration never completed properly
+type stuckMirror struct {
+	target *Client
+	repo   *GitHubRepo
+	owner  string
+	reason string
+}
+
+// diagnoseMirror returns why a Forgejo mirror of a GitHub repository needs to
+// be recreated, or an empty string if it looks healthy
+func diagnoseMirror(repo *GitHubRepo, mirror *ForgejoRepo) string {
+	switch {
+	case mirror.Empty && repo.Size > 0:
+		return "empty although GitHub has commits"
+	case mirror.OriginalURL != "" && !sameRemote(mirror.OriginalURL, repo.CloneURL) && !sameRemote(mirror.OriginalURL, repo.SSHURL):
+		return fmt.Sprintf("pulls from %s", mirror.OriginalURL)
+	case mirror.MirrorUpdated.IsZero():
+		return "never synced"
+	}
+	return ""
+}
+
+// sameRemote reports whether two git remote URLs point at the same repository
+func sameRemote(a, b string) bool {
+	normalize := func(u string) string {
+		return strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git"))
+	}
+	return normalize(a) == normalize(b)
+}
+
+// runRepair deletes and re-migrates mirrors that are stuck mid-migration on
+// every target and returns the exit code
+func runRepair(ctx context.Context, config *Config, client *Client) int {
+	fmt.Printf("🩺 Looking for stuck mirrors on %s\n", config.ForgejoURL)
+	repos, err := client.GetGitHubRepos(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+	}
+	fmt.Printf("   Found %d repositories on GitHub\n\n", len(repos))
+
+	var stuck []stuckMirror
+	var failed int
+	for _, target := range client.targets() {
+		for _, repo := range repos {
+			owner := target.ownerFor(repo)
+			mirror, err := target.GetForgejoRepo(ctx, owner, repo.Name)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", repo.Name, err)
+				failed++
+				continue
+			}
+			if mirror == nil || !mirror.Mirror {
+				continue
+			}
+			if migrationInProgress(repo, mirror, target.clock.Now()) {
+				fmt.Printf("   %s/%s on %s: still being migrated, leaving it alone\n", owner, repo.Name, target.name)
+				continue
+			}
+			if reason := diagnoseMirror(repo, mirror); reason != "" {
+				stuck = append(stuck, stuckMirror{target: target, repo: repo, owner: owner, reason: reason})
+				fmt.Printf("   %s/%s on %s: %s\n", owner, repo.Name, target.name, reason)
+			}
+		}
+	}
+	if len(stuck) == 0 {
+		fmt.Println("   No stuck mirrors found")
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+	fmt.Println()
+
+	if !config.DryRun && !confirm(config, fmt.Sprintf("Delete and re-migrate %d mirrors?", len(stuck))) {
+		fmt.Println("   Repair cancelled")
+		return 1
+	}
+
+	var repaired int
+	for _, m := range stuck {
+		if err := m.target.DeleteRepo(ctx, m.owner, m.repo.Name); err != nil {
+			fmt.Printf("❌ Failed to delete %s: %v\n", m.repo.Name, err)
+			failed++
+			continue
+		}
+		if config.DryRun {
+			// The mirror still exists, so planning the migration would only refresh it
+			fmt.Printf("[DRY RUN] Would migrate: %s\n", m.repo.Name)
+			repaired++
+			continue
+		}
+		result, detail := m.target.mirrorRepo(ctx, m.repo, false)
+		m.target.state.RecordResult(m.repo, m.target, result, detail, m.target.clock.Now())
+		if !result.Succeeded() {
+			fmt.Println(detail)
+			failed++
+			continue
+		}
+		repaired++
+	}
+	if client.state != nil && !config.DryRun {
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	fmt.Printf("\n📊 Repair Summary:\n")
+	fmt.Printf("   Stuck: %d\n", len(stuck))
+	fmt.Printf("   Repaired: %d\n", repaired)
+	fmt.Printf("   Failed: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiagnoseMirror(t *testing.T) {
+	repo := &GitHubRepo{FullName: "acme/api", Size: 120, CloneURL: "https://github.com/acme/api.git", SSHURL: "git@github.com:acme/api.git"}
+	synced := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		repo   *GitHubRepo
+		mirror *ForgejoRepo
+		want   string
+	}{
+		{"healthy", repo, &ForgejoRepo{OriginalURL: "https://github.com/acme/api.git", MirrorUpdated: synced}, ""},
+		{"healthy over ssh", repo, &ForgejoRepo{OriginalURL: "git@github.com:acme/api.git", MirrorUpdated: synced}, ""},
+		{"address not reported", repo, &ForgejoRepo{MirrorUpdated: synced}, ""},
+		{"empty", repo, &ForgejoRepo{Empty: true, OriginalURL: "https://github.com/acme/api.git", MirrorUpdated: synced}, "empty although GitHub has commits"},
+		{"empty on both sides", &GitHubRepo{FullName: "acme/api", CloneURL: repo.CloneURL}, &ForgejoRepo{Empty: true, OriginalURL: "https://github.com/acme/api.git", MirrorUpdated: synced}, ""},
+		{"other source", repo, &ForgejoRepo{OriginalURL: "https://github.com/acme/old-api.git", MirrorUpdated: synced}, "pulls from https://github.com/acme/old-api.git"},
+		{"never synced", repo, &ForgejoRepo{OriginalURL: "https://github.com/acme/api.git"}, "never synced"},
+	}
+	for _, tt := range tests {
+		if got := diagnoseMirror(tt.repo, tt.mirror); got != tt.want {
+			t.Errorf("%s: diagnoseMirror = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameRemote(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://github.com/acme/api.git", "https://github.com/acme/api.git", true},
+		{"https://github.com/acme/api.git", "https://github.com/acme/api", true},
+		{"https://github.com/Acme/API/", "https://github.com/acme/api.git", true},
+		{"https://github.com/acme/api.git", "https://github.com/acme/web.git", false},
+		{"https://github.com/acme/api.git", "git@github.com:acme/api.git", false},
+	}
+	for _, tt := range tests {
+		if got := sameRemote(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameRemote(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
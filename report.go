@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FailedRepo is a repository that failed to migrate to one target
+type FailedRepo struct {
+	Repo   string `json:"repo"`
+	Target string `json:"target"`
+	Error  string `json:"error"`
+}
+
+// RunReport is written at the end of a run with --report. Actions holds what
+// --check-actions found, by repository.
+type RunReport struct {
+	FinishedAt time.Time                   `json:"finished_at"`
+	Failed     []FailedRepo                `json:"failed"`
+	Actions    map[string][]ActionsFinding `json:"actions,omitempty"`
+}
+
+// writeReport writes the report of a run as JSON
+func writeReport(path string, report *RunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// loadFailedRepos returns the full names of the repositories that failed in
+// the run a report was written for
+func loadFailedRepos(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+
+	failed := make(map[string]bool)
+	for _, repo := range report.Failed {
+		failed[repo.Repo] = true
+	}
+	return failed, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+// Result is the outcome of processing one repository on one Forgejo target
+type Result int
+
+const (
+	// ResultCreated means the repository was migrated or created by this run
+	ResultCreated Result = iota
+	// ResultAlreadyExists means the repository was already on Forgejo and
+	// only its settings were refreshed
+	ResultAlreadyExists
+	// ResultSynced means the repository was an existing mirror and a sync
+	// was triggered
+	ResultSynced
+	// ResultSkipped means the repository was deliberately left alone, e.g.
+	// because it is empty or over quota
+	ResultSkipped
+	// ResultFailed means processing the repository failed
+	ResultFailed
+	// ResultTimedOut means processing the repository took longer than
+	// --repo-timeout and was abandoned
+	ResultTimedOut
+	// ResultConflict means the repository's name is taken on Forgejo by a
+	// repository that isn't its mirror
+	ResultConflict
+	// ResultDeleted means an orphaned repository was deleted during cleanup
+	ResultDeleted
+)
+
+// Reasons for skipping a repository, recorded as its last status in the state file
+const (
+	skipEmpty       = "empty"
+	skipQuota       = "quota"
+	skipUnchanged   = "unchanged"
+	skipDuplicate   = "duplicate"
+	skipMigrating   = "migration in progress"
+	skipInterrupted = "not processed: the run was interrupted"
+)
+
+func (r Result) String() string {
+	switch r {
+	case ResultCreated:
+		return "created"
+	case ResultAlreadyExists:
+		return "already exists"
+	case ResultSynced:
+		return "synced"
+	case ResultSkipped:
+		return "skipped"
+	case ResultFailed:
+		return "failed"
+	case ResultTimedOut:
+		return "timed out"
+	case ResultConflict:
+		return "conflict"
+	case ResultDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// Succeeded reports whether the repository is in the desired state on Forgejo
+func (r Result) Succeeded() bool {
+	return r == ResultCreated || r == ResultAlreadyExists || r == ResultSynced
+}
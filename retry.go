@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between attempts
+const maxRetryDelay = 5 * time.Minute
+
+// transientError marks a failure that may succeed when retried: a network
+// error, a timeout or a 5xx response
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transientIf marks err as transient if the response status is a server error
+func transientIf(status int, err error) error {
+	if status >= 500 {
+		return &transientError{err: err}
+	}
+	return err
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error or
+// --retry-attempts is used up, backing off exponentially with jitter
+func (c *Client) withRetry(ctx context.Context, what string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) || attempt >= c.config.RetryAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay := min(c.config.RetryDelay<<(attempt-1), maxRetryDelay)
+		// Spread retries so concurrent workers don't hit Forgejo at the same moment
+		delay = delay/2 + rand.N(delay/2+1)
+		fmt.Printf("🔁 %s failed (attempt %d of %d), retrying in %s: %v\n", what, attempt, c.config.RetryAttempts, delay.Round(time.Second), err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	transient := &transientError{err: errors.New("502 Bad Gateway")}
+	permanent := errors.New("422 Unprocessable Entity")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		errs      []error // returned by successive calls, nil once used up
+		wantCalls int
+		wantErr   error
+	}{
+		{"succeeds", context.Background(), nil, 1, nil},
+		{"recovers", context.Background(), []error{transient, transient}, 3, nil},
+		{"attempts used up", context.Background(), []error{transient, transient, transient, transient}, 3, transient},
+		{"permanent error", context.Background(), []error{permanent, transient}, 1, permanent},
+		{"cancelled", cancelled, []error{transient}, 1, transient},
+	}
+	for _, tt := range tests {
+		c := &Client{config: &Config{RetryAttempts: 3, RetryDelay: time.Millisecond}}
+		calls := 0
+		err := c.withRetry(tt.ctx, "Test", func() error {
+			calls++
+			if calls <= len(tt.errs) {
+				return tt.errs[calls-1]
+			}
+			return nil
+		})
+		if calls != tt.wantCalls || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: %d calls returning %v, want %d calls returning %v", tt.name, calls, err, tt.wantCalls, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// rollbackCreated deletes repositories created by a run so it can be retried
+// from scratch and returns how many couldn't be deleted. run is the state
+// record of the run, or nil without a state file.
+func rollbackCreated(ctx context.Context, targets []*Client, state *State, run *RunState, created []CreatedRepo) int {
+	byName := make(map[string]*Client)
+	for _, target := range targets {
+		byName[target.name] = target
+	}
+
+	var failed int
+	for _, c := range created {
+		target, ok := byName[c.Target]
+		owner, name, found := strings.Cut(c.Mirror, "/")
+		if !ok || !found {
+			fmt.Printf("❌ Can't roll back %s: target %s isn't configured\n", c.Mirror, c.Target)
+			failed++
+			continue
+		}
+		if err := target.DeleteRepo(ctx, owner, name); err != nil {
+			fmt.Printf("❌ Failed to roll back %s: %v\n", c.Mirror, err)
+			failed++
+			continue
+		}
+		if !target.config.DryRun {
+			state.RolledBack(run, c)
+		}
+	}
+	return failed
+}
+
+// runRollback deletes the repositories created by an earlier run, recorded in
+// the state file, and returns the exit code
+func runRollback(ctx context.Context, config *Config, client *Client, id string) int {
+	if client.state == nil {
+		fmt.Println("❌ rollback needs the state file of the run, set --state-file")
+		return 1
+	}
+	if id == "" {
+		fmt.Println("Usage: gh2forgejo rollback <run-id>")
+		fmt.Println("\nRecorded runs:")
+		for _, run := range client.state.Runs() {
+			fmt.Printf("   %s  started %s, created %d repositories\n", runID(run.StartedAt), run.StartedAt.Local().Format("2006-01-02 15:04"), len(run.Created))
+		}
+		return 1
+	}
+	run := client.state.FindRun(id)
+	if run == nil {
+		fmt.Printf("❌ No run %s in %s\n", id, config.StateFile)
+		return 1
+	}
+	created := append([]CreatedRepo(nil), run.Created...)
+	if len(created) == 0 {
+		fmt.Printf("   Run %s created no repositories that still exist, nothing to roll back\n", id)
+		return 0
+	}
+
+	fmt.Printf("⏪ Run %s created %d repositories:\n", id, len(created))
+	for _, c := range created {
+		fmt.Printf("   %s on %s\n", c.Mirror, c.Target)
+	}
+	fmt.Println()
+	if !config.DryRun && !confirm(config, fmt.Sprintf("Delete these %d repositories?", len(created))) {
+		fmt.Println("   Rollback cancelled")
+		return 1
+	}
+
+	failed := rollbackCreated(ctx, client.targets(), client.state, run, created)
+	if !config.DryRun {
+		if err := client.state.Save(); err != nil {
+			log.Printf("Warning: Failed to save state: %v", err)
+		}
+	}
+
+	fmt.Printf("\n📊 Rollback Summary:\n")
+	fmt.Printf("   Deleted: %d\n", len(created)-failed)
+	fmt.Printf("   Failed: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
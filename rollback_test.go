@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRollbackCreated(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != "DELETE" {
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/api/v1/repos/mirrors/locked" {
+			http.Error(w, `{"message":"repository is locked"}`, http.StatusConflict)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	api := CreatedRepo{Target: "primary", Mirror: "mirrors/api", Repo: "acme/api"}
+	locked := CreatedRepo{Target: "primary", Mirror: "mirrors/locked", Repo: "acme/locked"}
+	gone := CreatedRepo{Target: "removed", Mirror: "mirrors/web", Repo: "acme/web"}
+	run := &RunState{StartedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), Created: []CreatedRepo{api, locked, gone}}
+	state := &State{Run: run, Repos: map[string]*RepoState{
+		"acme/api":    {Targets: map[string]*TargetState{"primary": {LastStatus: "ok"}}},
+		"acme/locked": {Targets: map[string]*TargetState{"primary": {LastStatus: "ok"}}},
+	}}
+	target := &Client{httpClient: server.Client(), config: &Config{ForgejoURL: server.URL}, name: "primary"}
+
+	failed := rollbackCreated(context.Background(), []*Client{target}, state, run, slices.Clone(run.Created))
+	if failed != 2 {
+		t.Errorf("rollbackCreated = %d failures, want 2", failed)
+	}
+	if want := []string{"/api/v1/repos/mirrors/api"}; !slices.Equal(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+	// Only the deleted repository is forgotten, the others can be rolled back again
+	if want := []CreatedRepo{locked, gone}; !slices.Equal(run.Created, want) {
+		t.Errorf("run still records %v, want %v", run.Created, want)
+	}
+	if _, ok := state.Repos["acme/api"]; ok {
+		t.Error("the state still records the rolled back repository")
+	}
+	if _, ok := state.Repos["acme/locked"]; !ok {
+		t.Error("the state forgot the repository that couldn't be rolled back")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// githubRulesets returns the active rulesets of a GitHub repository, including
+// those inherited from its organization, that target "branch" or "tag"
+func (c *Client) githubRulesets(ctx context.Context, repo *GitHubRepo, target string) ([]*github.Ruleset, error) {
+	gh := c.githubFor(repo)
+	all, _, err := gh.Repositories.GetAllRulesets(ctx, repo.Owner, repo.githubName(), true)
+	if err != nil {
+		err = githubError("listing rulesets of "+repo.FullName, err)
+		// Rulesets aren't available on every plan
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rulesets []*github.Ruleset
+	for _, summary := range all {
+		if summary.GetTarget() != target || summary.Enforcement != "active" {
+			continue
+		}
+		// The listing leaves out the conditions and rules
+		ruleset, _, err := gh.Repositories.GetRuleset(ctx, repo.Owner, repo.githubName(), summary.GetID(), true)
+		if err != nil {
+			return nil, githubError("fetching ruleset "+summary.Name+" of "+repo.FullName, err)
+		}
+		rulesets = append(rulesets, ruleset)
+	}
+	return rulesets, nil
+}
+
+// rulesetPatterns returns the branch or tag name patterns a ruleset applies
+// to, in the glob syntax Forgejo uses. Exclusions can't be expressed in
+// Forgejo and are left out.
+func rulesetPatterns(ruleset *github.Ruleset, repo *GitHubRepo) []string {
+	if ruleset.Conditions == nil || ruleset.Conditions.RefName == nil {
+		return nil
+	}
+	var patterns []string
+	for _, include := range ruleset.Conditions.RefName.Include {
+		switch include {
+		case "~ALL":
+			// * stops at slashes in both GitHub's and Forgejo's patterns
+			patterns = append(patterns, "**")
+		case "~DEFAULT_BRANCH":
+			patterns = append(patterns, repo.DefaultBranch)
+		default:
+			include = strings.TrimPrefix(include, "refs/heads/")
+			patterns = append(patterns, strings.TrimPrefix(include, "refs/tags/"))
+		}
+	}
+	return patterns
+}
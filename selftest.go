@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// selftestName returns the name of the temporary repositories of a self-test
+// started at now
+func selftestName(now time.Time) string {
+	return fmt.Sprintf("gh2forgejo-selftest-%d", now.Unix())
+}
+
+// runSelftest mirrors a throwaway repository end to end to validate the
+// credentials and connectivity of a deployment, and returns the exit code.
+// The mirror always gets a new temporary name, so an existing mirror of
+// --selftest-repo is never touched, and only a mirror the self-test created
+// is deleted afterwards.
+func runSelftest(ctx context.Context, config *Config, client *Client) int {
+	if config.DryRun {
+		fmt.Println("❌ The self-test makes real changes and cannot run in dry-run mode")
+		return 1
+	}
+
+	fmt.Printf("🧪 Self-test: GitHub → %s\n\n", config.ForgejoURL)
+
+	failed := false
+	step := func(name string, fn func() error) bool {
+		if err := fn(); err != nil {
+			fmt.Printf("❌ %s: %v\n", name, err)
+			failed = true
+			return false
+		}
+		fmt.Printf("✅ %s\n", name)
+		return true
+	}
+
+	var repo *GitHubRepo
+	name := selftestName(time.Now())
+	created := config.SelftestRepo == ""
+	if created {
+		if !step("Create GitHub repository "+name, func() error {
+			r, _, err := client.github.Repositories.Create(ctx, "", &github.Repository{
+				Name:        github.String(name),
+				Description: github.String("Temporary repository created by the github-forgejo-mirror self-test"),
+				Private:     github.Bool(true),
+				AutoInit:    github.Bool(true),
+			})
+			if err != nil {
+				return err
+			}
+			repo = newGitHubRepo(r, nil)
+			return nil
+		}) {
+			return 1
+		}
+		// GitHub creates the initial commit asynchronously
+		time.Sleep(2 * time.Second)
+	} else {
+		if !step("Fetch GitHub repository "+config.SelftestRepo, func() error {
+			owner, name, ok := strings.Cut(config.SelftestRepo, "/")
+			if !ok {
+				return errors.New("expected owner/name")
+			}
+			r, _, err := client.github.Repositories.Get(ctx, owner, name)
+			if err != nil {
+				return err
+			}
+			repo = newGitHubRepo(r, nil)
+			return nil
+		}) {
+			return 1
+		}
+	}
+
+	repo.Name = name
+	owner := client.ownerFor(repo)
+	// Set once the name is known to be free, so cleanup never deletes a
+	// repository the self-test didn't create
+	ours := false
+	mirrored := step("Mirror repository to Forgejo as "+owner+"/"+name, func() error {
+		existing, err := client.GetForgejoRepo(ctx, owner, name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%s/%s already exists on Forgejo", owner, name)
+		}
+		ours = true
+		result, err := client.MigrateRepo(ctx, repo)
+		if err == nil && result != ResultCreated {
+			return fmt.Errorf("migration %s", result)
+		}
+		return err
+	})
+
+	if mirrored {
+		step("Verify default branch matches", func() error {
+			tips, err := client.GetBranchTips(ctx, repo)
+			if err != nil {
+				return err
+			}
+			return waitForBranch(ctx, client, owner, repo, tips[repo.DefaultBranch])
+		})
+
+		step("Sync mirror", func() error {
+			want := ""
+			if created {
+				ghOwner, name, _ := strings.Cut(repo.FullName, "/")
+				result, _, err := client.github.Repositories.CreateFile(ctx, ghOwner, name, "selftest.txt", &github.RepositoryContentFileOptions{
+					Message: github.String("Self-test sync commit"),
+					Content: []byte("sync check\n"),
+					Branch:  github.String(repo.DefaultBranch),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to push test commit: %w", err)
+				}
+				want = result.GetSHA()
+			}
+			if err := client.SyncMirror(ctx, owner, repo.Name); err != nil {
+				return err
+			}
+			if want == "" {
+				return nil
+			}
+			return waitForBranch(ctx, client, owner, repo, want)
+		})
+	}
+
+	// Clean up even if earlier steps failed, a failed migration may still
+	// have created the repository
+	if ours {
+		step("Delete Forgejo mirror", func() error {
+			return client.DeleteRepo(ctx, owner, repo.Name)
+		})
+	}
+	if created {
+		step("Delete GitHub repository", func() error {
+			ghOwner, name, _ := strings.Cut(repo.FullName, "/")
+			if _, err := client.github.Repositories.Delete(ctx, ghOwner, name); err != nil {
+				return fmt.Errorf("%w (the token may lack the delete_repo scope; remove %s manually)", err, repo.FullName)
+			}
+			return nil
+		})
+	}
+
+	fmt.Println()
+	if failed {
+		fmt.Println("❌ Self-test FAILED")
+		return 1
+	}
+	fmt.Println("🎉 Self-test PASSED")
+	return 0
+}
+
+// waitForBranch polls Forgejo until the default branch of the mirror points at
+// the expected commit
+func waitForBranch(ctx context.Context, client *Client, owner string, repo *GitHubRepo, want string) error {
+	deadline := time.Now().Add(time.Minute)
+	var got string
+	for {
+		var err error
+		got, err = client.GetForgejoBranchSHA(ctx, owner, repo.Name, repo.DefaultBranch)
+		if err != nil {
+			return err
+		}
+		if got == want {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(3 * time.Second)
+	}
+	return fmt.Errorf("%s is at %.7s on Forgejo, expected %.7s", repo.DefaultBranch, got, want)
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelftestName(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	name := selftestName(now)
+	if want := "gh2forgejo-selftest-1748779200"; name != want {
+		t.Errorf("selftestName = %q, want %q", name, want)
+	}
+	if reason := invalidNameReason(name); reason != "" {
+		t.Errorf("selftestName = %q is not a valid Forgejo name: %s", name, reason)
+	}
+	if selftestName(now.Add(time.Second)) == name {
+		t.Error("selftestName returns the same name a second later")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// mergeCache remembers the individually fetched GitHub repositories the merge
+// settings are read from, so every target shares one request per repository
+type mergeCache struct {
+	mu    sync.Mutex
+	repos map[string]*github.Repository
+}
+
+func newMergeCache() *mergeCache {
+	return &mergeCache{repos: make(map[string]*github.Repository)}
+}
+
+// desiredSettings returns the Forgejo repository settings that mirror the
+// current state of the GitHub repository. The merge settings take a request
+// per repository, so they are only included with merge.
+func (c *Client) desiredSettings(ctx context.Context, repo *GitHubRepo, merge bool) *EditRepoOption {
+	description := c.descriptionFor(repo)
+	opt := &EditRepoOption{
+		Description: &description,
+		Private:     &repo.Private,
+		HasIssues:   &repo.HasIssues,
+		HasWiki:     &repo.HasWiki,
+		HasProjects: &repo.HasProjects,
+		Template:    &repo.IsTemplate,
+	}
+	if interval := c.mirrorIntervalFor(repo); c.config.Mode == "mirror" && interval != "" {
+		opt.MirrorInterval = &interval
+	}
+	if repo.DefaultBranch != "" {
+		opt.DefaultBranch = &repo.DefaultBranch
+	}
+	if !merge {
+		return opt
+	}
+	if err := c.addMergeSettings(ctx, repo, opt); err != nil {
+		log.Printf("Warning: Failed to read merge settings of %s: %v", repo.Name, err)
+	}
+	return opt
+}
+
+// descriptionFor returns the Forgejo description of a repository: the GitHub
+// description followed by the expanded --description-suffix template
+func (c *Client) descriptionFor(repo *GitHubRepo) string {
+	if c.config.DescriptionSuffix == "" {
+		return repo.Description
+	}
+	suffix := strings.NewReplacer(
+		"{url}", strings.TrimSuffix(repo.CloneURL, ".git"),
+		"{full_name}", repo.FullName,
+		"{owner}", repo.Owner,
+		"{name}", repo.githubName(),
+	).Replace(c.config.DescriptionSuffix)
+	if repo.Description == "" {
+		return suffix
+	}
+	return repo.Description + " " + suffix
+}
+
+// addMergeSettings copies the allowed merge methods of a GitHub repository to
+// opt. GitHub only returns them when a repository is fetched individually.
+func (c *Client) addMergeSettings(ctx context.Context, repo *GitHubRepo, opt *EditRepoOption) error {
+	c.merges.mu.Lock()
+	ghRepo, ok := c.merges.repos[repo.FullName]
+	c.merges.mu.Unlock()
+	if !ok {
+		var err error
+		if ghRepo, _, err = c.githubFor(repo).Repositories.Get(ctx, repo.Owner, repo.githubName()); err != nil {
+			return err
+		}
+		c.merges.mu.Lock()
+		c.merges.repos[repo.FullName] = ghRepo
+		c.merges.mu.Unlock()
+	}
+	if ghRepo.AllowMergeCommit == nil {
+		// The token can't see the repository settings
+		return nil
+	}
+
+	merge, squash, rebase := ghRepo.GetAllowMergeCommit(), ghRepo.GetAllowSquashMerge(), ghRepo.GetAllowRebaseMerge()
+	deleteBranch := ghRepo.GetDeleteBranchOnMerge()
+	opt.AllowMergeCommits = &merge
+	opt.AllowSquashMerge = &squash
+	opt.AllowRebase = &rebase
+	opt.DefaultDeleteBranchAfterMerge = &deleteBranch
+
+	// GitHub has no default merge method; its merge button prefers them in this order
+	for _, style := range []struct {
+		name    string
+		allowed bool
+	}{{"merge", merge}, {"squash", squash}, {"rebase", rebase}} {
+		if style.allowed {
+			name := style.name
+			opt.DefaultMergeStyle = &name
+			break
+		}
+	}
+	return nil
+}
+
+// UpdateRepoSettings brings the visibility, description, default branch,
+// template flag and enabled units of an existing Forgejo repository in line
+// with GitHub, and its merge settings with --on-exists=update-settings
+func (c *Client) UpdateRepoSettings(ctx context.Context, repo *GitHubRepo) error {
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would update settings: %s\n", repo.Name)
+		return nil
+	}
+
+	if err := c.EditRepo(ctx, c.ownerFor(repo), repo.Name, c.desiredSettings(ctx, repo, c.config.OnExists == "update-settings")); err != nil {
+		return err
+	}
+	if c.config.Verbose {
+		fmt.Printf("🔧 Updated settings: %s\n", repo.Name)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdownContexts returns a context that is cancelled on the first SIGINT or
+// SIGTERM and one that is cancelled on the second. Work in flight uses the
+// second one, so it can finish after the first signal while nothing new starts.
+func shutdownContexts() (stopping, aborting context.Context) {
+	stopping, stop := context.WithCancel(context.Background())
+	aborting, abort := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("\n🛑 Stopping: finishing repositories in progress, interrupt again to abort them")
+		stop()
+		<-signals
+		fmt.Println("\n🛑 Aborting repositories in progress")
+		abort()
+	}()
+	return stopping, aborting
+}
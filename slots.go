@@ -0,0 +1,25 @@
+package main
+
+import "context"
+
+// slots limits how many operations of one kind run against a Forgejo
+// instance at the same time
+type slots chan struct{}
+
+func newSlots(n int) slots {
+	return make(slots, max(n, 1))
+}
+
+// acquire waits for a free slot or until ctx is done
+func (s slots) acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s slots) release() {
+	<-s
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// StarAndWatch stars and/or watches a newly created repository with the token
+// account and, with an admin token, every account in --star-watch-users
+func (c *Client) StarAndWatch(ctx context.Context, owner, name string) error {
+	if !c.config.Star && !c.config.Watch {
+		return nil
+	}
+
+	accounts := append([]string{""}, c.config.StarWatchUsers...)
+	for _, account := range accounts {
+		label := account
+		if label == "" {
+			label = c.config.ForgejoUser
+		}
+		if c.config.Star {
+			status, body, err := c.forgejoRequestAs(ctx, account, "PUT", "/user/starred/"+url.PathEscape(owner)+"/"+url.PathEscape(name), nil)
+			if err != nil {
+				return fmt.Errorf("failed to star %s as %s: %w", name, label, err)
+			}
+			if status != http.StatusNoContent {
+				return forgejoError(fmt.Sprintf("starring %s as %s (sudo)", name, label), status, body)
+			}
+		}
+		if c.config.Watch {
+			status, body, err := c.forgejoRequestAs(ctx, account, "PUT", repoPath(owner, name, "subscription"), nil)
+			if err != nil {
+				return fmt.Errorf("failed to watch %s as %s: %w", name, label, err)
+			}
+			if status != http.StatusOK {
+				return forgejoError(fmt.Sprintf("watching %s as %s (sudo)", name, label), status, body)
+			}
+		}
+	}
+	if c.config.Verbose {
+		fmt.Printf("⭐ Starred/watched %s for %d accounts\n", name, len(accounts))
+	}
+	return nil
+}
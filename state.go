@@ -0,0 +1,509 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RepoState holds what was recorded about a repository at the last run
+type RepoState struct {
+	ID       int64                   `json:"id,omitempty"`
+	Branches map[string]string       `json:"branches,omitempty"`
+	Targets  map[string]*TargetState `json:"targets,omitempty"`
+}
+
+// TargetState records the mirror of a repository on one Forgejo target
+type TargetState struct {
+	Owner       string    `json:"owner"`
+	Name        string    `json:"name"`
+	Mode        string    `json:"mode"`
+	Interval    string    `json:"interval,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	PushedAt    string    `json:"pushed_at,omitempty"` // GitHub push time the mirror was last created or synced at
+	LastStatus  string    `json:"last_status"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastRun     time.Time `json:"last_run"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	// Issues synced to a repository that isn't a mirror
+	Issues *IssueSyncState `json:"issues,omitempty"`
+	// GitHub Discussions imported as issues, by discussion number
+	Discussions *IssueSyncState `json:"discussions,omitempty"`
+	// Whether the GitHub links in migrated issues point at Forgejo
+	ReferencesRewritten bool `json:"references_rewritten,omitempty"`
+	// Whether the repository is the empty one --empty-repos=create made
+	EmptyRepo bool `json:"empty_repo,omitempty"`
+}
+
+// IssueSyncState records how far the issues of a repository were synced and
+// which Forgejo issues and comments the sync created for GitHub ones
+type IssueSyncState struct {
+	Since    time.Time       `json:"since"`
+	Issues   map[int]int64   `json:"issues,omitempty"`   // GitHub number to Forgejo index
+	Comments map[int64]int64 `json:"comments,omitempty"` // GitHub comment ID to Forgejo comment ID
+}
+
+// RunState records when the current or last run started and whether it
+// finished, so an interrupted run can be resumed, and the repositories it
+// created, so they can be rolled back
+type RunState struct {
+	ID         string        `json:"id,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at,omitempty"`
+	Created    []CreatedRepo `json:"created,omitempty"`
+}
+
+// CreatedRepo is a Forgejo repository created by a run
+type CreatedRepo struct {
+	Target string `json:"target"`
+	Mirror string `json:"mirror"` // Forgejo full name
+	Repo   string `json:"repo"`   // GitHub full name
+}
+
+// runHistory is how many finished runs are kept in the state file for rollback
+const runHistory = 10
+
+// runID identifies a run by its start time
+func runID(start time.Time) string {
+	return start.UTC().Format("20060102-150405")
+}
+
+// State is the persistent record of previous runs, keyed by GitHub full name
+type State struct {
+	LastRun time.Time `json:"last_run,omitempty"`
+	Run     *RunState `json:"run,omitempty"`
+	// Earlier runs, oldest first
+	History []*RunState `json:"history,omitempty"`
+	// Number of repositories the last GitHub listing returned, before filters
+	Listed int                   `json:"listed,omitempty"`
+	Repos  map[string]*RepoState `json:"repos"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// loadState reads the state file, returning an empty state if it doesn't exist yet
+func loadState(path string) (*State, error) {
+	state := &State{Repos: make(map[string]*RepoState), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+	if state.Repos == nil {
+		state.Repos = make(map[string]*RepoState)
+	}
+	return state, nil
+}
+
+// BranchTips returns the branch tips recorded for a repository
+func (s *State) BranchTips(fullName string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if repo, ok := s.Repos[fullName]; ok {
+		return repo.Branches
+	}
+	return nil
+}
+
+// SetBranchTips records the current branch tips of a repository
+func (s *State) SetBranchTips(fullName string, tips map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[fullName]
+	if !ok {
+		repo = &RepoState{}
+		s.Repos[fullName] = repo
+	}
+	repo.Branches = tips
+}
+
+// RecordResult records the result of mirroring a repository to a target. The
+// detail is the skip reason or error message.
+func (s *State) RecordResult(repo *GitHubRepo, target *Client, result Result, detail string, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repoState, ok := s.Repos[repo.FullName]
+	if !ok {
+		repoState = &RepoState{}
+		s.Repos[repo.FullName] = repoState
+	}
+	repoState.ID = repo.ID
+	if repoState.Targets == nil {
+		repoState.Targets = make(map[string]*TargetState)
+	}
+	targetState, ok := repoState.Targets[target.name]
+	if !ok {
+		targetState = &TargetState{}
+		repoState.Targets[target.name] = targetState
+	}
+
+	targetState.LastRun = now
+	s.LastRun = now
+	if detail == skipUnchanged {
+		// Still in the state recorded at the last successful run
+		return
+	}
+
+	targetState.Owner = target.ownerFor(repo)
+	targetState.Name = repo.Name
+	targetState.Mode = target.config.Mode
+	targetState.Interval = target.mirrorIntervalFor(repo)
+	targetState.LastError = ""
+	switch {
+	case result.Succeeded():
+		targetState.LastStatus = "ok"
+		targetState.LastSuccess = now
+		targetState.Fingerprint = target.fingerprint(repo)
+		if result == ResultCreated || result == ResultSynced {
+			targetState.PushedAt = repo.PushedAt
+		}
+	case result == ResultSkipped:
+		targetState.LastStatus = detail
+	case result == ResultTimedOut || result == ResultConflict:
+		targetState.LastStatus = result.String()
+		targetState.LastError = detail
+	default:
+		targetState.LastStatus = "failed"
+		targetState.LastError = detail
+	}
+}
+
+// FullNamesByID returns the full name every repository was last recorded under,
+// keyed by its GitHub ID
+func (s *State) FullNamesByID() map[int64]string {
+	names := make(map[int64]string)
+	if s == nil {
+		return names
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for fullName, repo := range s.Repos {
+		if repo.ID != 0 {
+			names[repo.ID] = fullName
+		}
+	}
+	return names
+}
+
+// Target returns what was recorded about a repository on a target
+func (s *State) Target(fullName, target string) (TargetState, bool) {
+	if s == nil {
+		return TargetState{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[fullName]
+	if !ok || repo.Targets[target] == nil {
+		return TargetState{}, false
+	}
+	return *repo.Targets[target], true
+}
+
+// RenameTarget moves the record of a target from a repository's former full
+// name to its current one, dropping the former entry once no target is left
+func (s *State) RenameTarget(oldFullName, newFullName, target string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.Repos[oldFullName]
+	if !ok || old.Targets[target] == nil {
+		return
+	}
+	repo, ok := s.Repos[newFullName]
+	if !ok {
+		repo = &RepoState{ID: old.ID, Branches: old.Branches}
+		s.Repos[newFullName] = repo
+	}
+	if repo.Targets == nil {
+		repo.Targets = make(map[string]*TargetState)
+	}
+	repo.Targets[target] = old.Targets[target]
+	delete(old.Targets, target)
+	if len(old.Targets) == 0 {
+		delete(s.Repos, oldFullName)
+	}
+}
+
+// IssueSync returns a copy of what was recorded about syncing the issues of a
+// repository to a target, or nil if they were never synced
+func (s *State) IssueSync(fullName, target string) *IssueSyncState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[fullName]
+	if !ok || repo.Targets[target] == nil || repo.Targets[target].Issues == nil {
+		return nil
+	}
+	return repo.Targets[target].Issues.clone()
+}
+
+// clone returns a deep copy, so the sync can keep working on its own while
+// the state is saved
+func (i *IssueSyncState) clone() *IssueSyncState {
+	synced := &IssueSyncState{Since: i.Since, Issues: make(map[int]int64), Comments: make(map[int64]int64)}
+	for number, index := range i.Issues {
+		synced.Issues[number] = index
+	}
+	for id, forgejoID := range i.Comments {
+		synced.Comments[id] = forgejoID
+	}
+	return synced
+}
+
+// SetIssueSync records the progress of syncing the issues of a repository to
+// a target
+func (s *State) SetIssueSync(repo *GitHubRepo, target string, synced *IssueSyncState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targetState(repo, target).Issues = synced.clone()
+}
+
+// DiscussionSync returns a copy of the progress of importing the discussions
+// of a repository to a target, or nil if none were imported yet
+func (s *State) DiscussionSync(fullName, target string) *IssueSyncState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[fullName]
+	if !ok || repo.Targets[target] == nil || repo.Targets[target].Discussions == nil {
+		return nil
+	}
+	return repo.Targets[target].Discussions.clone()
+}
+
+// SetDiscussionSync records the progress of importing the discussions of a
+// repository to a target
+func (s *State) SetDiscussionSync(repo *GitHubRepo, target string, synced *IssueSyncState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targetState(repo, target).Discussions = synced.clone()
+}
+
+// SetReferencesRewritten records whether the GitHub links in the migrated
+// issues of a repository were rewritten
+func (s *State) SetReferencesRewritten(repo *GitHubRepo, target string, rewritten bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targetState(repo, target).ReferencesRewritten = rewritten
+}
+
+// SetEmptyRepo records whether the Forgejo repository of a repository is the
+// empty one created for it while it had no commits
+func (s *State) SetEmptyRepo(repo *GitHubRepo, target string, empty bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targetState(repo, target).EmptyRepo = empty
+}
+
+// targetState returns the state of a repository on a target, creating it if
+// needed. The caller holds s.mu.
+func (s *State) targetState(repo *GitHubRepo, target string) *TargetState {
+	repoState, ok := s.Repos[repo.FullName]
+	if !ok {
+		repoState = &RepoState{ID: repo.ID}
+		s.Repos[repo.FullName] = repoState
+	}
+	if repoState.Targets == nil {
+		repoState.Targets = make(map[string]*TargetState)
+	}
+	if repoState.Targets[target] == nil {
+		repoState.Targets[target] = &TargetState{}
+	}
+	return repoState.Targets[target]
+}
+
+// StartRun records the start of a run. When resuming a run that didn't
+// finish, its start is kept and returned so repositories it already handled
+// can be skipped; otherwise the returned time is zero.
+func (s *State) StartRun(now time.Time, resume bool) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resume && s.Run != nil && s.Run.FinishedAt.IsZero() {
+		return s.Run.StartedAt
+	}
+	if s.Run != nil {
+		s.History = append(s.History, s.Run)
+		if len(s.History) > runHistory {
+			s.History = s.History[len(s.History)-runHistory:]
+		}
+	}
+	s.Run = &RunState{ID: runID(now), StartedAt: now}
+	return time.Time{}
+}
+
+// RunID returns the ID of the current run
+func (s *State) RunID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Run == nil {
+		return ""
+	}
+	if s.Run.ID == "" {
+		// Recorded before runs had IDs
+		s.Run.ID = runID(s.Run.StartedAt)
+	}
+	return s.Run.ID
+}
+
+// RecordCreated records a repository created by the current run
+func (s *State) RecordCreated(created CreatedRepo) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Run != nil {
+		s.Run.Created = append(s.Run.Created, created)
+	}
+}
+
+// FindRun returns the current or an earlier run by its ID
+func (s *State) FindRun(id string) *RunState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Run != nil && (s.Run.ID == id || runID(s.Run.StartedAt) == id) {
+		return s.Run
+	}
+	for _, run := range s.History {
+		if run.ID == id || runID(run.StartedAt) == id {
+			return run
+		}
+	}
+	return nil
+}
+
+// Runs returns the current and earlier runs, newest first
+func (s *State) Runs() []RunState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []RunState
+	if s.Run != nil {
+		runs = append(runs, *s.Run)
+	}
+	for i := len(s.History) - 1; i >= 0; i-- {
+		runs = append(runs, *s.History[i])
+	}
+	return runs
+}
+
+// RolledBack records that a repository created by a run was deleted again.
+// Its target record is dropped too, so the next run migrates it from scratch.
+func (s *State) RolledBack(run *RunState, created CreatedRepo) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run != nil {
+		for i, c := range run.Created {
+			if c == created {
+				run.Created = append(run.Created[:i], run.Created[i+1:]...)
+				break
+			}
+		}
+	}
+	if repo, ok := s.Repos[created.Repo]; ok {
+		delete(repo.Targets, created.Target)
+		if len(repo.Targets) == 0 && len(repo.Branches) == 0 {
+			delete(s.Repos, created.Repo)
+		}
+	}
+}
+
+// FinishRun records that the current run completed
+func (s *State) FinishRun(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Run != nil {
+		s.Run.FinishedAt = now
+	}
+}
+
+// Handled reports whether a repository was mirrored to every target, or
+// skipped as empty, since the given time
+func (s *State) Handled(fullName string, targets []*Client, since time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.Repos[fullName]
+	if !ok {
+		return false
+	}
+	for _, target := range targets {
+		t := repo.Targets[target.name]
+		if t == nil || t.LastRun.Before(since) || (t.LastStatus != "ok" && t.LastStatus != skipEmpty) {
+			return false
+		}
+	}
+	return true
+}
+
+// PreviousListed returns the size of the GitHub listing recorded by the last run
+func (s *State) PreviousListed() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Listed
+}
+
+// SetListed records the size of the current GitHub listing
+func (s *State) SetListed(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Listed = n
+}
+
+const (
+	// checkpointEvery and checkpointInterval bound how much progress a crashed
+	// run loses: the state is saved after this many results or this long
+	// after the last save, whichever comes first. Saving after every result
+	// rewrites the whole file thousands of times on large accounts.
+	checkpointEvery    = 25
+	checkpointInterval = 5 * time.Second
+)
+
+// Save writes the state file atomically
+func (s *State) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
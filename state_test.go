@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateHandled(t *testing.T) {
+	since := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := since.Add(-time.Hour), since.Add(time.Hour)
+	targets := []*Client{{name: "primary"}, {name: "backup"}}
+	state := &State{Repos: map[string]*RepoState{
+		"acme/done": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: "ok", LastRun: after},
+			"backup":  {LastStatus: "ok", LastRun: after},
+		}},
+		"acme/empty": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: skipEmpty, LastRun: after},
+			"backup":  {LastStatus: skipEmpty, LastRun: after},
+		}},
+		"acme/one-target": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: "ok", LastRun: after},
+		}},
+		"acme/failed": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: "ok", LastRun: after},
+			"backup":  {LastStatus: "failed", LastRun: after},
+		}},
+		"acme/earlier-run": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: "ok", LastRun: before},
+			"backup":  {LastStatus: "ok", LastRun: after},
+		}},
+		"acme/migrating": {Targets: map[string]*TargetState{
+			"primary": {LastStatus: skipMigrating, LastRun: after},
+			"backup":  {LastStatus: "ok", LastRun: after},
+		}},
+	}}
+
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"acme/done", true},
+		{"acme/empty", true},
+		{"acme/one-target", false},
+		{"acme/failed", false},
+		{"acme/earlier-run", false},
+		{"acme/migrating", false},
+		{"acme/unknown", false},
+	}
+	for _, tt := range tests {
+		if got := state.Handled(tt.repo, targets, since); got != tt.want {
+			t.Errorf("Handled(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
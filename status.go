@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// runStatus prints the mirror status recorded in the state file without
+// calling GitHub or Forgejo and returns the exit code
+func runStatus(config *Config, client *Client) int {
+	if client.state == nil {
+		log.Fatal("The status command requires a state file (--state-file or STATE_FILE)")
+	}
+	state := client.state
+	if state.LastRun.IsZero() {
+		fmt.Printf("📋 No runs recorded in %s yet\n", config.StateFile)
+		return 0
+	}
+	fmt.Printf("📋 Mirror status from %s (last run %s ago)\n\n", config.StateFile, client.clock.Since(state.LastRun).Round(time.Minute))
+
+	names := make([]string, 0, len(state.Repos))
+	for name := range state.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tracked, healthy, failing, stale int
+	for _, name := range names {
+		targetNames := make([]string, 0, len(state.Repos[name].Targets))
+		for target := range state.Repos[name].Targets {
+			targetNames = append(targetNames, target)
+		}
+		sort.Strings(targetNames)
+
+		for _, target := range targetNames {
+			t := state.Repos[name].Targets[target]
+			tracked++
+			line := fmt.Sprintf("%s → %s/%s (%s)", name, t.Owner, t.Name, target)
+			switch {
+			case t.LastRun.Before(state.LastRun):
+				// Filtered out or gone from GitHub since
+				stale++
+				fmt.Printf("💤 %s, not part of the last run, last seen %s ago\n", line, client.clock.Since(t.LastRun).Round(time.Minute))
+			case t.LastStatus == "failed" || t.LastStatus == ResultTimedOut.String() || t.LastStatus == ResultConflict.String():
+				failing++
+				if t.LastSuccess.IsZero() {
+					fmt.Printf("❌ %s, never succeeded: %s\n", line, t.LastError)
+				} else {
+					fmt.Printf("❌ %s, last success %s ago: %s\n", line, client.clock.Since(t.LastSuccess).Round(time.Minute), t.LastError)
+				}
+			case t.LastStatus == "ok":
+				healthy++
+				fmt.Printf("✅ %s, %s, last success %s ago\n", line, t.Mode, client.clock.Since(t.LastSuccess).Round(time.Minute))
+			default:
+				fmt.Printf("⏭️  %s, skipped (%s)\n", line, t.LastStatus)
+			}
+		}
+	}
+
+	fmt.Printf("\n📊 Status Summary:\n")
+	fmt.Printf("   Tracked: %d\n", tracked)
+	fmt.Printf("   Healthy: %d\n", healthy)
+	fmt.Printf("   Failing: %d\n", failing)
+	fmt.Printf("   Not in last run: %d\n", stale)
+	if failing > 0 {
+		return 1
+	}
+	return 0
+}
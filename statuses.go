@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// checkStates maps the conclusions of GitHub check runs to Forgejo commit
+// status states. Skipped runs aren't copied.
+var checkStates = map[string]string{
+	"success":         "success",
+	"neutral":         "success",
+	"failure":         "failure",
+	"timed_out":       "failure",
+	"startup_failure": "failure",
+	"action_required": "failure",
+	"cancelled":       "error",
+	"stale":           "error",
+}
+
+// commitStatus is a commit status on Forgejo, named by its context
+type commitStatus struct {
+	State       string `json:"status"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// CopyStatuses copies the commit statuses and check run results GitHub has
+// for the commits at the tips of the branches matching --copy-statuses on
+// Forgejo, so the copy shows whether CI passed while CI still runs on GitHub.
+// Contexts keep their GitHub names, which branch protection rules can require.
+func (c *Client) CopyStatuses(ctx context.Context, repo *GitHubRepo) error {
+	owner := c.ownerFor(repo)
+	branches, err := forgejoList[struct {
+		Name   string `json:"name"`
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}](ctx, c, repoPath(owner, repo.Name, "branches"), "branches of "+repo.Name)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[string]bool)
+	for _, branch := range branches {
+		if done[branch.Commit.ID] || !c.copiesStatuses(branch.Name) {
+			continue
+		}
+		done[branch.Commit.ID] = true
+		statuses, err := c.githubStatuses(ctx, repo, branch.Commit.ID)
+		if err != nil {
+			return err
+		}
+		if err := c.setStatuses(ctx, owner, repo, branch.Commit.ID, statuses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copiesStatuses reports whether a branch matches one of the --copy-statuses
+// patterns
+func (c *Client) copiesStatuses(branch string) bool {
+	for _, pattern := range c.config.StatusBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// githubStatuses returns the latest commit status of every context and the
+// result of every check run GitHub has for a commit. Commits GitHub doesn't
+// know, such as ones pushed to Forgejo directly, have none.
+func (c *Client) githubStatuses(ctx context.Context, repo *GitHubRepo, sha string) ([]commitStatus, error) {
+	gh := c.githubFor(repo)
+	var statuses []commitStatus
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		combined, resp, err := gh.Repositories.GetCombinedStatus(ctx, repo.Owner, repo.githubName(), sha, opts)
+		if err != nil {
+			err = githubError(fmt.Sprintf("fetching statuses of %.7s in %s", sha, repo.FullName), err)
+			var notFound *NotFoundError
+			var apiErr *APIError
+			if errors.As(err, &notFound) || (errors.As(err, &apiErr) && apiErr.Status == http.StatusUnprocessableEntity) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, status := range combined.Statuses {
+			statuses = append(statuses, commitStatus{
+				State:       status.GetState(),
+				TargetURL:   status.GetTargetURL(),
+				Description: status.GetDescription(),
+				Context:     status.GetContext(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	checkOpts := &github.ListCheckRunsOptions{Filter: github.String("latest"), ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		result, resp, err := gh.Checks.ListCheckRunsForRef(ctx, repo.Owner, repo.githubName(), sha, checkOpts)
+		if err != nil {
+			return nil, githubError(fmt.Sprintf("listing check runs of %.7s in %s", sha, repo.FullName), err)
+		}
+		for _, run := range result.CheckRuns {
+			state := "pending"
+			if run.GetStatus() == "completed" {
+				var ok bool
+				if state, ok = checkStates[run.GetConclusion()]; !ok {
+					continue
+				}
+			}
+			description := run.GetOutput().GetTitle()
+			if description == "" {
+				description = run.GetStatus()
+				if run.GetConclusion() != "" {
+					description = run.GetConclusion()
+				}
+			}
+			statuses = append(statuses, commitStatus{
+				State:       state,
+				TargetURL:   run.GetHTMLURL(),
+				Description: description,
+				Context:     run.GetName(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		checkOpts.Page = resp.NextPage
+	}
+	return statuses, nil
+}
+
+// setStatuses creates the commit statuses on Forgejo that differ from the
+// latest ones of their context there, so repeated runs don't pile up copies
+func (c *Client) setStatuses(ctx context.Context, owner string, repo *GitHubRepo, sha string, statuses []commitStatus) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, repo.Name, "commits", sha, "status"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch statuses of %.7s: %w", sha, err)
+	}
+	if status != http.StatusOK {
+		return forgejoError(fmt.Sprintf("fetching statuses of %.7s in %s", sha, repo.Name), status, body)
+	}
+	var combined struct {
+		Statuses []commitStatus `json:"statuses"`
+	}
+	if err := json.Unmarshal(body, &combined); err != nil {
+		return fmt.Errorf("failed to decode statuses: %w", err)
+	}
+	latest := make(map[string]commitStatus)
+	for _, existing := range combined.Statuses {
+		latest[existing.Context] = existing
+	}
+
+	var copied int
+	for _, s := range statuses {
+		if latest[s.Context] == s {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would set status %s of %.7s in %s to %s\n", s.Context, sha, repo.Name, s.State)
+			continue
+		}
+		payload := map[string]string{"state": s.State, "target_url": s.TargetURL, "description": s.Description, "context": s.Context}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "statuses", sha), payload)
+		if err != nil {
+			return fmt.Errorf("failed to set status %s: %w", s.Context, err)
+		}
+		if status != http.StatusCreated {
+			return forgejoError(fmt.Sprintf("setting status %s of %.7s in %s", s.Context, sha, repo.Name), status, body)
+		}
+		copied++
+	}
+	if c.config.Verbose && copied > 0 {
+		fmt.Printf("🚦 Copied %d commit statuses of %.7s to %s\n", copied, sha, repo.Name)
+	}
+	return nil
+}
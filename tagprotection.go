@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// CopyTagProtection creates a Forgejo tag protection rule for every tag
+// pattern protected on GitHub, through the legacy tag protection settings or a
+// tag ruleset. Like on GitHub, where only admins may create protected tags,
+// only the owners of the Forgejo organization, or the owning user, may push
+// matching tags.
+func (c *Client) CopyTagProtection(ctx context.Context, repo *GitHubRepo) error {
+	patterns, err := c.protectedTagPatterns(ctx, repo)
+	if err != nil || len(patterns) == 0 {
+		return err
+	}
+
+	owner, isOrg := c.resolveOwner(repo)
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, repo.Name, "tag_protections"), nil)
+	if err != nil {
+		return fmt.Errorf("failed to list tag protections: %w", err)
+	}
+	if status != http.StatusOK {
+		return forgejoError("listing tag protections of "+repo.Name, status, body)
+	}
+	var existing []struct {
+		NamePattern string `json:"name_pattern"`
+	}
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return fmt.Errorf("failed to decode tag protections: %w", err)
+	}
+	protected := make(map[string]bool)
+	for _, rule := range existing {
+		protected[rule.NamePattern] = true
+	}
+
+	for _, pattern := range patterns {
+		if protected[pattern] {
+			continue
+		}
+		protected[pattern] = true
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would protect tags matching %s on %s\n", pattern, repo.Name)
+			continue
+		}
+		payload := map[string]interface{}{"name_pattern": pattern}
+		if isOrg {
+			payload["whitelist_teams"] = []string{"Owners"}
+		} else {
+			payload["whitelist_usernames"] = []string{owner}
+		}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "tag_protections"), payload)
+		if err != nil {
+			return fmt.Errorf("failed to protect tags matching %s: %w", pattern, err)
+		}
+		if status != http.StatusCreated {
+			return forgejoError("protecting tags matching "+pattern, status, body)
+		}
+		if c.config.Verbose {
+			fmt.Printf("🔒 Protected tags matching %s on %s\n", pattern, repo.Name)
+		}
+	}
+	return nil
+}
+
+// protectedTagPatterns returns the tag patterns protected on GitHub
+func (c *Client) protectedTagPatterns(ctx context.Context, repo *GitHubRepo) ([]string, error) {
+	var patterns []string
+	seen := make(map[string]bool)
+	add := func(pattern string) {
+		if pattern != "" && !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	// GitHub retired the legacy settings in favour of rulesets, so they may be gone
+	legacy, _, err := c.githubFor(repo).Repositories.ListTagProtection(ctx, repo.Owner, repo.githubName())
+	if err != nil {
+		err = githubError("listing tag protections of "+repo.FullName, err)
+		var notFound *NotFoundError
+		var apiErr *APIError
+		if !errors.As(err, &notFound) && !(errors.As(err, &apiErr) && apiErr.Status == http.StatusGone) {
+			return nil, err
+		}
+	}
+	for _, protection := range legacy {
+		add(protection.GetPattern())
+	}
+
+	rulesets, err := c.githubRulesets(ctx, repo, "tag")
+	if err != nil {
+		return nil, err
+	}
+	for _, ruleset := range rulesets {
+		for _, pattern := range rulesetPatterns(ruleset, repo) {
+			add(pattern)
+		}
+	}
+	return patterns, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Target is an additional Forgejo instance every repository is mirrored to
+type Target struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	Token        string `json:"token"`
+	User         string `json:"user"`
+	Organization string `json:"organization"`
+	Type         string `json:"type"`
+}
+
+// loadTargets reads the list of additional targets from a JSON file
+func loadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to decode targets file: %w", err)
+	}
+
+	for i := range targets {
+		t := &targets[i]
+		if t.URL == "" || t.Token == "" {
+			return nil, fmt.Errorf("target %d: url and token are required", i+1)
+		}
+		if t.User == "" && t.Organization == "" {
+			return nil, fmt.Errorf("target %d: user or organization is required", i+1)
+		}
+		if t.Type != "" && t.Type != "forgejo" && t.Type != "gitea" {
+			return nil, fmt.Errorf("target %d: invalid type %q (must be 'forgejo' or 'gitea')", i+1, t.Type)
+		}
+		t.URL = strings.TrimSuffix(t.URL, "/")
+		if t.Name == "" {
+			t.Name = targetName(t.URL)
+		}
+	}
+	return targets, nil
+}
+
+// targetName derives a display name for a Forgejo instance from its URL
+func targetName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// forTarget returns a client for another Forgejo instance that shares the
+// GitHub client, state, clock and merge settings cache of c
+func (c *Client) forTarget(t Target) *Client {
+	config := *c.config
+	config.ForgejoURL = t.URL
+	config.ForgejoToken = t.Token
+	config.ForgejoUser = t.User
+	config.Organization = t.Organization
+	if t.Type != "" {
+		config.TargetType = t.Type
+	}
+
+	client := *c
+	client.config = &config
+	client.name = t.Name
+	client.knownOrgs = newOrgCache()
+	client.quota = newQuotaTracker()
+	client.plan = newPlan()
+	client.migrations = newSlots(config.ConcurrentMigrate)
+	client.syncs = newSlots(config.ConcurrentSync)
+	return &client
+}
+
+// targets returns clients for every configured Forgejo instance, starting
+// with the primary one
+func (c *Client) targets() []*Client {
+	clients := []*Client{c}
+	for _, t := range c.config.ExtraTargets {
+		clients = append(clients, c.forTarget(t))
+	}
+	return clients
+}
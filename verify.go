@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// verifyResult holds the outcome of verifying a single mirror
+type verifyResult struct {
+	repo       string
+	checked    int
+	lfsObjects int
+	lfsBytes   int64
+	problems   []string
+}
+
+// runVerify checks every GitHub repository against its Forgejo mirror and
+// returns the process exit code
+func runVerify(ctx context.Context, config *Config, client *Client) int {
+	fmt.Printf("🔍 Verifying mirrors on %s\n", config.ForgejoURL)
+	repos, err := client.GetGitHubRepos(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch GitHub repositories: %v", err)
+	}
+	fmt.Printf("   Checking %d repositories\n\n", len(repos))
+
+	results := make([]verifyResult, len(repos))
+	forEach(ctx, config.Concurrent, repos, func(i int, r *GitHubRepo) {
+		results[i] = client.VerifyRepo(ctx, r)
+	}, func(i int, r *GitHubRepo) {
+		results[i] = verifyResult{repo: r.Name, problems: []string{"not verified, the run was interrupted"}}
+	})
+
+	var failed int
+	for _, result := range results {
+		if len(result.problems) == 0 {
+			var details []string
+			if result.checked > 0 {
+				details = append(details, fmt.Sprintf("%d sampled files match", result.checked))
+			}
+			if result.lfsObjects > 0 {
+				details = append(details, fmt.Sprintf("%d LFS objects, %.1f MB", result.lfsObjects, float64(result.lfsBytes)/1e6))
+			}
+			if len(details) > 0 {
+				fmt.Printf("✅ %s (%s)\n", result.repo, strings.Join(details, ", "))
+			} else {
+				fmt.Printf("✅ %s\n", result.repo)
+			}
+			continue
+		}
+		failed++
+		fmt.Printf("❌ %s\n", result.repo)
+		for _, problem := range result.problems {
+			fmt.Printf("   - %s\n", problem)
+		}
+	}
+
+	fmt.Printf("\n📊 Verification Summary:\n")
+	fmt.Printf("   Verified: %d\n", len(results)-failed)
+	fmt.Printf("   Problems: %d\n", failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// VerifyRepo checks that the mirror of a GitHub repository exists on Forgejo,
+// that its default branch is up to date and, if enabled, that a random sample
+// of its files and its LFS objects match GitHub
+func (c *Client) VerifyRepo(ctx context.Context, repo *GitHubRepo) verifyResult {
+	result := verifyResult{repo: repo.Name}
+
+	forgejoRepo, err := c.GetForgejoRepo(ctx, c.ownerFor(repo), repo.Name)
+	if err != nil {
+		result.problems = append(result.problems, err.Error())
+		return result
+	}
+	if forgejoRepo == nil {
+		result.problems = append(result.problems, "mirror does not exist on Forgejo")
+		return result
+	}
+
+	// Regular repositories aren't synced, so they are expected to drift
+	if forgejoRepo.Mirror {
+		if problem := c.checkDrift(ctx, repo); problem != "" {
+			result.problems = append(result.problems, problem)
+		}
+	}
+	if c.config.VerifyRefs != "" {
+		result.problems = append(result.problems, c.verifyRefs(ctx, repo)...)
+	}
+	if c.config.SampleFiles > 0 {
+		checked, problems := c.sampleFiles(ctx, repo)
+		result.checked = checked
+		result.problems = append(result.problems, problems...)
+	}
+	if c.config.VerifyMilestones && !forgejoRepo.Mirror {
+		result.problems = append(result.problems, c.verifyMilestones(ctx, repo)...)
+	}
+	if c.config.VerifyLFS {
+		var problems []string
+		result.lfsObjects, result.lfsBytes, problems = c.verifyLFS(ctx, repo)
+		result.problems = append(result.problems, problems...)
+	}
+	return result
+}
+
+// checkDrift compares the tip of the default branch on GitHub and Forgejo and
+// describes a stale or diverged mirror. Mirrors that are behind only by
+// commits younger than their sync interval are considered up to date.
+func (c *Client) checkDrift(ctx context.Context, repo *GitHubRepo) string {
+	if repo.DefaultBranch == "" {
+		return ""
+	}
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	gh := c.githubFor(repo)
+	branch, _, err := gh.Repositories.GetBranch(ctx, owner, name, repo.DefaultBranch, 1)
+	if err != nil {
+		return fmt.Sprintf("failed to fetch %s from GitHub: %v", repo.DefaultBranch, err)
+	}
+	want := branch.GetCommit().GetSHA()
+
+	got, err := c.GetForgejoBranchSHA(ctx, c.ownerFor(repo), repo.Name, repo.DefaultBranch)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("failed to fetch %s from Forgejo: %v", repo.DefaultBranch, err)
+	case got == "":
+		return fmt.Sprintf("%s is missing on Forgejo", repo.DefaultBranch)
+	case got == want:
+		return ""
+	}
+
+	comparison, _, err := gh.Repositories.CompareCommits(ctx, owner, name, got, want, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		// GitHub doesn't know the mirrored commit, so the histories diverged
+		return fmt.Sprintf("%s diverged (GitHub %.7s, Forgejo %.7s)", repo.DefaultBranch, want, got)
+	}
+	if comparison.GetStatus() != "ahead" {
+		return fmt.Sprintf("%s diverged (GitHub %.7s, Forgejo %.7s)", repo.DefaultBranch, want, got)
+	}
+
+	interval := c.mirrorIntervalFor(repo)
+	if interval == "" {
+		interval = "8h" // Forgejo's default
+	}
+	if interval, err := time.ParseDuration(interval); err == nil && len(comparison.Commits) > 0 {
+		oldest := comparison.Commits[0].GetCommit().GetCommitter().GetDate().Time
+		if c.clock.Since(oldest) < interval {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s is %d commits behind GitHub (GitHub %.7s, Forgejo %.7s)", repo.DefaultBranch, comparison.GetAheadBy(), want, got)
+}
+
+// sampleFiles compares the blob hashes of randomly chosen files on the default
+// branch between GitHub and Forgejo
+func (c *Client) sampleFiles(ctx context.Context, repo *GitHubRepo) (int, []string) {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	tree, _, err := c.githubFor(repo).Git.GetTree(ctx, owner, name, repo.DefaultBranch, true)
+	if err != nil {
+		return 0, []string{fmt.Sprintf("failed to list files on GitHub: %v", err)}
+	}
+
+	var blobs []string
+	shas := make(map[string]string)
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			blobs = append(blobs, entry.GetPath())
+			shas[entry.GetPath()] = entry.GetSHA()
+		}
+	}
+	rand.Shuffle(len(blobs), func(i, j int) { blobs[i], blobs[j] = blobs[j], blobs[i] })
+	if len(blobs) > c.config.SampleFiles {
+		blobs = blobs[:c.config.SampleFiles]
+	}
+
+	var problems []string
+	for _, path := range blobs {
+		sha, err := c.GetForgejoFileSHA(ctx, c.ownerFor(repo), repo.Name, path, repo.DefaultBranch)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("failed to fetch %s from Forgejo: %v", path, err))
+		case sha == "":
+			problems = append(problems, fmt.Sprintf("%s is missing on Forgejo", path))
+		case sha != shas[path]:
+			problems = append(problems, fmt.Sprintf("%s differs (GitHub %.7s, Forgejo %.7s)", path, shas[path], sha))
+		}
+	}
+	return len(blobs), problems
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// reportVisibility announces that a mirror's visibility was changed to match GitHub
+func reportVisibility(name string, private bool) {
+	if private {
+		fmt.Printf("🔒 Made %s private: it was made private on GitHub\n", name)
+	} else {
+		fmt.Printf("🔓 Made %s public: it was made public on GitHub\n", name)
+	}
+}
+
+// HidePrivateMirrors makes public mirrors private when their GitHub repository
+// has been made private. Without --include-private such repositories drop out
+// of the run, so their mirrors would otherwise stay public.
+func (c *Client) HidePrivateMirrors(ctx context.Context, forgejoRepos []*ForgejoRepo) {
+	for _, mirror := range forgejoRepos {
+		if !mirror.Mirror || mirror.Private {
+			continue
+		}
+		owner, name, ok := githubRepoFromURL(mirror.OriginalURL)
+		if !ok {
+			continue
+		}
+		if listed, private := c.listing.listed(owner, name); !listed || !private {
+			continue
+		}
+
+		forgejoOwner, _, _ := strings.Cut(mirror.FullName, "/")
+		if c.config.DryRun {
+			c.plan.add(PlanAction{
+				Action:  "update",
+				Target:  c.name,
+				Repo:    owner + "/" + name,
+				Mirror:  mirror.FullName,
+				Note:    "made private on GitHub",
+				Changes: []SettingChange{{Setting: "private", From: "false", To: "true"}},
+			})
+			continue
+		}
+		private := true
+		if err := c.EditRepo(ctx, forgejoOwner, mirror.Name, &EditRepoOption{Private: &private}); err != nil {
+			log.Printf("Warning: Failed to make %s private: %v", mirror.FullName, err)
+			continue
+		}
+		reportVisibility(mirror.FullName, true)
+	}
+}
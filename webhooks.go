@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// webhookEvents maps GitHub webhook events to the Forgejo events that cover
+// them. Forgejo splits some GitHub events into several.
+var webhookEvents = map[string][]string{
+	"push":                        {"push"},
+	"create":                      {"create"},
+	"delete":                      {"delete"},
+	"fork":                        {"fork"},
+	"issues":                      {"issues", "issue_assign", "issue_label", "issue_milestone"},
+	"issue_comment":               {"issue_comment", "pull_request_comment"},
+	"pull_request":                {"pull_request", "pull_request_assign", "pull_request_label", "pull_request_milestone", "pull_request_sync"},
+	"pull_request_review":         {"pull_request_review_approved", "pull_request_review_rejected"},
+	"pull_request_review_comment": {"pull_request_review_comment"},
+	"gollum":                      {"wiki"},
+	"release":                     {"release"},
+	"repository":                  {"repository"},
+	"package":                     {"package"},
+}
+
+// forgejoHook is the part of a Forgejo webhook needed to recognise a copy
+type forgejoHook struct {
+	ID     int64             `json:"id"`
+	Config map[string]string `json:"config"`
+}
+
+// CopyWebhooks creates the webhooks of a GitHub repository on its Forgejo
+// repository, unless one with the same URL already exists. URLs are rewritten
+// with --webhook-url-template. GitHub never reveals webhook secrets, so the
+// copies get --webhook-secret instead.
+func (c *Client) CopyWebhooks(ctx context.Context, repo *GitHubRepo) error {
+	var hooks []*github.Hook
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := c.githubFor(repo).Repositories.ListHooks(ctx, repo.Owner, repo.githubName(), opts)
+		if err != nil {
+			err = githubError("listing webhooks of "+repo.FullName, err)
+			var notFound *NotFoundError
+			var denied *PermissionError
+			if errors.As(err, &notFound) || errors.As(err, &denied) {
+				return fmt.Errorf("reading webhooks needs admin access to the repository and, for classic tokens, the admin:repo_hook scope: %w", err)
+			}
+			return err
+		}
+		hooks = append(hooks, list...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	owner := c.ownerFor(repo)
+	existing, err := forgejoList[forgejoHook](ctx, c, repoPath(owner, repo.Name, "hooks"), "webhooks of "+repo.Name)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool)
+	for _, hook := range existing {
+		present[hook.Config["url"]] = true
+	}
+
+	for _, hook := range hooks {
+		hookURL, _ := hook.Config["url"].(string)
+		if hookURL == "" {
+			continue
+		}
+		target, err := c.webhookURL(repo, hookURL)
+		if err != nil {
+			return err
+		}
+		if present[target] {
+			continue
+		}
+		events, unsupported := forgejoEvents(hook.Events)
+		if len(unsupported) > 0 {
+			fmt.Printf("⚠️  Forgejo has no equivalent of the %s events of the webhook %s of %s\n", strings.Join(unsupported, ", "), target, repo.Name)
+		}
+		if len(events) == 0 {
+			continue
+		}
+		if c.config.DryRun {
+			fmt.Printf("[DRY RUN] Would add webhook %s to %s\n", target, repo.Name)
+			continue
+		}
+
+		contentType, _ := hook.Config["content_type"].(string)
+		if contentType == "" {
+			contentType = "form"
+		}
+		config := map[string]string{"url": target, "content_type": contentType}
+		if c.config.WebhookSecret != "" {
+			config["secret"] = c.config.WebhookSecret
+		}
+		// Forgejo's own payload format is compatible with GitHub's for most consumers
+		payload := map[string]interface{}{"type": "gitea", "config": config, "events": events, "active": hook.GetActive()}
+		status, body, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "hooks"), payload)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook: %w", err)
+		}
+		if status != http.StatusCreated {
+			return forgejoError("creating webhook "+target, status, body)
+		}
+		present[target] = true
+		if c.config.Verbose {
+			fmt.Printf("🪝 Added webhook %s to %s\n", target, repo.Name)
+		}
+	}
+	return nil
+}
+
+// forgejoEvents translates GitHub webhook events into Forgejo's and returns
+// the GitHub events Forgejo has no equivalent for
+func forgejoEvents(events []string) ([]string, []string) {
+	if len(events) == 1 && events[0] == "*" {
+		events = nil
+		for event := range webhookEvents {
+			events = append(events, event)
+		}
+	}
+	seen := make(map[string]bool)
+	var result, unsupported []string
+	for _, event := range events {
+		mapped, ok := webhookEvents[event]
+		if !ok {
+			unsupported = append(unsupported, event)
+			continue
+		}
+		for _, e := range mapped {
+			if !seen[e] {
+				seen[e] = true
+				result = append(result, e)
+			}
+		}
+	}
+	sort.Strings(result)
+	sort.Strings(unsupported)
+	return result, unsupported
+}
+
+// webhookURL applies --webhook-url-template to the URL of a GitHub webhook
+func (c *Client) webhookURL(repo *GitHubRepo, hookURL string) (string, error) {
+	if c.config.WebhookURLTemplate == "" {
+		return hookURL, nil
+	}
+	u, err := url.Parse(hookURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook URL %q: %w", hookURL, err)
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return strings.NewReplacer(
+		"{url}", hookURL,
+		"{host}", u.Host,
+		"{path}", path,
+		"{full_name}", repo.FullName,
+		"{owner}", repo.Owner,
+		"{name}", repo.githubName(),
+	).Replace(c.config.WebhookURLTemplate), nil
+}
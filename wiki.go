@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// forgejoWikiHasPages reports whether the Forgejo wiki of a repository has any pages
+func (c *Client) forgejoWikiHasPages(ctx context.Context, owner, name string) (bool, error) {
+	status, body, err := c.forgejoRequest(ctx, "GET", repoPath(owner, name, "wiki", "pages")+"?limit=1", nil)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("Forgejo API returned status %d: %s", status, string(body))
+	}
+
+	var pages []json.RawMessage
+	if err := json.Unmarshal(body, &pages); err != nil {
+		return false, fmt.Errorf("failed to decode wiki pages: %w", err)
+	}
+	return len(pages) > 0, nil
+}
+
+// githubWikiURL returns the HTTPS clone URL of a repository's wiki
+func githubWikiURL(repo *GitHubRepo) string {
+	return strings.TrimSuffix(repo.CloneURL, ".git") + ".wiki.git"
+}
+
+// forgejoWikiURL returns the HTTPS clone URL of a Forgejo repository's wiki
+func (c *Client) forgejoWikiURL(owner, name string) string {
+	return fmt.Sprintf("%s/%s/%s.wiki.git", c.config.ForgejoURL, url.PathEscape(owner), url.PathEscape(name))
+}
+
+// EnsureWiki copies the GitHub wiki to Forgejo with git when Forgejo's own wiki
+// migration left it empty, which is common for private wikis. Forgejo refuses
+// pushes to pull mirrors, so an empty wiki of a mirror is only reported.
+func (c *Client) EnsureWiki(ctx context.Context, repo *GitHubRepo) error {
+	if !repo.HasWiki {
+		return nil
+	}
+
+	owner := c.ownerFor(repo)
+	hasPages, err := c.forgejoWikiHasPages(ctx, owner, repo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check Forgejo wiki: %w", err)
+	}
+	if hasPages {
+		return nil
+	}
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil {
+		return err
+	}
+	if forgejoRepo.Mirror {
+		return fmt.Errorf("the wiki of the mirror is empty and mirrors can't be pushed to, migrate it with --mode=migrate to copy the wiki with git")
+	}
+	return c.pushWiki(ctx, repo, "Pushed wiki via git fallback")
+}
+
+// SyncWiki brings the Forgejo wiki of a repository up to date with GitHub,
+// because wikis of existing repositories often lag far behind their code.
+// Forgejo refuses pushes to pull mirrors but fetches their wiki along with
+// their code, so mirrors get a mirror sync instead, unless result says this
+// run already created or synced them. Other repositories get the wiki pushed
+// with git.
+func (c *Client) SyncWiki(ctx context.Context, repo *GitHubRepo, result Result) error {
+	if !repo.HasWiki {
+		return nil
+	}
+	owner := c.ownerFor(repo)
+	forgejoRepo, err := c.GetForgejoRepo(ctx, owner, repo.Name)
+	if err != nil || forgejoRepo == nil {
+		return err
+	}
+	if !forgejoRepo.Mirror {
+		return c.pushWiki(ctx, repo, "Synced wiki")
+	}
+	if result == ResultCreated || result == ResultSynced {
+		return nil
+	}
+	return c.withRetry(ctx, "Wiki sync of "+repo.Name, func() error {
+		return c.SyncMirror(ctx, owner, repo.Name)
+	})
+}
+
+// pushWiki mirrors the GitHub wiki of a repository to its Forgejo wiki with
+// git and prints done when something was pushed
+func (c *Client) pushWiki(ctx context.Context, repo *GitHubRepo, done string) error {
+	owner := c.ownerFor(repo)
+	source, target := githubWikiURL(repo), c.forgejoWikiURL(owner, repo.Name)
+	ghAuth, err := c.githubAuth(repo, source)
+	if err != nil {
+		return err
+	}
+	auth := []gitAuth{ghAuth, c.forgejoAuth(target)}
+
+	// GitHub only creates the wiki repository once the first page is saved.
+	// Any other failure is reported, the wiki would be lost silently otherwise.
+	refs, err := runGit(ctx, "", auth, "ls-remote", source)
+	if err != nil && !remoteMissing(err) {
+		return fmt.Errorf("failed to list the GitHub wiki: %w", err)
+	}
+	if err != nil || strings.TrimSpace(refs) == "" {
+		if c.config.Verbose {
+			fmt.Printf("📖 No wiki content on GitHub for: %s\n", repo.Name)
+		}
+		return nil
+	}
+
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would push wiki via git: %s\n", repo.Name)
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "wiki-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	clone := filepath.Join(dir, "wiki.git")
+	if _, err := runGit(ctx, "", auth, "clone", "--mirror", source, clone); err != nil {
+		return err
+	}
+	if _, err := runGit(ctx, clone, auth, "push", "--mirror", target); err != nil {
+		return err
+	}
+
+	fmt.Printf("📖 %s: %s\n", done, repo.Name)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeWikiForgejo serves a Forgejo repository acme/api and records every other
+// request, git requests to the GitHub wiki included
+func fakeWikiForgejo(t *testing.T, mirror bool) (*Client, func() []string) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/repos/acme/api":
+			w.Write([]byte(`{"full_name":"acme/api","mirror":` + strconv.FormatBool(mirror) + `}`))
+		case "GET /api/v1/repos/acme/api/wiki/pages":
+			w.Write([]byte(`[]`))
+		default:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.URL.Path != "/api/v1/repos/acme/api/mirror-sync" {
+				http.Error(w, "unexpected request", http.StatusInternalServerError)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	config := &Config{ForgejoURL: server.URL, ForgejoUser: "acme", RetryAttempts: 1}
+	c := &Client{httpClient: server.Client(), config: config, syncs: newSlots(1)}
+	return c, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(requests)
+	}
+}
+
+func TestSyncWiki(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  bool
+		result  Result
+		want    []string
+		wantErr bool
+	}{
+		{"existing mirror", true, ResultAlreadyExists, []string{"POST /api/v1/repos/acme/api/mirror-sync"}, false},
+		{"mirror synced by this run", true, ResultSynced, nil, false},
+		{"mirror created by this run", true, ResultCreated, nil, false},
+		// Pushed with git, which fails against the fake GitHub
+		{"not a mirror", false, ResultAlreadyExists, []string{"GET /gh/acme/api.wiki.git/info/refs"}, true},
+	}
+	for _, tt := range tests {
+		c, requests := fakeWikiForgejo(t, tt.mirror)
+		repo := &GitHubRepo{FullName: "acme/api", Name: "api", HasWiki: true, CloneURL: c.config.ForgejoURL + "/gh/acme/api.git"}
+		err := c.SyncWiki(context.Background(), repo, tt.result)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: SyncWiki = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if got := requests(); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: requests = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureWikiMirror(t *testing.T) {
+	c, requests := fakeWikiForgejo(t, true)
+	repo := &GitHubRepo{FullName: "acme/api", Name: "api", HasWiki: true, CloneURL: c.config.ForgejoURL + "/gh/acme/api.git"}
+	if err := c.EnsureWiki(context.Background(), repo); err == nil {
+		t.Error("EnsureWiki reported no problem with the empty wiki of a mirror")
+	}
+	if got := requests(); len(got) > 0 {
+		t.Errorf("EnsureWiki tried to push to a mirror: %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// actionsIssueTitle is the title of the issue --actions-issue opens
+const actionsIssueTitle = "GitHub Actions workflows incompatible with Forgejo Actions"
+
+// ActionsFinding is a use of a GitHub Actions feature Forgejo Actions lacks
+type ActionsFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Problem string `json:"problem"`
+}
+
+func (f ActionsFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Problem)
+}
+
+// githubOnlyActions are actions that need GitHub services Forgejo doesn't
+// have. Every action of the github organization does too.
+var githubOnlyActions = map[string]string{
+	"actions/attest":                   "GitHub artifact attestations",
+	"actions/attest-build-provenance":  "GitHub artifact attestations",
+	"actions/attest-sbom":              "GitHub artifact attestations",
+	"actions/configure-pages":          "GitHub Pages",
+	"actions/create-github-app-token":  "GitHub Apps",
+	"actions/dependency-review-action": "GitHub's dependency graph",
+	"actions/deploy-pages":             "GitHub Pages",
+	"actions/upload-pages-artifact":    "GitHub Pages",
+	"dependabot/fetch-metadata":        "Dependabot",
+}
+
+// workflowEvents are the events Forgejo Actions can trigger workflows on
+var workflowEvents = map[string]bool{
+	"create": true, "delete": true, "fork": true, "gollum": true,
+	"issue_comment": true, "issues": true, "label": true, "milestone": true,
+	"pull_request": true, "pull_request_review": true, "pull_request_review_comment": true,
+	"pull_request_target": true, "push": true, "registry_package": true, "release": true,
+	"schedule": true, "workflow_call": true, "workflow_dispatch": true,
+}
+
+var (
+	usesPattern    = regexp.MustCompile(`^\s*(?:-\s+)?uses:\s*["']?([^"'\s#]+)`)
+	runsOnPattern  = regexp.MustCompile(`^\s*runs-on:\s*(.+)`)
+	idTokenPattern = regexp.MustCompile(`^\s*id-token:\s*["']?write`)
+	envPattern     = regexp.MustCompile(`^\s*environment:`)
+	onPattern      = regexp.MustCompile(`^["']?(?:on|true)["']?:\s*(.*)$`)
+)
+
+// CheckWorkflows scans the GitHub Actions workflows on the default branch of a
+// repository for features Forgejo Actions doesn't support
+func (c *Client) CheckWorkflows(ctx context.Context, repo *GitHubRepo) ([]ActionsFinding, error) {
+	gh := c.githubFor(repo)
+	_, entries, _, err := gh.Repositories.GetContents(ctx, repo.Owner, repo.githubName(), ".github/workflows", nil)
+	if err != nil {
+		err = githubError("listing workflows of "+repo.FullName, err)
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var findings []ActionsFinding
+	for _, entry := range entries {
+		ext := path.Ext(entry.GetName())
+		if entry.GetType() != "file" || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		file, _, _, err := gh.Repositories.GetContents(ctx, repo.Owner, repo.githubName(), entry.GetPath(), nil)
+		if err != nil {
+			return nil, githubError("fetching "+entry.GetPath()+" of "+repo.FullName, err)
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", entry.GetPath(), err)
+		}
+		findings = append(findings, scanWorkflow(entry.GetPath(), content)...)
+	}
+	return findings, nil
+}
+
+// scanWorkflow looks for unsupported features line by line, which is enough
+// for the keys it checks and doesn't need a YAML parser
+func scanWorkflow(file, content string) []ActionsFinding {
+	var findings []ActionsFinding
+	add := func(line int, format string, args ...interface{}) {
+		findings = append(findings, ActionsFinding{File: file, Line: line, Problem: fmt.Sprintf(format, args...)})
+	}
+	checkEvent := func(line int, event string) {
+		event = strings.Trim(strings.TrimSpace(event), `"'`)
+		if event != "" && !workflowEvents[event] {
+			add(line, "is triggered by %s, which Forgejo Actions doesn't support", event)
+		}
+	}
+
+	inOn, eventIndent := false, -1
+	for i, line := range strings.Split(content, "\n") {
+		number := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		// Events are the keys or list items one level below on:
+		if inOn && indent == 0 {
+			inOn = false
+		}
+		if inOn {
+			if eventIndent < 0 {
+				eventIndent = indent
+			}
+			if indent == eventIndent {
+				event, _, _ := strings.Cut(strings.TrimPrefix(trimmed, "- "), ":")
+				checkEvent(number, event)
+			}
+			continue
+		}
+		if indent == 0 {
+			if m := onPattern.FindStringSubmatch(trimmed); m != nil {
+				value, _, _ := strings.Cut(m[1], "#")
+				if value = strings.TrimSpace(value); value == "" {
+					inOn, eventIndent = true, -1
+				} else {
+					for _, event := range strings.Split(strings.Trim(value, "[]"), ",") {
+						checkEvent(number, event)
+					}
+				}
+			}
+			continue
+		}
+
+		if m := usesPattern.FindStringSubmatch(line); m != nil {
+			action, _, _ := strings.Cut(m[1], "@")
+			// owner/repo, without the path of actions in subdirectories
+			parts := strings.SplitN(action, "/", 3)
+			if len(parts) >= 2 && !strings.HasPrefix(action, "./") && !strings.HasPrefix(action, "docker://") {
+				name := strings.ToLower(parts[0] + "/" + parts[1])
+				if service, ok := githubOnlyActions[name]; ok {
+					add(number, "uses %s, which needs %s", action, service)
+				} else if parts[0] == "github" {
+					add(number, "uses %s, which needs GitHub services", action)
+				}
+			}
+		}
+		if m := runsOnPattern.FindStringSubmatch(line); m != nil {
+			label := strings.ToLower(m[1])
+			if strings.Contains(label, "macos") || strings.Contains(label, "windows") {
+				add(number, "runs on %s, which needs a self-hosted Forgejo runner with that label", strings.TrimSpace(m[1]))
+			}
+		}
+		if idTokenPattern.MatchString(line) {
+			add(number, "requests an OIDC token (id-token: write), which Forgejo Actions doesn't issue")
+		}
+		if envPattern.MatchString(line) {
+			add(number, "deploys to an environment; Forgejo has no environments, protection rules or environment secrets")
+		}
+	}
+	return findings
+}
+
+// TrackActionsFindings opens an issue listing the findings on the Forgejo
+// repository, or updates the one opened by an earlier run
+func (c *Client) TrackActionsFindings(ctx context.Context, repo *GitHubRepo, findings []ActionsFinding) error {
+	if c.config.DryRun {
+		fmt.Printf("[DRY RUN] Would open an issue about %d Forgejo Actions incompatibilities on %s\n", len(findings), repo.Name)
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "The GitHub Actions workflows of [%s](https://github.com/%s) use features Forgejo Actions doesn't support. They need to be adapted before the workflows can run here.\n\n", repo.FullName, repo.FullName)
+	for _, finding := range findings {
+		fmt.Fprintf(&body, "- [ ] `%s` line %d: %s\n", finding.File, finding.Line, finding.Problem)
+	}
+
+	owner := c.ownerFor(repo)
+	issues, err := forgejoList[struct {
+		Index int64  `json:"number"`
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}](ctx, c, repoPath(owner, repo.Name, "issues")+"?state=open&type=issues&q="+url.QueryEscape(actionsIssueTitle), "issues of "+repo.Name)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if issue.Title != actionsIssueTitle {
+			continue
+		}
+		if issue.Body == body.String() {
+			return nil
+		}
+		status, respBody, err := c.forgejoRequest(ctx, "PATCH", repoPath(owner, repo.Name, "issues", strconv.FormatInt(issue.Index, 10)), map[string]string{"body": body.String()})
+		if err != nil {
+			return fmt.Errorf("failed to update issue #%d: %w", issue.Index, err)
+		}
+		if status != http.StatusOK && status != http.StatusCreated {
+			return forgejoError("updating issue #"+strconv.FormatInt(issue.Index, 10), status, respBody)
+		}
+		return nil
+	}
+
+	status, respBody, err := c.forgejoRequest(ctx, "POST", repoPath(owner, repo.Name, "issues"), map[string]string{"title": actionsIssueTitle, "body": body.String()})
+	if err != nil {
+		return fmt.Errorf("failed to open issue: %w", err)
+	}
+	if status != http.StatusCreated {
+		return forgejoError("opening issue", status, respBody)
+	}
+	if c.config.Verbose {
+		fmt.Printf("📝 Opened an issue about Forgejo Actions incompatibilities on %s\n", repo.Name)
+	}
+	return nil
+}